@@ -3,18 +3,66 @@ package multikf
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	kfmanifests "github.com/footprintai/multikf/kfmanifests"
 	"github.com/footprintai/multikf/pkg/k8s"
 	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/audit"
+	"github.com/footprintai/multikf/pkg/machine/metallb"
 	"github.com/footprintai/multikf/pkg/machine/plugins"
+	kubeflowplugin "github.com/footprintai/multikf/pkg/machine/plugins/kubeflow"
+	"github.com/footprintai/multikf/pkg/machine/plugins/storage"
 	"github.com/footprintai/multikf/pkg/machine/vagrant"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/kind/pkg/log"
+	"sigs.k8s.io/yaml"
 )
 
+// readKindPatches reads kind config patches, each of them should be a yaml map
+func readKindPatches(files []string) ([]string, error) {
+	var patches []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("cmdadd: read kind patch failed, err:%+v", err)
+		}
+		patch := map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &patch); err != nil {
+			return nil, fmt.Errorf("cmdadd: kind patch %s is not a yaml map, err:%+v", file, err)
+		}
+		patches = append(patches, string(content))
+	}
+	return patches, nil
+}
+
+// readWorkerGroups parses worker groups from flags followed by the ones in the worker groups file
+func readWorkerGroups(groups []string, file string) ([]machine.WorkerGroup, error) {
+	var workerGroups []machine.WorkerGroup
+	for _, group := range groups {
+		workerGroup, err := machine.ParseWorkerGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		workerGroups = append(workerGroups, workerGroup)
+	}
+	if file == "" {
+		return workerGroups, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cmdadd: read worker groups failed, err:%+v", err)
+	}
+	fromFile, err := machine.LoadWorkerGroups(content)
+	if err != nil {
+		return nil, err
+	}
+	return append(workerGroups, fromFile...), nil
+}
+
 func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
 	var (
 		provisionerStr              string // provider specifies the underly privisoner for virtual machine, either docker (under host) or vagrant
@@ -26,6 +74,8 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 		withKubeflowDefaultPassword string // with kubeflow defaultpassword
 		withIP                      string // with specific IP
 		withAudit                   bool   // with audit enabled
+		withAuditPolicy             string // audit policy preset or file
+		withControlPlanes           int    // with control-plane nodes
 		withWorkers                 int    // with workers
 		withLabels                  string // with labels
 		exportPorts                 string // export ports on hostmachine
@@ -33,12 +83,34 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 		useLocalPath                string // with localpath
 		withK8sVersion              string
 		withK8sSHA256               string
+		withCNI                     string   // with cni
+		withMetalLB                 bool     // with metallb for LoadBalancer services
+		withAPISANs                 string   // extra SANs for kubeapi certificate
+		withAdvertiseHost           string   // host used by remote clients to reach kubeapi
+		withKubeflowUsers           []string // initial kubeflow users in email:password
+		withKubeflowOverlay         string   // kustomize overlay applied on kubeflow manifest
+		withKubeflowPVCSizes        []string // kubeflow pvc sizes in component=GiB
+		withKubeflowComponents      string   // kubeflow profile or components
+		withStorageProvisioner      string   // storage provisioner installed before kubeflow
+		withDefaultStorageClass     string   // storageclass marked as the default one
+		ignoreCompatibility         bool     // install kubeflow on unsupported k8s versions
+		withKindPatches             []string // yaml files merged into the generated kind config
+		withWorkerGroups            []string // worker groups with their own labels, taints, gpu, mounts and image
+		withWorkerGroupsFile        string   // yaml file of worker groups
 	)
 
-	ensureNoGPUForVagrant := func(vag machine.MachineCURDFactory, useGPUs int) error {
-		if _, isVargant := vag.(*vagrant.VagrantMachines); isVargant && useGPUs > 0 {
+	ensureNoGPUForVagrant := func(vag machine.MachineCURDFactory, useGPUs int, workerGroups []machine.WorkerGroup) error {
+		if _, isVargant := vag.(*vagrant.VagrantMachines); !isVargant {
+			return nil
+		}
+		if useGPUs > 0 {
 			return errors.New("vagrant machine haven't support gpu passthrough yet")
 		}
+		for _, group := range workerGroups {
+			if group.GPU {
+				return errors.New("vagrant machine haven't support gpu passthrough yet")
+			}
+		}
 		return nil
 	}
 
@@ -50,7 +122,73 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 		if err != nil {
 			return err
 		}
-		if err := ensureNoGPUForVagrant(vag, useGPUs); err != nil {
+		if !withAudit && withAuditPolicy != "" {
+			return errors.New("cmdadd: --audit_policy requires --with_audit")
+		}
+		auditPolicy, err := audit.ResolvePolicy(withAuditPolicy)
+		if err != nil {
+			return err
+		}
+		if withControlPlanes < 1 {
+			return fmt.Errorf("cmdadd: --control_planes should be at least 1, got %d", withControlPlanes)
+		}
+		workerGroups, err := readWorkerGroups(withWorkerGroups, withWorkerGroupsFile)
+		if err != nil {
+			return err
+		}
+		for _, group := range workerGroups {
+			if err := group.ValidateImage(withK8sVersion); err != nil {
+				return err
+			}
+		}
+		if err := ensureNoGPUForVagrant(vag, useGPUs, workerGroups); err != nil {
+			return err
+		}
+		if _, isVargant := vag.(*vagrant.VagrantMachines); isVargant && withMetalLB {
+			return errors.New("vagrant machine haven't support metallb yet")
+		}
+		cni, err := k8s.ParseCNI(withCNI)
+		if err != nil {
+			return err
+		}
+		if withStorageProvisioner != "" {
+			provisioner, err := storage.ParseProvisioner(withStorageProvisioner)
+			if err != nil {
+				return err
+			}
+			if provisioner == storage.ProvisionerSharedPath && useLocalPath == "" {
+				return errors.New("cmdadd: --storage_provisioner shared-path requires --use_localpath as the directory shared by nodes")
+			}
+		}
+		kubeflowParams := map[string]string{
+			plugins.KubeflowParamPassword:   withKubeflowDefaultPassword,
+			plugins.KubeflowParamOverlay:    withKubeflowOverlay,
+			plugins.KubeflowParamComponents: withKubeflowComponents,
+		}
+		if err := parseKubeflowPVCSizes(withKubeflowPVCSizes, kubeflowParams); err != nil {
+			return err
+		}
+		if _, err := kubeflowplugin.ParseComponents(withKubeflowComponents); err != nil {
+			return err
+		}
+		if withKubeflow {
+			if err := checkKubeflowCompatibility(logger, withKubeflowVersion, withK8sVersion, ignoreCompatibility); err != nil {
+				return err
+			}
+			checkMinResources(logger, withKubeflowVersion, cpus, memoryInG)
+		}
+		kindPatches, err := readKindPatches(withKindPatches)
+		if err != nil {
+			return err
+		}
+		exportPortPairs, err := machine.ParseExportPorts(exportPorts)
+		if err != nil {
+			logger.Errorf("cmdadd: invalid export ports (%s), err:%+v\n", exportPorts, err)
+			return err
+		}
+		logger.V(1).Infof("cmdadd: export ports:%+v\n", exportPortPairs)
+		apiServerCertSANs, err := machine.ParseAPIServerCertSANs(withAPISANs, withAdvertiseHost)
+		if err != nil {
 			return err
 		}
 
@@ -60,16 +198,22 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 			MemoryInG:      memoryInG,
 			UseGPUs:        useGPUs,
 			KubeAPIIP:      withIP,
-			ExportPorts:    exportPorts,
+			ExportPorts:    exportPortPairs,
 			ForceOverwrite: forceOverwrite,
 			IsAuditEnabled: withAudit,
+			AuditPolicy:    auditPolicy,
+			ControlPlanes:  withControlPlanes,
 			Workers:        withWorkers,
+			WorkerGroups:   workerGroups,
 			NodeLabels:     withLabels,
 			LocalPath:      useLocalPath,
 			NodeVersion: k8s.NewKindK8sVersion(
 				withK8sVersion,
 				withK8sSHA256,
 			),
+			CNI:               cni,
+			APIServerCertSANs: apiServerCertSANs,
+			KindPatches:       kindPatches,
 		})
 		if err != nil {
 			return err
@@ -78,13 +222,51 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 			logger.Errorf("cmdadd: add node (%s) failed, err:%+v\n", machineName, err)
 			return err
 		}
+		if withAdvertiseHost != "" {
+			state, err := machine.LoadMachineState(m.HostDir())
+			if err != nil {
+				return err
+			}
+			state.AdvertiseHost = withAdvertiseHost
+			if err := state.Save(m.HostDir()); err != nil {
+				return err
+			}
+			logger.V(0).Infof("cmdadd: use `multikf export %s --kubeconfig_path <path> --for-remote %s` to export kubeconfig for remote clients\n", machineName, withAdvertiseHost)
+		}
+		if withMetalLB {
+			if err := metallb.InstallOnMachine(logger, viperConfigKeyRootDir.GetString(), viperConfigKeyVerbose.GetBool(), m); err != nil {
+				return err
+			}
+		}
+		if withDefaultStorageClass != "" {
+			if err := storage.SetDefaultStorageClass(logger, m, withDefaultStorageClass); err != nil {
+				return err
+			}
+		}
 		var installedPlugins []plugins.Plugin
+		if withStorageProvisioner != "" {
+			storageParams := map[string]string{storage.ParamProvisioner: withStorageProvisioner}
+			if withDefaultStorageClass != "" {
+				storageParams[storage.ParamDefault] = "false"
+			}
+			installedPlugins = append(installedPlugins, plugins.NewPlugin(storage.TypePluginStorage, "", storageParams))
+		}
 		if withKubeflow {
 			installedPlugins = append(installedPlugins,
-				kubeflowPlugin{withKubeflowDefaultPassword: withKubeflowDefaultPassword, kubeflowVersion: plugins.NewTypePluginVersion(withKubeflowVersion)},
+				plugins.NewPlugin(
+					plugins.TypePluginKubeflow,
+					plugins.NewTypePluginVersion(withKubeflowVersion),
+					kubeflowParams,
+				),
 			)
 		}
-		return plugins.AddPlugins(m, installedPlugins...)
+		if err := plugins.AddPlugins(logger, m, installedPlugins...); err != nil {
+			return err
+		}
+		if withKubeflow {
+			return addKubeflowUsers(m, logger, withKubeflowUsers)
+		}
+		return nil
 	}
 	cmd := &cobra.Command{
 		Use:   "add <machine-name>",
@@ -102,14 +284,37 @@ func NewAddCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *co
 	cmd.Flags().BoolVar(&withKubeflow, "with_kubeflow", true, "install kubeflow modules (default: true)")
 	cmd.Flags().StringVar(&withKubeflowVersion, "kubeflow_version", kfVersions[0], fmt.Sprintf("support kubeflow version: %s", strings.Join(kfVersions, ",")))
 	cmd.Flags().BoolVar(&withAudit, "with_audit", true, "enable k8s auditing (default: true)")
+	cmd.Flags().StringVar(&withAuditPolicy, "audit_policy", "", fmt.Sprintf("audit policy file or preset, possible preset: %s (default: %s)", strings.Join(audit.ListPresets(), "|"), audit.DefaultPreset))
 	cmd.Flags().StringVar(&withKubeflowDefaultPassword, "with_password", "12341234", "with a specific password for default user (default: 12341234)")
+	cmd.Flags().StringVar(&withKubeflowOverlay, "kubeflow_overlay", "", "kustomize overlay dir applied on the kubeflow manifest, which refers the manifest as ../base (default: )")
+	cmd.Flags().StringVar(&withKubeflowComponents, "kubeflow_components", "full", "kubeflow profile (full|lite|pipelines-only) or components delimited by comma: pipelines,notebooks,katib,training-operator,kserve")
+	cmd.Flags().StringArrayVar(&withKubeflowUsers, "kubeflow_user", nil, "add kubeflow user with its own profile, format: email:password, repeatable (default: )")
 	cmd.Flags().IntVar(&useGPUs, "use_gpus", 0, "use gpu resources (default: 0), possible value (0 or 1)")
 	cmd.Flags().StringVar(&withIP, "with_ip", "0.0.0.0", "with a specific ip address for kubeapi (default: 0.0.0.0)")
-	cmd.Flags().StringVar(&exportPorts, "export_ports", "", "export ports to host, delimited by comma(example: 8443:443 stands for mapping host port 8443 to container port 443)")
+	cmd.Flags().StringVar(&exportPorts, "export_ports", "", "export ports to host, delimited by comma, format: hostPort[-end]:containerPort[-end][/tcp|udp|sctp][@listenAddress] (example: 8443:443 stands for mapping host port 8443 to container port 443, 8000-8010:30000-30010/udp@127.0.0.1 maps a udp port range on 127.0.0.1)")
+	cmd.Flags().IntVar(&withControlPlanes, "control_planes", 1, "number of control-plane nodes, kind load balances kubeapi across them if there are more than one (default: 1)")
 	cmd.Flags().IntVar(&withWorkers, "with_workers", 0, "use workers (default: 0)")
+	cmd.Flags().StringArrayVar(&withWorkerGroups, "worker_group", nil, "add a group of workers, fields delimited by semicolon: count=2;labels=k=v,...;taints=k[=v]:NoSchedule,...;gpu=true;mounts=/host:/container[:ro],...;image=kindest/node:v1.29.8;localpath=true, repeatable (default: )")
+	cmd.Flags().StringVar(&withWorkerGroupsFile, "worker_groups_file", "", "yaml file of worker groups, a list of count, labels, taints, gpu, mounts, image and localPath (default: )")
 	cmd.Flags().StringVar(&withLabels, "with_labels", "", "attach labels, format: key1=value1,key2=value2(default: )")
 	cmd.Flags().StringVar(&useLocalPath, "use_localpath", "", "mount local path to kind cluster")
+	cmd.Flags().StringVar(&withStorageProvisioner, "storage_provisioner", "", fmt.Sprintf("storage provisioner installed as the default storageclass, possible value: %s (default: kind's built-in local-path storageclass standard)", strings.Join(storage.ListProvisionerString(), "|")))
+	cmd.Flags().StringVar(&withDefaultStorageClass, "default_storageclass", "", "existing storageclass marked as the default one, e.g. standard (default: the one from --storage_provisioner)")
+	cmd.Flags().StringArrayVar(&withKubeflowPVCSizes, "kubeflow_pvc_size", nil, "kubeflow pvc size in GiB, format: authservice|katib_mysql|pipeline_minio|pipeline_mysql=size, repeatable (default: 10,10,20,20)")
 	cmd.Flags().StringVar(&withK8sVersion, "with_k8s_version", k8s.DefaultVersion().Version(), fmt.Sprintf("support verisions:%s", strings.Join(k8s.ListVersionString(), ",")))
+	cmd.Flags().StringVar(&withCNI, "cni", k8s.CNIDefault.String(), fmt.Sprintf("cni installed before any plugins, possible value: %s", strings.Join(k8s.ListCNIString(), "|")))
+	cmd.Flags().BoolVar(&withMetalLB, "with_metallb", false, "install metallb with an address pool from kind's docker network for LoadBalancer services (default: false)")
+	cmd.Flags().StringVar(&withAPISANs, "api_sans", "", "extra ip addresses/hostnames added into kubeapi certificate, delimited by comma (default: )")
+	cmd.Flags().StringVar(&withAdvertiseHost, "advertise_host", "", "ip address/hostname which remote clients use to reach kubeapi, it is added into kubeapi certificate (default: )")
+	cmd.Flags().StringArrayVar(&withKindPatches, "kind_patch", nil, "yaml file deep merged into the generated kind config, e.g. kubeadmConfigPatches, containerdConfigPatches, featureGates or nodes, repeatable (default: )")
+	// --kind-patch is accepted as well
+	cmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "kind-patch" {
+			name = "kind_patch"
+		}
+		return pflag.NormalizedName(name)
+	})
+	cmd.Flags().BoolVar(&ignoreCompatibility, "ignore_compatibility", false, "install kubeflow even if it doesn't support the k8s version, see `multikf versions` (default: false)")
 	cmd.Flags().StringVar(&withK8sSHA256, "with_k8s_sha256", k8s.DefaultVersion().Sha256(), fmt.Sprintf("k8s version and its sha256 mapping list:%s", strings.Join(k8s.ListVersionSha256String(), ",")))
 
 	return cmd
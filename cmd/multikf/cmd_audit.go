@@ -0,0 +1,119 @@
+package multikf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine/audit"
+)
+
+func NewAuditCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "audit",
+		Long:  `view k8s audit logs of machines`,
+	}
+	cmd.AddCommand(newAuditLogsCommand(logger, ioStreams))
+	return cmd
+}
+
+// parseAuditTime parses either a duration relative to now (e.g. 1h) or a RFC3339 timestamp, empty stands for no limit
+func parseAuditTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cmdaudit: invalid time %q, expect a duration (e.g. 30m) or RFC3339 (e.g. 2024-01-02T15:04:05Z)", s)
+	}
+	return t, nil
+}
+
+func newAuditLogsCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		filter    audit.Filter
+		since     string // duration or RFC3339
+		until     string // duration or RFC3339
+		outputStr string // table, csv or json
+	)
+	handle := func(machineName string) error {
+		now := time.Now()
+		var err error
+		if filter.Since, err = parseAuditTime(since, now); err != nil {
+			return err
+		}
+		if filter.Until, err = parseAuditTime(until, now); err != nil {
+			return err
+		}
+		if outputStr != "json" && MustParseFormat(outputStr) == UnknownFormat {
+			return fmt.Errorf("cmdaudit: unknown output %q, possible value: table, csv and json", outputStr)
+		}
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		events, err := audit.Logs(m, filter)
+		if err != nil {
+			return err
+		}
+		if outputStr == "json" {
+			// json lines, which is the same format as the audit log
+			encoder := json.NewEncoder(ioStreams.Out)
+			for _, e := range events {
+				if err := encoder.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		var values [][]string
+		for _, e := range events {
+			var namespace, name, code string
+			if e.ObjectRef != nil {
+				namespace, name = e.ObjectRef.Namespace, e.ObjectRef.Name
+			}
+			if e.ResponseStatus != nil {
+				code = strconv.Itoa(int(e.ResponseStatus.Code))
+			}
+			values = append(values, []string{
+				audit.Timestamp(e).Local().Format(time.RFC3339),
+				e.User.Username,
+				e.Verb,
+				audit.Resource(e),
+				namespace,
+				name,
+				code,
+			})
+		}
+		return NewFormatWriter(ioStreams.Out, MustParseFormat(outputStr)).WriteAndClose(
+			[]string{"time", "user", "verb", "resource", "namespace", "name", "code"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "logs <machine-name>",
+		Short: "fetch and filter audit events from control-plane nodes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	cmd.Flags().StringVar(&filter.User, "user", "", "username, e.g. kubernetes-admin (default: )")
+	cmd.Flags().StringVar(&filter.Verb, "verb", "", "verb, e.g. create, update, patch, delete (default: )")
+	cmd.Flags().StringVar(&filter.Resource, "resource", "", "resource with an optional subresource, e.g. pods or pods/exec (default: )")
+	cmd.Flags().StringVar(&filter.Namespace, "namespace", "", "namespace (default: )")
+	cmd.Flags().StringVar(&since, "since", "", "events after a duration ago (e.g. 1h) or a RFC3339 time (default: )")
+	cmd.Flags().StringVar(&until, "until", "", "events before a duration ago (e.g. 10m) or a RFC3339 time (default: )")
+	cmd.Flags().StringVar(&outputStr, "output", string(Table), fmt.Sprintf("output format, possible value: %s", strings.Join([]string{string(Table), string(CSV), "json"}, "|")))
+	return cmd
+}
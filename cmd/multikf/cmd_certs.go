@@ -0,0 +1,112 @@
+package multikf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/certs"
+)
+
+func NewCertsCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "certs",
+		Long:  `check and renew control-plane certificates`,
+	}
+	cmd.AddCommand(newCheckCertsCommand(logger, ioStreams))
+	cmd.AddCommand(newRenewCertsCommand(logger, ioStreams))
+	return cmd
+}
+
+func newCheckCertsCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		certificates, err := certs.Check(m)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var values [][]string
+		for _, c := range certificates {
+			values = append(values, []string{
+				fmt.Sprintf("%d", c.Node),
+				c.Name,
+				c.Expires.Format("2006-01-02 15:04 MST"),
+				formatResidual(c.Residual(now)),
+				fmt.Sprintf("%t", c.IsAuthority),
+			})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"node", "certificate", "expires", "residual", "authority"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "check <machine-name>",
+		Short: "report expiry dates of control-plane certificates",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	return cmd
+}
+
+func newRenewCertsCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		if err := certs.Renew(logger, m); err != nil {
+			return err
+		}
+		logger.V(0).Infof("certs: certificates of %s are renewed, kubeconfig is re-exported to %s\n", m.Name(), m.GetKubeConfig())
+		state, err := machine.LoadMachineState(m.HostDir())
+		if err == nil && len(state.MergedKubeConfigPaths) > 0 {
+			logger.V(0).Infof("certs: run `multikf export %s --merge` to refresh the merged kubeconfig\n", m.Name())
+		}
+		return nil
+	}
+	cmd := &cobra.Command{
+		Use:   "renew <machine-name>",
+		Short: "renew control-plane certificates and re-export kubeconfig",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	return cmd
+}
+
+func formatResidual(d time.Duration) string {
+	if d < 0 {
+		return "expired"
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// warnExpiringCertificates logs a warning if any control-plane certificate of the machine expires soon
+func warnExpiringCertificates(m machine.MachineCURD, logger log.Logger) {
+	certificates, err := certs.Check(m)
+	if err != nil {
+		logger.V(1).Infof("certs: check machine (%s) failed, err:%+v\n", m.Name(), err)
+		return
+	}
+	expiring := certs.ExpiringWithin(certificates, time.Now(), certs.WarnBefore)
+	if len(expiring) == 0 {
+		return
+	}
+	var names []string
+	for _, c := range expiring {
+		names = append(names, fmt.Sprintf("%s(node %d)", c.Name, c.Node))
+	}
+	logger.Warnf("certificates of machine (%s) expire within %d days: %s, run `multikf certs renew %s`\n", m.Name(), int(certs.WarnBefore.Hours()/24), strings.Join(names, ","), m.Name())
+}
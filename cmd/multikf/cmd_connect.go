@@ -2,6 +2,7 @@ package multikf
 
 import (
 	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins/monitoring"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/kind/pkg/log"
@@ -14,10 +15,12 @@ func NewConnectCommand(logger log.Logger, ioStreams genericclioptions.IOStreams)
 	}
 
 	cmd.AddCommand(newConnectKubeflowCommand(logger, ioStreams))
+	cmd.AddCommand(newConnectGrafanaCommand(logger, ioStreams))
 	return cmd
 }
 
-func newConnectKubeflowCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+// newConnectServiceCommand port-forwards the service's port to a local port
+func newConnectServiceCommand(logger log.Logger, use string, short string, svc string, namespace string, svcPort int) *cobra.Command {
 	var (
 		port         int  // dedicated port
 		enablePublic bool // enable public access
@@ -40,11 +43,12 @@ func newConnectKubeflowCommand(logger log.Logger, ioStreams genericclioptions.IO
 		if enablePublic {
 			listenedAddress = "0.0.0.0"
 		}
-		return m.GetKubeCli().Portforward(m.GetKubeConfig(), "svc/istio-ingressgateway", "istio-system", listenedAddress, 80, destPort)
+		return m.GetKubeCli().Portforward(m.GetKubeConfig(), svc, namespace, listenedAddress, svcPort, destPort)
 	}
 	cmd := &cobra.Command{
-		Use:   "kubeflow",
-		Short: "connect with kubeflow via port-forward",
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return handle(args[0])
 		},
@@ -54,3 +58,11 @@ func newConnectKubeflowCommand(logger log.Logger, ioStreams genericclioptions.IO
 	cmd.Flags().BoolVar(&enablePublic, "enable_public", false, "enable public access, default: false")
 	return cmd
 }
+
+func newConnectKubeflowCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return newConnectServiceCommand(logger, "kubeflow <machine-name>", "connect with kubeflow via port-forward", "svc/istio-ingressgateway", "istio-system", 80)
+}
+
+func newConnectGrafanaCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	return newConnectServiceCommand(logger, "grafana <machine-name>", "connect with grafana of the monitoring plugin via port-forward", "svc/"+monitoring.GrafanaService, monitoring.Namespace, 80)
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/kubeconfig"
 )
 
 func NewDeleteCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
@@ -12,6 +15,17 @@ func NewDeleteCommand(logger log.Logger, ioStreams genericclioptions.IOStreams)
 		if err != nil {
 			return err
 		}
+		// cleanup merged kubeconfig entries before the machine dir is gone
+		state, err := machine.LoadMachineState(m.HostDir())
+		if err != nil {
+			logger.Errorf("del: load state of node (%s) failed, err:%+v\n", machineName, err)
+		} else {
+			for _, mergedPath := range state.MergedKubeConfigPaths {
+				if err := kubeconfig.RemoveFile(mergedPath, m.Name()); err != nil {
+					logger.Errorf("del: remove context (%s) from %s failed, err:%+v\n", machineName, mergedPath, err)
+				}
+			}
+		}
 		if err := m.Destroy(); err != nil {
 			logger.Errorf("del: delete node (%s) failed, err:%+v\n", machineName, err)
 		}
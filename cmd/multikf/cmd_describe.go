@@ -0,0 +1,83 @@
+package multikf
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+func NewDescribeCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		state, err := machine.LoadMachineState(m.HostDir())
+		if err != nil {
+			return err
+		}
+		detail := &OutputMachineDetail{
+			Name:          m.Name(),
+			Type:          m.Type().String(),
+			MachineDir:    m.HostDir(),
+			KubeConfig:    m.GetKubeConfig(),
+			Status:        "unknown",
+			LoadBalancer:  state.LoadBalancerAddressRange,
+			AdvertiseHost: state.AdvertiseHost,
+		}
+		info, err := m.Info()
+		if err != nil {
+			logger.V(1).Infof("describe: grab info from machine (%s) failed, err:%+v\n", machineName, err)
+		} else {
+			detail.Status = info.Status
+			detail.Gpus = fmt.Sprintf("%s", info.GpuInfo.Info())
+			detail.Cpus = fmt.Sprintf("%d", info.CpuInfo.NumCPUs())
+			detail.Memory = fmt.Sprintf("%s/%s", info.MemInfo.Free(), info.MemInfo.Total())
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"field", "value"},
+			detail.Rows(),
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "describe <machine-name>",
+		Short: "describe a guest machine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	return cmd
+}
+
+// OutputMachineDetail defines the output format returned for describing a single Machine
+type OutputMachineDetail struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	MachineDir    string `json:"dir"`
+	KubeConfig    string `json:"kubeconfig"`
+	Status        string `json:"status"`
+	Cpus          string `json:"cpus"`
+	Gpus          string `json:"gpus"`
+	Memory        string `json:"memory"`
+	LoadBalancer  string `json:"loadBalancer"`
+	AdvertiseHost string `json:"advertiseHost"`
+}
+
+func (o *OutputMachineDetail) Rows() [][]string {
+	return [][]string{
+		{"name", o.Name},
+		{"type", o.Type},
+		{"dir", o.MachineDir},
+		{"kubeconfig", o.KubeConfig},
+		{"status", o.Status},
+		{"cpus", o.Cpus},
+		{"gpus", o.Gpus},
+		{"memory", o.Memory},
+		{"loadBalancer", o.LoadBalancer},
+		{"advertiseHost", o.AdvertiseHost},
+	}
+}
@@ -0,0 +1,106 @@
+package multikf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/kubeconfig"
+)
+
+func NewExportCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		kubeconfigPath string // path to the exported kubeconfig
+		forceOverwrite bool   // force overwrite existing kubeconfig
+		forRemote      string // rewrite server url with the host for remote clients
+		merge          bool   // merge into an existing kubeconfig instead of writing a standalone one
+	)
+	handleMerge := func(m machine.MachineCURD) error {
+		targetPath := kubeconfigPath
+		if targetPath == "" {
+			targetPath = kubeconfig.DefaultPath()
+		}
+		// recorded for delete, which could run in another directory
+		targetPath, err := filepath.Abs(targetPath)
+		if err != nil {
+			return err
+		}
+		tmpDir, err := os.MkdirTemp("", "multikf-export")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		tmpKubeConfig := filepath.Join(tmpDir, "kubeconfig")
+		if err := m.ExportKubeConfig(tmpKubeConfig, true); err != nil {
+			logger.Errorf("export: export kubeconfig for (%s) failed, err:%+v\n", m.Name(), err)
+			return err
+		}
+		if forRemote != "" {
+			if err := kubeconfig.RewriteServerFile(tmpKubeConfig, forRemote); err != nil {
+				return err
+			}
+		}
+		blob, err := os.ReadFile(tmpKubeConfig)
+		if err != nil {
+			return err
+		}
+		state, err := machine.LoadMachineState(m.HostDir())
+		if err != nil {
+			return err
+		}
+		// entries merged by us before are safe to replace
+		replace := forceOverwrite || state.HasMergedKubeConfigPath(targetPath)
+		if err := kubeconfig.MergeFile(targetPath, blob, m.Name(), replace); err != nil {
+			if errors.Is(err, kubeconfig.ErrNameCollision) {
+				logger.Errorf("export: context (%s) already exists in %s, use --f to replace it\n", m.Name(), targetPath)
+			}
+			return err
+		}
+		state.AddMergedKubeConfigPath(targetPath)
+		if err := state.Save(m.HostDir()); err != nil {
+			return err
+		}
+		logger.V(0).Infof("export: kubeconfig is merged into %s as context %s, run `multikf use %s` to switch to it\n", targetPath, m.Name(), m.Name())
+		return nil
+	}
+	handle := func(machineName string) error {
+		if kubeconfigPath == "" && !merge {
+			return errors.New("export: --kubeconfig_path is required")
+		}
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		if merge {
+			return handleMerge(m)
+		}
+		if err := m.ExportKubeConfig(kubeconfigPath, forceOverwrite); err != nil {
+			logger.Errorf("export: export kubeconfig for (%s) failed, err:%+v\n", machineName, err)
+			return err
+		}
+		if forRemote != "" {
+			if err := kubeconfig.RewriteServerFile(kubeconfigPath, forRemote); err != nil {
+				return err
+			}
+		}
+		logger.V(0).Infof("export: kubeconfig is exported to %s\n", kubeconfigPath)
+		return nil
+	}
+	cmd := &cobra.Command{
+		Use:   "export <machine-name> [--kubeconfig_path <path>] [--merge]",
+		Short: "export kubeconfig of a guest machine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfigPath, "kubeconfig_path", "", "path to the exported kubeconfig, with --merge it defaults to $KUBECONFIG or ~/.kube/config")
+	cmd.Flags().BoolVar(&forceOverwrite, "f", false, "force to overwrite existing kubeconfig, or existing context with the same name when --merge. (default: false)")
+	cmd.Flags().StringVar(&forRemote, "for-remote", "", "rewrite kubeapi server address with this host for remote clients, the host should be one of --api_sans/--advertise_host (default: )")
+	cmd.Flags().BoolVar(&merge, "merge", false, "merge cluster/user/context named after the machine into an existing kubeconfig. (default: false)")
+	return cmd
+}
@@ -0,0 +1,160 @@
+package multikf
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	kubeflowplugin "github.com/footprintai/multikf/pkg/machine/plugins/kubeflow"
+)
+
+func NewKubeflowCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubeflow",
+		Short: "kubeflow",
+		Long:  `manage kubeflow installed on the machine`,
+	}
+	userCmd := &cobra.Command{
+		Use:   "user",
+		Short: "manage kubeflow users (dex static users and their profiles)",
+	}
+	userCmd.AddCommand(newAddKubeflowUserCommand(logger, ioStreams))
+	userCmd.AddCommand(newRemoveKubeflowUserCommand(logger, ioStreams))
+	userCmd.AddCommand(newListKubeflowUserCommand(logger, ioStreams))
+	cmd.AddCommand(userCmd)
+	return cmd
+}
+
+func newKubeflowUserManager(m machine.MachineCURD, logger log.Logger) *kubeflowplugin.UserManager {
+	return kubeflowplugin.NewUserManager(logger, m.GetKubeCli(), m.GetKubeConfig(), m.HostDir())
+}
+
+// addKubeflowUsers adds users in format of email:password with their default profiles
+func addKubeflowUsers(m machine.MachineCURD, logger log.Logger, userPasswords []string) error {
+	if len(userPasswords) == 0 {
+		return nil
+	}
+	var users []kubeflowplugin.User
+	for _, userPassword := range userPasswords {
+		email, password, err := kubeflowplugin.ParseUserPassword(userPassword)
+		if err != nil {
+			return err
+		}
+		users = append(users, kubeflowplugin.User{
+			Email:    email,
+			Password: password,
+			Profile:  kubeflowplugin.Profile{Name: kubeflowplugin.ProfileName(email), Owner: email},
+		})
+	}
+	return newKubeflowUserManager(m, logger).Add(users...)
+}
+
+func newAddKubeflowUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		password string // password for dex login
+		profile  string // profile name, default to one derived from email
+		cpu      string // cpu quota of the profile
+		memory   string // memory quota of the profile
+		gpu      string // gpu quota of the profile
+	)
+
+	handle := func(machineName string, email string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		user := kubeflowplugin.User{
+			Email:    email,
+			Password: password,
+			Profile: kubeflowplugin.Profile{
+				Name:  profile,
+				Owner: email,
+				Quota: map[string]string{},
+			},
+		}
+		if user.Profile.Name == "" {
+			user.Profile.Name = kubeflowplugin.ProfileName(email)
+		}
+		if cpu != "" {
+			user.Profile.Quota["cpu"] = cpu
+		}
+		if memory != "" {
+			user.Profile.Quota["memory"] = memory
+		}
+		if gpu != "" {
+			user.Profile.Quota["requests.nvidia.com/gpu"] = gpu
+		}
+		return newKubeflowUserManager(m, logger).Add(user)
+	}
+	cmd := &cobra.Command{
+		Use:   "add <machine-name> <email> --password <password>",
+		Short: "add a kubeflow user with its profile, password is updated if the user exists",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0], args[1])
+		},
+	}
+	cmd.Flags().StringVar(&password, "password", "", "password for the user to login kubeflow")
+	cmd.Flags().StringVar(&profile, "profile", "", "profile (namespace) owned by the user (default: kubeflow-<email with non-alphanumeric replaced by dash>)")
+	cmd.Flags().StringVar(&cpu, "cpu", "", "cpu quota of the profile, e.g. 4 (default: no limit)")
+	cmd.Flags().StringVar(&memory, "memory", "", "memory quota of the profile, e.g. 8Gi (default: no limit)")
+	cmd.Flags().StringVar(&gpu, "gpu", "", "gpu quota of the profile, e.g. 1 (default: no limit)")
+	cmd.MarkFlagRequired("password")
+	return cmd
+}
+
+func newRemoveKubeflowUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		keepProfile bool // keep profiles owned by the user
+	)
+	handle := func(machineName string, email string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		return newKubeflowUserManager(m, logger).Remove(email, keepProfile)
+	}
+	cmd := &cobra.Command{
+		Use:   "remove <machine-name> <email>",
+		Short: "remove a kubeflow user and profiles owned by it",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0], args[1])
+		},
+	}
+	cmd.Flags().BoolVar(&keepProfile, "keep_profile", false, "keep profiles (and their namespaces) owned by the user (default: false)")
+	return cmd
+}
+
+func newListKubeflowUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		users, err := newKubeflowUserManager(m, logger).List()
+		if err != nil {
+			return err
+		}
+		var values [][]string
+		for _, u := range users {
+			values = append(values, []string{u.Email, u.Username, strings.Join(u.Profiles, ",")})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"email", "username", "profiles"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "list <machine-name>",
+		Short: "list kubeflow users",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	return cmd
+}
@@ -2,6 +2,7 @@ package multikf
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -31,6 +32,15 @@ func NewListCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *c
 					//return
 					continue
 				}
+				if info.Status == "running" {
+					warnExpiringCertificates(m, logger)
+				}
+				var installed []string
+				if state, err := machine.LoadMachineState(m.HostDir()); err == nil {
+					for _, p := range state.Plugins {
+						installed = append(installed, p.String())
+					}
+				}
 				machineNamesMap[m.Name()] = &OutputMachineInfo{
 					Name:       m.Name(),
 					Type:       m.Type().String(),
@@ -40,6 +50,7 @@ func NewListCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *c
 					KubeApi:    info.KubeApi,
 					Cpus:       fmt.Sprintf("%d", info.CpuInfo.NumCPUs()),
 					Memory:     fmt.Sprintf("%s/%s", info.MemInfo.Free(), info.MemInfo.Total()),
+					Plugins:    strings.Join(installed, ","),
 				}
 			}
 		})
@@ -74,6 +85,7 @@ type OutputMachineInfo struct {
 	Gpus       string `json:"gpus"`
 	KubeApi    string `json:"kubeAPI"`
 	Memory     string `json:"memory"`
+	Plugins    string `json:"plugins"`
 }
 
 func (o *OutputMachineInfo) Headers() []string {
@@ -86,6 +98,7 @@ func (o *OutputMachineInfo) Headers() []string {
 		"kubeAPI",
 		"cpus",
 		"memory",
+		"plugins",
 	}
 }
 
@@ -99,5 +112,6 @@ func (o *OutputMachineInfo) Values() []string {
 		o.KubeApi,
 		o.Cpus,
 		o.Memory,
+		o.Plugins,
 	}
 }
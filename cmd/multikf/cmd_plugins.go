@@ -1,7 +1,14 @@
 package multikf
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/footprintai/multikf/pkg/machine/plugins"
+	"github.com/footprintai/multikf/pkg/machine/plugins/helm"
+	"github.com/footprintai/multikf/pkg/machine/plugins/source"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/kind/pkg/log"
@@ -17,73 +24,390 @@ func NewPluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams)
 	}
 	cmd.AddCommand(newAddPluginCommand(logger, ioStreams))
 	cmd.AddCommand(newRemovePluginCommand(logger, ioStreams))
+	cmd.AddCommand(newListPluginCommand(logger, ioStreams))
+	cmd.AddCommand(newStatusPluginCommand(logger, ioStreams))
+	cmd.AddCommand(newUpgradePluginCommand(logger, ioStreams))
 	return cmd
 }
 
+// parsePluginParams parses key=value pairs from --set
+func parsePluginParams(sets []string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, set := range sets {
+		tokens := strings.SplitN(set, "=", 2)
+		if len(tokens) != 2 || tokens[0] == "" {
+			return nil, fmt.Errorf("plugin: expect key=value, got %q", set)
+		}
+		params[tokens[0]] = tokens[1]
+	}
+	return params, nil
+}
+
+// parseKubeflowPVCSizes puts pvc sizes from --kubeflow_pvc_size into kubeflow's parameters,
+// e.g. pipeline_minio=50 becomes pipeline_minio_pvc_size=50
+func parseKubeflowPVCSizes(sizes []string, params map[string]string) error {
+	sizeParams, err := parsePluginParams(sizes)
+	if err != nil {
+		return err
+	}
+	for component, size := range sizeParams {
+		param := component + "_pvc_size"
+		supported := false
+		for _, sizeParam := range plugins.KubeflowPVCSizeParams() {
+			if sizeParam == param {
+				supported = true
+			}
+		}
+		if !supported {
+			return fmt.Errorf("plugin: unsupported kubeflow pvc %q, available pvcs: authservice,katib_mysql,pipeline_minio,pipeline_mysql", component)
+		}
+		params[param] = size
+	}
+	return nil
+}
+
+// withSourceParams puts --source and --source_sha256 into the plugin's parameters
+func withSourceParams(params map[string]string, location string, sha256 string) {
+	if location != "" {
+		params[source.ParamLocation] = location
+	}
+	if sha256 != "" {
+		params[source.ParamSHA256] = sha256
+	}
+}
+
 func newAddPluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
 	var (
-		withKubeflow                bool   // install with kubeflow components
-		withKubeflowVersion         string // with kubeflow version
-		withKubeflowDefaultPassword string // with kubeflow defaultpassword
+		withKubeflow                bool     // install with kubeflow components
+		withKubeflowVersion         string   // with kubeflow version
+		withKubeflowDefaultPassword string   // with kubeflow defaultpassword
+		withKubeflowUsers           []string // with kubeflow users in email:password
+		withKubeflowPVCSizes        []string // with kubeflow pvc sizes in component=GiB
+		withKubeflowComponents      string   // with kubeflow profile or components
+		version                     string   // version of the named plugin
+		sets                        []string // parameters of the named plugin
+		valuesFiles                 []string // values files of helm chart
+		helmSets                    []string // value overrides of helm chart
+		sourceLocation              string   // manifests from a directory, tarball or url
+		sourceSHA256                string   // sha256 of the source
+		ignoreCompatibility         bool     // install kubeflow on unsupported k8s versions
 	)
 
-	handle := func(machineName string) error {
+	handle := func(machineName string, pluginName string) error {
+		params, err := parsePluginParams(sets)
+		if err != nil {
+			return err
+		}
+		if len(valuesFiles) > 0 {
+			params[helm.ParamValues] = plugins.ListParam(valuesFiles...)
+		}
+		if len(helmSets) > 0 {
+			params[helm.ParamSet] = strings.Join(helmSets, ",")
+		}
+		withSourceParams(params, sourceLocation, sourceSHA256)
+		if pluginName == "" && withKubeflow {
+			// legacy flags: plugin add <machine-name> --with_kubeflow
+			pluginName, version = plugins.TypePluginKubeflow.String(), withKubeflowVersion
+		}
+		if pluginName == "" {
+			return errors.New("plugin: no plugin specified")
+		}
+		pluginType, err := plugins.ParseTypePlugin(pluginName)
+		if err != nil {
+			return err
+		}
+		if pluginType == plugins.TypePluginKubeflow {
+			if version == "" {
+				version = withKubeflowVersion
+			}
+			if version == "" && sourceLocation == "" {
+				// resolved here so the compatibility is checked against the version installed
+				version = defaultKubeflowVersion()
+			}
+			if _, found := params[plugins.KubeflowParamPassword]; !found {
+				params[plugins.KubeflowParamPassword] = withKubeflowDefaultPassword
+			}
+			if err := parseKubeflowPVCSizes(withKubeflowPVCSizes, params); err != nil {
+				return err
+			}
+			if _, found := params[plugins.KubeflowParamComponents]; !found && withKubeflowComponents != "" {
+				params[plugins.KubeflowParamComponents] = withKubeflowComponents
+			}
+		}
 		m, err := findMachineByName(machineName, logger)
 		if err != nil {
 			return err
 		}
-		var installedPlugins []plugins.Plugin
-		if withKubeflow {
-			installedPlugins = append(installedPlugins,
-				kubeflowPlugin{
-					withKubeflowDefaultPassword: withKubeflowDefaultPassword,
-					kubeflowVersion:             plugins.NewTypePluginVersion(withKubeflowVersion),
-				},
-			)
+		if pluginType == plugins.TypePluginKubeflow {
+			if err := checkMachineKubeflowCompatibility(logger, m, version, ignoreCompatibility); err != nil {
+				return err
+			}
+		}
+		if err := plugins.AddPlugins(logger, m, plugins.NewPlugin(pluginType, plugins.NewTypePluginVersion(version), params)); err != nil {
+			return err
+		}
+		if pluginType == plugins.TypePluginKubeflow {
+			return addKubeflowUsers(m, logger, withKubeflowUsers)
 		}
-		return plugins.AddPlugins(m, installedPlugins...)
+		return nil
 	}
 	cmd := &cobra.Command{
-		Use:   "add <machine-name> --with_kubeflow",
-		Short: "add kubeflow plugins to the machine",
+		Use:   "add <machine-name> [<plugin-name>] [--version <version>] [--set key=value]",
+		Short: "add a plugin to the machine, kubeflow is added if no plugin is named",
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return handle(args[0])
+			var pluginName string
+			if len(args) > 1 {
+				pluginName = args[1]
+			}
+			return handle(args[0], pluginName)
 		},
 	}
 
+	cmd.Flags().StringVar(&version, "version", "", "version of the plugin, see `multikf plugin list` (default: latest)")
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "plugin parameters, format: key=value, repeatable (default: )")
+	cmd.Flags().StringArrayVar(&valuesFiles, "values", nil, "values files for helm plugin, repeatable (default: )")
+	cmd.Flags().StringArrayVar(&helmSets, "helm_set", nil, "value overrides for helm plugin, format: key=value, repeatable (default: )")
+	cmd.Flags().BoolVar(&ignoreCompatibility, "ignore_compatibility", false, "install kubeflow even if it doesn't support the machine's k8s version, see `multikf versions` (default: false)")
+	cmd.Flags().StringVar(&sourceLocation, "source", "", "load manifests from a local directory, a tarball or an http(s) url instead of embedded ones, kubeflow only (default: )")
+	cmd.Flags().StringVar(&sourceSHA256, "source_sha256", "", "sha256 of --source, required with --source (default: )")
 	cmd.Flags().BoolVar(&withKubeflow, "with_kubeflow", true, "install kubeflow modules (default: true)")
-	cmd.Flags().StringVar(&withKubeflowVersion, "kubeflow_version", "v1.4", "kubeflow version v1.4/v1.5.1")
+	cmd.Flags().StringVar(&withKubeflowVersion, "kubeflow_version", "", "kubeflow version, see `multikf plugin list` (default: latest)")
 	cmd.Flags().StringVar(&withKubeflowDefaultPassword, "with_password", "12341234", "with a specific password for default user (default: 12341234)")
+	cmd.Flags().StringArrayVar(&withKubeflowUsers, "kubeflow_user", nil, "add kubeflow user with its own profile, format: email:password, repeatable (default: )")
+	cmd.Flags().StringVar(&withKubeflowComponents, "kubeflow_components", "", "kubeflow profile (full|lite|pipelines-only) or components delimited by comma: pipelines,notebooks,katib,training-operator,kserve (default: full)")
+	cmd.Flags().StringArrayVar(&withKubeflowPVCSizes, "kubeflow_pvc_size", nil, "kubeflow pvc size in GiB, format: authservice|katib_mysql|pipeline_minio|pipeline_mysql=size, repeatable (default: 10,10,20,20)")
 
 	return cmd
 }
 
 func newRemovePluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
 	var (
-		removeKubeflow        bool   // remove kubeflow components
-		removeKubeflowVersion string // with kubeflow version
+		removeKubeflow        bool     // remove kubeflow components
+		removeKubeflowVersion string   // with kubeflow version
+		version               string   // version of the named plugin
+		sets                  []string // parameters of the named plugin
 	)
 
-	handle := func(machineName string) error {
-		m, err := findMachineByName(machineName, logger)
+	handle := func(machineName string, pluginName string) error {
+		params, err := parsePluginParams(sets)
+		if err != nil {
+			return err
+		}
+		if pluginName == "" && removeKubeflow {
+			// legacy flags: plugin remove <machine-name> --remove_kubeflow
+			pluginName, version = plugins.TypePluginKubeflow.String(), removeKubeflowVersion
+		}
+		if pluginName == "" {
+			return errors.New("plugin: no plugin specified")
+		}
+		pluginType, err := plugins.ParseTypePlugin(pluginName)
 		if err != nil {
 			return err
 		}
-		var removingPlugins []plugins.Plugin
-		if removeKubeflow {
-			removingPlugins = append(removingPlugins, kubeflowPlugin{kubeflowVersion: plugins.NewTypePluginVersion(removeKubeflowVersion)})
+		if pluginType == plugins.TypePluginKubeflow && version == "" {
+			version = removeKubeflowVersion
+		}
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
 		}
-		return plugins.RemovePlugins(m, removingPlugins...)
+		return plugins.RemovePlugins(logger, m, plugins.NewPlugin(pluginType, plugins.NewTypePluginVersion(version), params))
 	}
 	cmd := &cobra.Command{
-		Use:   "remove <machine-name> --remove_kubeflow",
-		Short: "rmeove kubeflow plugins to the machine",
+		Use:   "remove <machine-name> [<plugin-name>] [--version <version>]",
+		Short: "rmeove a plugin from the machine",
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return handle(args[0])
+			var pluginName string
+			if len(args) > 1 {
+				pluginName = args[1]
+			}
+			return handle(args[0], pluginName)
 		},
 	}
 
+	cmd.Flags().StringVar(&version, "version", "", "version of the plugin (default: installed version)")
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "plugin parameters, format: key=value, repeatable (default: )")
 	cmd.Flags().BoolVar(&removeKubeflow, "remove_kubeflow", false, "remove kubeflow modules (default: false)")
-	cmd.Flags().StringVar(&removeKubeflowVersion, "kubeflow_version", "v1.4", "kubeflow version v1.4/v1.5.1")
+	cmd.Flags().StringVar(&removeKubeflowVersion, "kubeflow_version", "", "kubeflow version (default: installed version)")
+	return cmd
+}
+
+func newUpgradePluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		version             string   // version to upgrade to
+		upgradeKubeflowVer  string   // kubeflow version to upgrade to
+		sets                []string // parameters of the named plugin
+		dryRun              bool     // print the plan only
+		exportDB            bool     // export databases before upgrading
+		pruneCascading      bool     // prune removed namespaces and crds
+		sourceLocation      string   // manifests from a directory, tarball or url
+		sourceSHA256        string   // sha256 of the source
+		ignoreCompatibility bool     // upgrade kubeflow to versions unsupported by k8s
+	)
+
+	handle := func(machineName string, pluginName string) error {
+		params, err := parsePluginParams(sets)
+		if err != nil {
+			return err
+		}
+		withSourceParams(params, sourceLocation, sourceSHA256)
+		if pluginName == "" {
+			pluginName = plugins.TypePluginKubeflow.String()
+		}
+		pluginType, err := plugins.ParseTypePlugin(pluginName)
+		if err != nil {
+			return err
+		}
+		if pluginType == plugins.TypePluginKubeflow && version == "" {
+			version = upgradeKubeflowVer
+		}
+		if pluginType == plugins.TypePluginKubeflow && version == "" && sourceLocation == "" {
+			version = defaultKubeflowVersion()
+		}
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		if pluginType == plugins.TypePluginKubeflow {
+			if err := checkMachineKubeflowCompatibility(logger, m, version, ignoreCompatibility); err != nil {
+				return err
+			}
+		}
+		return plugins.UpgradePlugin(logger, m, plugins.NewPlugin(pluginType, plugins.NewTypePluginVersion(version), params), plugins.UpgradeOptions{
+			DryRun:         dryRun,
+			ExportDB:       exportDB,
+			PruneCascading: pruneCascading,
+			Out:            ioStreams.Out,
+		})
+	}
+	cmd := &cobra.Command{
+		Use:   "upgrade <machine-name> [<plugin-name>] [--version <version>]",
+		Short: "upgrade a plugin on the machine in place, kubeflow is upgraded if no plugin is named",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var pluginName string
+			if len(args) > 1 {
+				pluginName = args[1]
+			}
+			return handle(args[0], pluginName)
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "version to upgrade to, see `multikf plugin list` (default: latest for kubeflow)")
+	cmd.Flags().StringVar(&upgradeKubeflowVer, "kubeflow_version", "", "kubeflow version to upgrade to (default: latest)")
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "plugin parameters overriding installed ones, format: key=value, repeatable (default: )")
+	cmd.Flags().BoolVar(&dryRun, "dry_run", false, "print objects to add, change and prune without upgrading (default: false)")
+	cmd.Flags().StringVar(&sourceLocation, "source", "", "load manifests of the new version from a local directory, a tarball or an http(s) url, kubeflow only (default: )")
+	cmd.Flags().StringVar(&sourceSHA256, "source_sha256", "", "sha256 of --source, required with --source (default: )")
+	cmd.Flags().BoolVar(&ignoreCompatibility, "ignore_compatibility", false, "upgrade kubeflow even if it doesn't support the machine's k8s version (default: false)")
+	cmd.Flags().BoolVar(&exportDB, "export_db", false, "export kubeflow's mysql databases under the machine's folder before upgrading (default: false)")
+	cmd.Flags().BoolVar(&pruneCascading, "prune_cascading", false, "prune namespaces and crds removed in the new version, which deletes every object inside them, e.g. profiles (default: false)")
+	return cmd
+}
+
+func newListPluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handleAvailable := func() error {
+		var values [][]string
+		for _, pluginType := range plugins.ListPlugins() {
+			installer, err := plugins.NewInstaller(pluginType, logger)
+			if err != nil {
+				return err
+			}
+			var versions []string
+			for _, version := range installer.Versions() {
+				versions = append(versions, version.String())
+			}
+			values = append(values, []string{pluginType.String(), strings.Join(versions, ","), installer.Description()})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"plugin", "versions", "description"},
+			values,
+		)
+	}
+	handleInstalled := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		installed, err := plugins.InstalledPlugins(m)
+		if err != nil {
+			return err
+		}
+		var values [][]string
+		for _, p := range installed {
+			values = append(values, []string{
+				p.Name,
+				p.Instance,
+				p.Version,
+				plugins.FormatParams(p.Params),
+				p.InstalledAt.Format(time.RFC3339),
+				p.Status,
+			})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"plugin", "instance", "version", "params", "installedAt", "status"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "list [<machine-name>]",
+		Short: "list available plugins, or plugins installed on the machine",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				return handleInstalled(args[0])
+			}
+			return handleAvailable()
+		},
+	}
+	return cmd
+}
+
+func newStatusPluginCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string, pluginName string) error {
+		pluginTypes := plugins.ListPlugins()
+		if pluginName != "" {
+			pluginType, err := plugins.ParseTypePlugin(pluginName)
+			if err != nil {
+				return err
+			}
+			pluginTypes = []plugins.TypePlugin{pluginType}
+		}
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		var values [][]string
+		for _, pluginType := range pluginTypes {
+			installer, err := plugins.NewInstaller(pluginType, logger)
+			if err != nil {
+				return err
+			}
+			status, err := installer.Status(m)
+			if err != nil {
+				logger.Errorf("plugin: get status of %s failed, err:%+v\n", pluginType, err)
+				status = "unknown"
+			}
+			values = append(values, []string{pluginType.String(), status.String()})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"plugin", "status"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "status <machine-name> [<plugin-name>]",
+		Short: "show status of plugins on the machine",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var pluginName string
+			if len(args) > 1 {
+				pluginName = args[1]
+			}
+			return handle(args[0], pluginName)
+		},
+	}
 	return cmd
 }
@@ -0,0 +1,48 @@
+package multikf
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/kubeconfig"
+)
+
+func NewUseCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		kubeconfigPath string // path to the merged kubeconfig
+	)
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		targetPath := kubeconfigPath
+		if targetPath == "" {
+			state, err := machine.LoadMachineState(m.HostDir())
+			if err != nil {
+				return err
+			}
+			targetPath = state.LastMergedKubeConfigPath()
+		}
+		if targetPath == "" {
+			targetPath = kubeconfig.DefaultPath()
+		}
+		if err := kubeconfig.UseContextFile(targetPath, m.Name()); err != nil {
+			logger.Errorf("use: switch context failed, run `multikf export %s --merge` first, err:%+v\n", machineName, err)
+			return err
+		}
+		logger.V(0).Infof("use: switched to context %s in %s\n", m.Name(), targetPath)
+		return nil
+	}
+	cmd := &cobra.Command{
+		Use:   "use <machine-name>",
+		Short: "switch current context to a guest machine merged by `export --merge`",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	cmd.Flags().StringVar(&kubeconfigPath, "kubeconfig_path", "", "path to the merged kubeconfig, default to the path used by `export --merge`")
+	return cmd
+}
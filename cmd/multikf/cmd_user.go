@@ -0,0 +1,178 @@
+package multikf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/kubeconfig"
+	"github.com/footprintai/multikf/pkg/machine/users"
+)
+
+func NewUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "user",
+		Long:  `manage namespace scoped users with their own kubeconfig`,
+	}
+	cmd.AddCommand(newAddUserCommand(logger, ioStreams))
+	cmd.AddCommand(newListUserCommand(logger, ioStreams))
+	cmd.AddCommand(newRevokeUserCommand(logger, ioStreams))
+	return cmd
+}
+
+// userKubeConfigDir is where users' kubeconfigs are written by default
+func userKubeConfigDir(m machine.MachineCURD) string {
+	return filepath.Join(m.HostDir(), "users")
+}
+
+func newAddUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	var (
+		namespace string // namespace the user is scoped to, default to the user name
+		role      string // clusterrole bound within the namespace
+		fromCsv   string // bulk creation from csv file
+		outputDir string // dir for generated kubeconfigs
+		forRemote string // rewrite server url with the host for remote clients
+	)
+
+	handle := func(machineName string, userName string) error {
+		defaultRole, err := users.ParseRole(role)
+		if err != nil {
+			return err
+		}
+		var addingUsers []users.User
+		if fromCsv != "" {
+			f, err := os.Open(fromCsv)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if addingUsers, err = users.ParseCSV(f, defaultRole); err != nil {
+				return err
+			}
+		} else {
+			if userName == "" {
+				return errors.New("user: either <user-name> or --from_csv is required")
+			}
+			userNamespace := namespace
+			if userNamespace == "" {
+				userNamespace = userName
+			}
+			addingUsers = append(addingUsers, users.User{Name: userName, Namespace: userNamespace, Role: defaultRole})
+		}
+		for _, u := range addingUsers {
+			if err := u.Validate(); err != nil {
+				return err
+			}
+		}
+
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		dir := outputDir
+		if dir == "" {
+			dir = userKubeConfigDir(m)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		for _, u := range addingUsers {
+			blob, err := users.Add(logger, m.GetKubeCli(), m.GetKubeConfig(), m.HostDir(), u)
+			if err != nil {
+				logger.Errorf("user: add user (%s) failed, err:%+v\n", u.Name, err)
+				return err
+			}
+			if forRemote != "" {
+				if blob, err = kubeconfig.RewriteServer(blob, forRemote); err != nil {
+					return err
+				}
+			}
+			kubeconfigPath := filepath.Join(dir, fmt.Sprintf("%s.kubeconfig", u.Name))
+			if err := os.WriteFile(kubeconfigPath, blob, 0600); err != nil {
+				return err
+			}
+			logger.V(0).Infof("user: kubeconfig for %s is exported to %s\n", u.Name, kubeconfigPath)
+		}
+		return nil
+	}
+	cmd := &cobra.Command{
+		Use:   "add <machine-name> [<user-name>] [--namespace ns] [--role edit] [--from_csv users.csv]",
+		Short: "add namespace scoped users and export their kubeconfigs",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var userName string
+			if len(args) > 1 {
+				userName = args[1]
+			}
+			return handle(args[0], userName)
+		},
+	}
+	cmd.Flags().StringVar(&namespace, "namespace", "", "namespace the user is scoped to, created if not exist (default: same as user name)")
+	cmd.Flags().StringVar(&role, "role", users.RoleEdit.String(), "role bound within the namespace: view/edit/admin")
+	cmd.Flags().StringVar(&fromCsv, "from_csv", "", "create users in bulk from a csv file with lines of user,namespace[,role]")
+	cmd.Flags().StringVar(&outputDir, "output_dir", "", "dir for exported kubeconfigs (default: $machinedir/users)")
+	cmd.Flags().StringVar(&forRemote, "for-remote", "", "rewrite kubeapi server address with this host for remote clients (default: )")
+	return cmd
+}
+
+func newListUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		userList, err := users.List(m.GetKubeCli(), m.GetKubeConfig())
+		if err != nil {
+			return err
+		}
+		var values [][]string
+		for _, u := range userList {
+			values = append(values, []string{u.Name, u.Namespace, u.Role.String()})
+		}
+		return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(
+			[]string{"user", "namespace", "role"},
+			values,
+		)
+	}
+	cmd := &cobra.Command{
+		Use:   "list <machine-name>",
+		Short: "list users of the machine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0])
+		},
+	}
+	return cmd
+}
+
+func newRevokeUserCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	handle := func(machineName string, userName string) error {
+		m, err := findMachineByName(machineName, logger)
+		if err != nil {
+			return err
+		}
+		if err := users.Revoke(logger, m.GetKubeCli(), m.GetKubeConfig(), userName); err != nil {
+			return err
+		}
+		kubeconfigPath := filepath.Join(userKubeConfigDir(m), fmt.Sprintf("%s.kubeconfig", userName))
+		if err := os.Remove(kubeconfigPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	cmd := &cobra.Command{
+		Use:   "revoke <machine-name> <user-name>",
+		Short: "revoke a user, its kubeconfig stops working",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handle(args[0], args[1])
+		},
+	}
+	return cmd
+}
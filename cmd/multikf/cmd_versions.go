@@ -0,0 +1,120 @@
+package multikf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	kfmanifests "github.com/footprintai/multikf/kfmanifests"
+	"github.com/footprintai/multikf/pkg/k8s"
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+func NewVersionsCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions",
+		Short: "show compatibility of kubeflow versions with k8s versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			headers := []string{"kubeflow", "tested k8s", "min cpus", "min memory"}
+			for _, k8sVersion := range k8s.ListVersion() {
+				headers = append(headers, k8sVersion.Version())
+			}
+			var values [][]string
+			for _, kfVersion := range kfmanifests.ListVersions() {
+				info, _ := kfmanifests.GetVersionInfo(kfVersion)
+				row := []string{kfVersion, info.TestedK8s.String(), strconv.Itoa(info.MinResources.Cpus), fmt.Sprintf("%dG", info.MinResources.MemoryInG)}
+				for _, k8sVersion := range k8s.ListVersion() {
+					compatibility, err := kfmanifests.CheckCompatibility(kfVersion, k8sVersion.Version())
+					if err != nil {
+						return err
+					}
+					row = append(row, compatibility.String())
+				}
+				values = append(values, row)
+			}
+			return NewFormatWriter(ioStreams.Out, Table).WriteAndClose(headers, values)
+		},
+	}
+	return cmd
+}
+
+// checkKubeflowCompatibility rejects kubeflow versions unsupported by the k8s version unless ignored, and warns about untested ones
+func checkKubeflowCompatibility(logger log.Logger, kfVersion string, k8sVersion string, ignore bool) error {
+	compatibility, err := kfmanifests.CheckCompatibility(kfVersion, k8sVersion)
+	if err != nil {
+		return err
+	}
+	info, _ := kfmanifests.GetVersionInfo(kfVersion)
+	switch compatibility {
+	case kfmanifests.CompatibilityUnknown:
+		logger.Warnf("compat: no compatibility metadata of kubeflow %s, see `multikf versions`\n", kfVersion)
+	case kfmanifests.CompatibilityUntested:
+		logger.Warnf("compat: kubeflow %s is not tested on k8s %s (tested: %s), see `multikf versions`\n", kfVersion, k8sVersion, info.TestedK8s)
+	case kfmanifests.CompatibilityUnsupported:
+		err := fmt.Errorf("compat: kubeflow %s doesn't support k8s %s (supported: %s), see `multikf versions` or pass --ignore_compatibility", kfVersion, k8sVersion, info.SupportedK8s)
+		if !ignore {
+			return err
+		}
+		logger.Warnf("%+v\n", err)
+	}
+	return nil
+}
+
+// checkMinResources warns if cpus or memory are below the minimum of kubeflow and kubeadm,
+// the k8s version is not taken as kubeadm's minimum is the same for every listed k8s version
+func checkMinResources(logger log.Logger, kfVersion string, cpus int, memoryInG int) {
+	required := k8s.KubeadmMinResources
+	if info, found := kfmanifests.GetVersionInfo(kfVersion); found {
+		if info.MinResources.Cpus > required.Cpus {
+			required.Cpus = info.MinResources.Cpus
+		}
+		if info.MinResources.MemoryInG > required.MemoryInG {
+			required.MemoryInG = info.MinResources.MemoryInG
+		}
+	}
+	if cpus < required.Cpus || memoryInG < required.MemoryInG {
+		logger.Warnf("compat: kubeflow %s recommends at least %d cpus and %dG memory, got %d cpus and %dG\n",
+			kfVersion, required.Cpus, required.MemoryInG, cpus, memoryInG)
+	}
+}
+
+// defaultKubeflowVersion returns the latest embedded kubeflow version, which plugins default to
+func defaultKubeflowVersion() string {
+	versions := kfmanifests.ListVersions()
+	if len(versions) == 0 {
+		return ""
+	}
+	return versions[0]
+}
+
+// checkMachineKubeflowCompatibility checks the kubeflow version against the machine's k8s version,
+// the check is skipped with a warning if the machine's version is not available
+func checkMachineKubeflowCompatibility(logger log.Logger, m machine.MachineCURD, kfVersion string, ignore bool) error {
+	k8sVersion, err := serverK8sVersion(m)
+	if err != nil {
+		logger.Warnf("compat: unable to get k8s version of %s, skip compatibility check, err:%+v\n", m.Name(), err)
+		return nil
+	}
+	return checkKubeflowCompatibility(logger, kfVersion, k8sVersion, ignore)
+}
+
+// serverK8sVersion returns the k8s version (e.g. v1.28.13) of the machine's kubeapi
+func serverK8sVersion(m machine.MachineCURD) (string, error) {
+	out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "version", "-o", "json")
+	if err != nil {
+		return "", err
+	}
+	v := struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}{}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return "", err
+	}
+	return v.ServerVersion.GitVersion, nil
+}
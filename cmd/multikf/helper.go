@@ -3,12 +3,10 @@ package multikf
 import (
 	"encoding/json"
 	"errors"
-	"strconv"
 	"strings"
 
 	"github.com/footprintai/multikf/pkg/k8s"
 	"github.com/footprintai/multikf/pkg/machine"
-	"github.com/footprintai/multikf/pkg/machine/plugins"
 	"sigs.k8s.io/kind/pkg/log"
 )
 
@@ -65,19 +63,25 @@ var (
 )
 
 type machineConfig struct {
-	logger          log.Logger
-	Cpus            int                `json:"cpus"`
-	MemoryInG       int                `json:"memoryInG"`
-	UseGPUs         int                `json:"useGpus"`
-	KubeAPIIP       string             `json:"kubeapi_ip"`
-	ExportPorts     string             `json:"export_ports"`
-	DefaultPassword string             `json:"default_password"`
-	ForceOverwrite  bool               `json:"force_overwrite"`
-	IsAuditEnabled  bool               `json:"audit_enabled"`
-	Workers         int                `json:"workers"`
-	NodeLabels      string             `json:"node_labels"`
-	LocalPath       string             `json:"local_path"`
-	NodeVersion     k8s.KindK8sVersion `json:"node_version"`
+	logger            log.Logger
+	Cpus              int                      `json:"cpus"`
+	MemoryInG         int                      `json:"memoryInG"`
+	UseGPUs           int                      `json:"useGpus"`
+	KubeAPIIP         string                   `json:"kubeapi_ip"`
+	ExportPorts       []machine.ExportPortPair `json:"export_ports"`
+	DefaultPassword   string                   `json:"default_password"`
+	ForceOverwrite    bool                     `json:"force_overwrite"`
+	IsAuditEnabled    bool                     `json:"audit_enabled"`
+	AuditPolicy       string                   `json:"audit_policy"`
+	ControlPlanes     int                      `json:"control_planes"`
+	Workers           int                      `json:"workers"`
+	WorkerGroups      []machine.WorkerGroup    `json:"worker_groups"`
+	NodeLabels        string                   `json:"node_labels"`
+	LocalPath         string                   `json:"local_path"`
+	NodeVersion       k8s.KindK8sVersion       `json:"node_version"`
+	CNI               k8s.CNI                  `json:"cni"`
+	APIServerCertSANs []string                 `json:"apiserver_cert_sans"`
+	KindPatches       []string                 `json:"kind_patches"`
 }
 
 func (m machineConfig) Info() string {
@@ -90,6 +94,18 @@ func (m machineConfig) GetNodeVersion() k8s.KindK8sVersion {
 	return m.NodeVersion
 }
 
+func (m machineConfig) GetCNI() k8s.CNI {
+	return m.CNI
+}
+
+func (m machineConfig) GetAPIServerCertSANs() []string {
+	return m.APIServerCertSANs
+}
+
+func (m machineConfig) GetKindPatches() []string {
+	return m.KindPatches
+}
+
 func (m machineConfig) GetCPUs() int {
 	return m.Cpus
 }
@@ -111,43 +127,30 @@ func (m machineConfig) AuditEnabled() bool {
 	return m.IsAuditEnabled
 }
 
+func (m machineConfig) GetAuditPolicy() string {
+	return m.AuditPolicy
+}
+
 func (m machineConfig) GetExportPorts() []machine.ExportPortPair {
-	if len(m.ExportPorts) == 0 {
-		m.logger.V(1).Infof("getexportport: export nothing\n")
-		return nil
-	}
-	tokens := strings.Split(m.ExportPorts, ",")
-	var exportPorts []machine.ExportPortPair
-	for _, token := range tokens {
-		subtokens := strings.Split(token, ":")
-		if len(subtokens) != 2 {
-			m.logger.Errorf("getexportport: parse failed, expect: a:b but got:%s\n", token)
-			continue
-		}
-		hostport, err := strconv.Atoi(subtokens[0])
-		if err != nil {
-			m.logger.Errorf("getexportport: parse failed, err:%+v\n", err)
-			continue
-		}
-		containerport, err := strconv.Atoi(subtokens[1])
-		if err != nil {
-			m.logger.Errorf("getexportport: parse failed, err:%+v\n", err)
-			continue
-		}
-		exportPorts = append(exportPorts, machine.ExportPortPair{HostPort: hostport, ContainerPort: containerport})
-	}
-	m.logger.V(1).Infof("getexportport: export ports:%+v\n", exportPorts)
-	return exportPorts
+	return m.ExportPorts
 }
 
 func (m machineConfig) GetForceOverwriteConfig() bool {
 	return m.ForceOverwrite
 }
 
+func (m machineConfig) GetControlPlanes() int {
+	return m.ControlPlanes
+}
+
 func (m machineConfig) GetWorkers() int {
 	return m.Workers
 }
 
+func (m machineConfig) GetWorkerGroups() []machine.WorkerGroup {
+	return m.WorkerGroups
+}
+
 // a=b,c=d
 func (m machineConfig) GetNodeLabels() []machine.NodeLabel {
 	if len(m.NodeLabels) == 0 {
@@ -171,21 +174,3 @@ func (m machineConfig) GetNodeLabels() []machine.NodeLabel {
 func (m machineConfig) GetLocalPath() string {
 	return m.LocalPath
 }
-
-type kubeflowPlugin struct {
-	withKubeflowDefaultPassword string
-	kubeflowVersion             plugins.TypePluginVersion
-}
-
-func (k kubeflowPlugin) PluginType() plugins.TypePlugin {
-	return plugins.TypePluginKubeflow
-}
-
-func (k kubeflowPlugin) PluginVersion() plugins.TypePluginVersion {
-	return k.kubeflowVersion
-
-}
-
-func (k kubeflowPlugin) GetDefaultPassword() string {
-	return k.withKubeflowDefaultPassword
-}
@@ -10,6 +10,10 @@ import (
 	"sigs.k8s.io/kind/pkg/log"
 
 	_ "github.com/footprintai/multikf/pkg/machine/docker"
+	_ "github.com/footprintai/multikf/pkg/machine/plugins/gpu"
+	_ "github.com/footprintai/multikf/pkg/machine/plugins/helm"
+	_ "github.com/footprintai/multikf/pkg/machine/plugins/monitoring"
+	_ "github.com/footprintai/multikf/pkg/machine/plugins/storage"
 	_ "github.com/footprintai/multikf/pkg/machine/vagrant"
 )
 
@@ -29,11 +33,19 @@ func NewRootCommand(logger log.Logger, ioStreams genericclioptions.IOStreams) *c
 		},
 	}
 	cmd.AddCommand(NewVersionCommand(logger, ioStreams))
+	cmd.AddCommand(NewVersionsCommand(logger, ioStreams))
 	cmd.AddCommand(NewAddCommand(logger, ioStreams))
 	cmd.AddCommand(NewListCommand(logger, ioStreams))
+	cmd.AddCommand(NewDescribeCommand(logger, ioStreams))
+	cmd.AddCommand(NewExportCommand(logger, ioStreams))
+	cmd.AddCommand(NewUseCommand(logger, ioStreams))
 	cmd.AddCommand(NewDeleteCommand(logger, ioStreams))
 	cmd.AddCommand(NewConnectCommand(logger, ioStreams))
 	cmd.AddCommand(NewPluginCommand(logger, ioStreams))
+	cmd.AddCommand(NewUserCommand(logger, ioStreams))
+	cmd.AddCommand(NewCertsCommand(logger, ioStreams))
+	cmd.AddCommand(NewAuditCommand(logger, ioStreams))
+	cmd.AddCommand(NewKubeflowCommand(logger, ioStreams))
 
 	cmd.PersistentFlags().StringVar(&guestRootDir, "dir", ".multikfdir", "multikf root dir")
 	cmd.PersistentFlags().BoolVar(&verbose, "verbose", true, "verbose (default: true)")
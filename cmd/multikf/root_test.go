@@ -0,0 +1,83 @@
+package multikf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+	"github.com/footprintai/multikf/pkg/machine/plugins/gpu"
+	"github.com/footprintai/multikf/pkg/machine/plugins/helm"
+	"github.com/footprintai/multikf/pkg/machine/plugins/monitoring"
+	"github.com/footprintai/multikf/pkg/machine/plugins/storage"
+)
+
+// defaultPlugin returns the plugin with the installer's default version
+func defaultPlugin(t *testing.T, typePlugin plugins.TypePlugin, params map[string]string) plugins.Plugin {
+	installer, err := plugins.NewInstaller(typePlugin, log.NoopLogger{})
+	assert.NoError(t, err)
+	var version plugins.TypePluginVersion
+	if versions := installer.Versions(); len(versions) > 0 {
+		version = versions[0]
+	}
+	return plugins.NewPlugin(typePlugin, version, params)
+}
+
+func TestPluginsGraph(t *testing.T) {
+	assert.EqualValues(t, []plugins.TypePlugin{
+		gpu.TypePluginGPU,
+		helm.TypePluginHelm,
+		plugins.TypePluginKubeflow,
+		monitoring.TypePluginMonitoring,
+		storage.TypePluginStorage,
+	}, plugins.ListPlugins())
+
+	var all []plugins.Plugin
+	for _, typePlugin := range plugins.ListPlugins() {
+		all = append(all, defaultPlugin(t, typePlugin, nil))
+	}
+	operator := defaultPlugin(t, gpu.TypePluginGPU, map[string]string{gpu.ParamMode: gpu.ModeOperator.String()})
+	assert.NoError(t, plugins.CheckGraph(log.NoopLogger{}, append(all, operator)...))
+
+	dependencies := map[plugins.TypePlugin][]plugins.TypePlugin{}
+	for _, p := range append(all, operator) {
+		installer, err := plugins.NewInstaller(p.PluginType(), log.NoopLogger{})
+		assert.NoError(t, err)
+		if dependent, ok := installer.(plugins.Dependent); ok {
+			dependencies[p.PluginType()] = append(dependencies[p.PluginType()], dependent.Dependencies(p)...)
+		}
+	}
+	assert.EqualValues(t, map[plugins.TypePlugin][]plugins.TypePlugin{
+		gpu.TypePluginGPU:               {helm.TypePluginHelm},
+		monitoring.TypePluginMonitoring: {helm.TypePluginHelm},
+	}, dependencies, "helm is a dependency of gpu's operator mode only")
+
+	// storage goes before plugins creating pvcs even if it is requested last
+	order, err := plugins.InstallOrder(log.NoopLogger{},
+		defaultPlugin(t, plugins.TypePluginKubeflow, nil),
+		defaultPlugin(t, monitoring.TypePluginMonitoring, nil),
+		defaultPlugin(t, storage.TypePluginStorage, nil),
+		operator,
+	)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []plugins.TypePlugin{
+		storage.TypePluginStorage,
+		plugins.TypePluginKubeflow,
+		monitoring.TypePluginMonitoring,
+		gpu.TypePluginGPU,
+	}, order)
+
+	installer, err := plugins.NewInstaller(helm.TypePluginHelm, log.NoopLogger{})
+	assert.NoError(t, err)
+	embedder, ok := installer.(plugins.Embedder)
+	assert.True(t, ok && embedder.Embedded(), "helm's releases are installed by its dependents")
+
+	// --source is rejected by every plugin but kubeflow
+	for _, typePlugin := range plugins.ListPlugins() {
+		installer, err := plugins.NewInstaller(typePlugin, log.NoopLogger{})
+		assert.NoError(t, err)
+		supporter, ok := installer.(plugins.SourceSupporter)
+		assert.EqualValues(t, typePlugin == plugins.TypePluginKubeflow, ok && supporter.SupportsSource(), typePlugin.String())
+	}
+}
@@ -1,5 +1,7 @@
 #!/usr/bin/env bash
 
+# deprecated: use `multikf plugin add <machine-name> gpu --set mode=operator` instead
+
 # run as root
 if (( $EUID != 0 )); then
    echo "this script should be running as root identity"
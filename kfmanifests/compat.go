@@ -0,0 +1,106 @@
+package kfmanifests
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/footprintai/multikf/pkg/k8s"
+)
+
+// Compatibility is how well a kubeflow version works with a k8s version
+type Compatibility string
+
+func (c Compatibility) String() string {
+	return string(c)
+}
+
+const (
+	// CompatibilityTested is a k8s version the kubeflow release was tested against
+	CompatibilityTested Compatibility = "tested"
+	// CompatibilityUntested is a k8s version which serves all apis of the manifest but was not tested
+	CompatibilityUntested Compatibility = "untested"
+	// CompatibilityUnsupported is a k8s version which misses apis of the manifest, installation fails
+	CompatibilityUnsupported Compatibility = "unsupported"
+	// CompatibilityUnknown is returned for versions without metadata, e.g. manifests from a source
+	CompatibilityUnknown Compatibility = "unknown"
+)
+
+// K8sRange is an inclusive range of k8s minor versions, e.g. 1.25-1.26
+type K8sRange struct {
+	Min string
+	Max string
+}
+
+func (r K8sRange) String() string {
+	return r.Min + "-" + r.Max
+}
+
+// Contains returns true if v's minor version is within the range, patch versions are ignored
+func (r K8sRange) Contains(v *version.Version) bool {
+	minor := version.MajorMinor(v.Major(), v.Minor())
+	return !minor.LessThan(version.MustParseGeneric(r.Min)) && !version.MustParseGeneric(r.Max).LessThan(minor)
+}
+
+// VersionInfo is the compatibility metadata of an embedded kubeflow version
+type VersionInfo struct {
+	Version string
+	// TestedK8s is the range the kubeflow release was tested against
+	TestedK8s K8sRange
+	// SupportedK8s is the range serving all apis used by the manifest, e.g. v1.6 still uses batch/v1beta1 cronjobs removed in 1.25
+	SupportedK8s K8sRange
+	// MinResources is recommended for the full set of components
+	MinResources k8s.Resources
+}
+
+var versionInfos = map[string]VersionInfo{
+	"v1.9.0": {
+		Version:      "v1.9.0",
+		TestedK8s:    K8sRange{Min: "1.27", Max: "1.29"},
+		SupportedK8s: K8sRange{Min: "1.26", Max: "1.31"},
+		MinResources: k8s.Resources{Cpus: 4, MemoryInG: 12},
+	},
+	"v1.8.1": {
+		Version:      "v1.8.1",
+		TestedK8s:    K8sRange{Min: "1.25", Max: "1.26"},
+		SupportedK8s: K8sRange{Min: "1.25", Max: "1.28"},
+		MinResources: k8s.Resources{Cpus: 4, MemoryInG: 12},
+	},
+	"v1.7.0": {
+		Version:      "v1.7.0",
+		TestedK8s:    K8sRange{Min: "1.24", Max: "1.25"},
+		SupportedK8s: K8sRange{Min: "1.24", Max: "1.26"},
+		MinResources: k8s.Resources{Cpus: 4, MemoryInG: 12},
+	},
+	"v1.6.1": {
+		Version:      "v1.6.1",
+		TestedK8s:    K8sRange{Min: "1.22", Max: "1.24"},
+		SupportedK8s: K8sRange{Min: "1.21", Max: "1.24"},
+		MinResources: k8s.Resources{Cpus: 4, MemoryInG: 12},
+	},
+}
+
+// GetVersionInfo returns the compatibility metadata of the kubeflow version
+func GetVersionInfo(kfVersion string) (VersionInfo, bool) {
+	info, found := versionInfos[kfVersion]
+	return info, found
+}
+
+// CheckCompatibility returns the compatibility of the kubeflow version with the k8s version (e.g. v1.28.13)
+func CheckCompatibility(kfVersion string, k8sVersion string) (Compatibility, error) {
+	info, found := GetVersionInfo(kfVersion)
+	if !found {
+		return CompatibilityUnknown, nil
+	}
+	v, err := version.ParseGeneric(k8sVersion)
+	if err != nil {
+		return CompatibilityUnknown, fmt.Errorf("kfmanifests: invalid k8s version %q, err:%+v", k8sVersion, err)
+	}
+	switch {
+	case info.TestedK8s.Contains(v):
+		return CompatibilityTested, nil
+	case info.SupportedK8s.Contains(v):
+		return CompatibilityUntested, nil
+	}
+	return CompatibilityUnsupported, nil
+}
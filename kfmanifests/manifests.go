@@ -4,60 +4,75 @@ import (
 	"embed"
 	"errors"
 	"fmt"
-	"path/filepath"
+	"path"
 	"regexp"
 	"sort"
 
 	"io/fs"
+
+	"github.com/footprintai/multikf/pkg/kustomize"
 )
 
-//go:embed manifests/*
-var manifestFs embed.FS
+// bases are kustomize directories (base/kfNN) referring an upstream kubeflow manifest, they are built at install time
+//
+//go:embed base
+var baseFs embed.FS
+
+const baseDir = "base"
 
 var (
-	// looking for the following possible values: v1.4.1-lite, v1.4.1, v1.4
-	versionRegexp = regexp.MustCompile(`(v[0-9]\.[0-9](\.[0-9])?(\-lite)?)`)
+	// upstream manifest referred by a base, e.g. ./kubeflow-manifest-v1.9.0.yaml, possible versions: v1.4.1-lite, v1.4.1, v1.4
+	upstreamManifestRegexp = regexp.MustCompile(`kubeflow-manifest-(v[0-9]\.[0-9](\.[0-9])?(\-lite)?)\.yaml`)
 )
 
-func versionRelFileName(version string) string {
-	return fmt.Sprintf("manifests/kubeflow-manifest-%s-template.yaml", version)
+// VersionBaseFileName returns the file name of the manifest template built from the version's base
+func VersionBaseFileName(version string) string {
+	return fmt.Sprintf("kubeflow-manifest-%s-template.yaml", version)
 }
 
-func VersionBaseFileName(version string) string {
-	return filepath.Base(versionRelFileName(version))
+// listBases returns embedded base dirs by version, bases whose upstream manifest is not embedded are skipped
+func listBases() map[string]string {
+	bases := map[string]string{}
+	entries, err := fs.ReadDir(baseFs, baseDir)
+	if err != nil {
+		return bases
+	}
+	for _, entry := range entries {
+		dir := path.Join(baseDir, entry.Name())
+		kustomization, err := fs.ReadFile(baseFs, path.Join(dir, "kustomization.yaml"))
+		if err != nil {
+			continue
+		}
+		matched := upstreamManifestRegexp.FindSubmatch(kustomization)
+		if matched == nil {
+			continue
+		}
+		if _, err := fs.Stat(baseFs, path.Join(dir, string(matched[0]))); err != nil {
+			continue
+		}
+		bases[string(matched[1])] = dir
+	}
+	return bases
 }
 
 func ListVersions() []string {
 	var versions []string
-	fs.WalkDir(manifestFs, ".", func(path string, d fs.DirEntry, err error) error {
-		matched := versionRegexp.FindAllString(path, -1)
-		//fmt.Printf("path:%s, matched:%s\n", path, matched)
-		if len(matched) != 1 {
-			// ignore
-			return nil
-		}
-		versions = append(versions, matched[0])
-		return nil
-	})
+	for version := range listBases() {
+		versions = append(versions, version)
+	}
 	sort.Sort(sort.Reverse(sort.StringSlice(versions)))
 	return versions
 }
 
+// GetVersion builds the version's base with kustomize in-process, the result is a template with [[ ]] placeholders
 func GetVersion(version string) (string, error) {
-	// check the version is one of possible versions
-	possbileVersions := ListVersions()
-	versionExists := false
-	for _, possibleVersion := range possbileVersions {
-		if version == possibleVersion {
-			versionExists = true
-		}
-	}
-	if !versionExists {
+	dir, found := listBases()[version]
+	if !found {
 		return "", errors.New("version not found")
 	}
-	manifestBytes, err := manifestFs.ReadFile(versionRelFileName(version))
+	manifestBytes, err := kustomize.BuildFS(baseFs, dir)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("kfmanifests: build %s failed, err:%+v", dir, err)
 	}
 	return string(manifestBytes), nil
 }
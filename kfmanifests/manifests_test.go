@@ -16,3 +16,28 @@ func TestManifests(t *testing.T) {
 		assert.NotNil(t, foundVersionManifest)
 	}
 }
+
+func TestCompatibility(t *testing.T) {
+	for _, version := range ListVersions() {
+		_, found := GetVersionInfo(version)
+		assert.True(t, found, "every embedded version has compatibility metadata: %s", version)
+	}
+	for _, tc := range []struct {
+		kf, k8s  string
+		expected Compatibility
+	}{
+		{"v1.9.0", "v1.28.13", CompatibilityTested},
+		{"v1.9.0", "v1.29.8", CompatibilityTested},
+		{"v1.9.0", "v1.31.0", CompatibilityUntested},
+		{"v1.9.0", "v1.25.3", CompatibilityUnsupported},
+		{"v1.8.1", "v1.27.16", CompatibilityUntested},
+		{"v1.6.1", "v1.26.15", CompatibilityUnsupported},
+		{"v1.10.0", "v1.31.0", CompatibilityUnknown},
+	} {
+		c, err := CheckCompatibility(tc.kf, tc.k8s)
+		assert.NoError(t, err)
+		assert.EqualValues(t, tc.expected, c, "%s on %s", tc.kf, tc.k8s)
+	}
+	_, err := CheckCompatibility("v1.9.0", "latest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import "fmt"
+
+// CNI represents the container network interface installed into a kind cluster
+type CNI string
+
+func (c CNI) String() string {
+	return string(c)
+}
+
+// IsDefault returns true when kind's builtin cni (kindnetd) is used
+func (c CNI) IsDefault() bool {
+	return c == "" || c == CNIDefault
+}
+
+const (
+	CNIDefault CNI = "default"
+	CNICalico  CNI = "calico"
+	CNICilium  CNI = "cilium"
+)
+
+func ListCNI() []CNI {
+	return []CNI{
+		CNIDefault,
+		CNICalico,
+		CNICilium,
+	}
+}
+
+func ListCNIString() []string {
+	var cs []string
+	for _, c := range ListCNI() {
+		cs = append(cs, c.String())
+	}
+	return cs
+}
+
+func ParseCNI(s string) (CNI, error) {
+	if s == "" {
+		return CNIDefault, nil
+	}
+	for _, c := range ListCNI() {
+		if s == c.String() {
+			return c, nil
+		}
+	}
+	return CNIDefault, fmt.Errorf("unknown cni:%s", s)
+}
@@ -1,6 +1,8 @@
 package k8s
 
-import "fmt"
+import (
+	"fmt"
+)
 
 type KindK8sVersion struct {
 	version string
@@ -24,6 +26,15 @@ func (k KindK8sVersion) String() string {
 	return fmt.Sprintf("kindest/node:%s@sha256:%s", k.version, k.sha256)
 }
 
+// Resources are cpus and memory of a machine
+type Resources struct {
+	Cpus      int
+	MemoryInG int
+}
+
+// KubeadmMinResources are the minimum resources kubeadm requires for a control plane, which are the same for all listed versions
+var KubeadmMinResources = Resources{Cpus: 2, MemoryInG: 2}
+
 func DefaultVersion() KindK8sVersion {
 	return v12813
 }
@@ -0,0 +1,90 @@
+package kustomize
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const (
+	overlayBaseDir           = "/multikf/base"
+	overlayDir               = "/multikf/overlay"
+	overlayBaseFile          = "manifest.yaml"
+	overlayBaseKustomization = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ` + overlayBaseFile + "\n"
+)
+
+// Build runs `kustomize build` on dir of fSys in-process
+func Build(fSys filesys.FileSystem, dir string) ([]byte, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := k.Run(fSys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return resMap.AsYaml()
+}
+
+// BuildDir runs `kustomize build` on a directory on disk
+func BuildDir(dir string) ([]byte, error) {
+	return Build(filesys.MakeFsOnDisk(), dir)
+}
+
+// BuildFS runs `kustomize build` on dir of fsys, e.g. bases embedded in the binary
+func BuildFS(fsys fs.FS, dir string) ([]byte, error) {
+	fSys := filesys.MakeFsInMemory()
+	if err := copyDir(fSys, fsys, dir, "/"+dir); err != nil {
+		return nil, err
+	}
+	return Build(fSys, "/"+dir)
+}
+
+// BuildOverlay builds the overlay directory on disk against base manifests, the overlay refers the base as `../base`
+// in its kustomization resources, e.g.
+//
+//	resources:
+//	- ../base
+//	patches:
+//	- path: pvc-size.yaml
+func BuildOverlay(base []byte, overlayPath string) ([]byte, error) {
+	fSys := filesys.MakeFsInMemory()
+	if err := fSys.MkdirAll(overlayBaseDir); err != nil {
+		return nil, err
+	}
+	if err := fSys.WriteFile(filepath.Join(overlayBaseDir, "kustomization.yaml"), []byte(overlayBaseKustomization)); err != nil {
+		return nil, err
+	}
+	if err := fSys.WriteFile(filepath.Join(overlayBaseDir, overlayBaseFile), base); err != nil {
+		return nil, err
+	}
+	if err := copyDir(fSys, os.DirFS(overlayPath), ".", overlayDir); err != nil {
+		return nil, err
+	}
+	return Build(fSys, overlayDir)
+}
+
+// copyDir copies files under srcDir of srcFs into dstDir of fSys
+func copyDir(fSys filesys.FileSystem, srcFs fs.FS, srcDir string, dstDir string) error {
+	return fs.WalkDir(srcFs, srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, rel)
+		if d.IsDir() {
+			return fSys.MkdirAll(dst)
+		}
+		blob, err := fs.ReadFile(srcFs, path)
+		if err != nil {
+			return err
+		}
+		return fSys.WriteFile(dst, blob)
+	})
+}
@@ -0,0 +1,87 @@
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+var basePVC = `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: minio-pvc
+  namespace: kubeflow
+spec:
+  accessModes:
+  - ReadWriteOnce
+  resources:
+    requests:
+      storage: 20Gi
+`
+
+func TestBuild(t *testing.T) {
+	fSys := filesys.MakeFsInMemory()
+	assert.NoError(t, fSys.WriteFile("/app/pvc.yaml", []byte(basePVC)))
+	assert.NoError(t, fSys.WriteFile("/app/kustomization.yaml", []byte(`resources:
+- pvc.yaml
+commonLabels:
+  app: minio
+`)))
+	out, err := Build(fSys, "/app")
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "app: minio")
+}
+
+func TestBuildFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base/kf19/pvc.yaml": {Data: []byte(basePVC)},
+		"base/kf19/kustomization.yaml": {Data: []byte(`resources:
+- pvc.yaml
+patches:
+- patch: |-
+    - op: replace
+      path: /spec/resources/requests/storage
+      value: "[[.PipelineMinioPVCSizeInG]]Gi"
+  target:
+    kind: PersistentVolumeClaim
+    name: minio-pvc
+`)},
+	}
+	out, err := BuildFS(fsys, "base/kf19")
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "storage: '[[.PipelineMinioPVCSizeInG]]Gi'")
+
+	_, err = BuildFS(fsys, "base/kf18")
+	assert.Error(t, err)
+}
+
+func TestBuildOverlay(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "patches"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(`resources:
+- ../base
+patches:
+- path: patches/pvc.yaml
+`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "patches", "pvc.yaml"), []byte(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: minio-pvc
+  namespace: kubeflow
+spec:
+  resources:
+    requests:
+      storage: 50Gi
+`), 0644))
+	out, err := BuildOverlay([]byte(basePVC), dir)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "storage: 50Gi")
+	assert.NotContains(t, string(out), "storage: 20Gi")
+
+	_, err = BuildOverlay([]byte(basePVC), filepath.Join(dir, "not-exist"))
+	assert.Error(t, err)
+}
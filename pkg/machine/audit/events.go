@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+// LogDir is where kube-apiserver writes audit logs inside control-plane nodes, including rotated ones
+const LogDir = "/var/log/kubernetes"
+
+// ErrNotSupported is returned when the machine is not able to run commands inside its control-plane nodes
+var ErrNotSupported = errors.New("audit: machine type is not supported")
+
+// Filter selects audit events, empty fields match everything
+type Filter struct {
+	User      string // username, e.g. kubernetes-admin
+	Verb      string // e.g. create, delete
+	Resource  string // resource with an optional subresource, e.g. pods or pods/exec
+	Namespace string
+	Since     time.Time
+	Until     time.Time
+}
+
+// Match returns true if the event is selected by the filter
+func (f Filter) Match(e auditv1.Event) bool {
+	if f.User != "" && e.User.Username != f.User {
+		return false
+	}
+	if f.Verb != "" && !strings.EqualFold(e.Verb, f.Verb) {
+		return false
+	}
+	if f.Resource != "" && Resource(e) != f.Resource && (e.ObjectRef == nil || e.ObjectRef.Resource != f.Resource) {
+		return false
+	}
+	if f.Namespace != "" && (e.ObjectRef == nil || e.ObjectRef.Namespace != f.Namespace) {
+		return false
+	}
+	ts := Timestamp(e)
+	if !f.Since.IsZero() && ts.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && ts.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Resource returns resource/subresource of the event, or the request uri for non-resource requests
+func Resource(e auditv1.Event) string {
+	if e.ObjectRef == nil {
+		return e.RequestURI
+	}
+	if e.ObjectRef.Subresource != "" {
+		return e.ObjectRef.Resource + "/" + e.ObjectRef.Subresource
+	}
+	return e.ObjectRef.Resource
+}
+
+// Timestamp returns when the request is received
+func Timestamp(e auditv1.Event) time.Time {
+	if e.RequestReceivedTimestamp.IsZero() {
+		return e.StageTimestamp.Time
+	}
+	return e.RequestReceivedTimestamp.Time
+}
+
+// ParseEvents parses audit events in json lines, and returns those matched by the filter
+func ParseEvents(r io.Reader, f Filter) ([]auditv1.Event, error) {
+	var events []auditv1.Event
+	scanner := bufio.NewScanner(r)
+	// events with request and response bodies could be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		e := auditv1.Event{}
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("audit: invalid event at line %d, err:%+v", line, err)
+		}
+		if f.Match(e) {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// readLogsCmd prints current and rotated audit logs on a node. Files last written before f.Since only hold older
+// events, so they are pruned on the node instead of being transferred. A missing log dir is not an error as audit
+// could be disabled, while failures of reading existing logs are.
+func readLogsCmd(f Filter) string {
+	find := "find " + LogDir + " -maxdepth 1 -type f -name 'kube-apiserver-audit*.log'"
+	if !f.Since.IsZero() {
+		// -newermt is exclusive and in seconds, step back a second to keep files written right at since
+		find += fmt.Sprintf(" -newermt @%d", f.Since.Unix()-1)
+	}
+	return "[ -d " + LogDir + " ] || exit 0; " + find + " -exec cat {} +"
+}
+
+// Logs fetches audit events of every control-plane node of the machine, events are sorted by time
+func Logs(m machine.MachineCURD, f Filter) ([]auditv1.Event, error) {
+	exec, ok := m.(machine.ControlPlaneExecutor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotSupported, m.Type())
+	}
+	outs, err := exec.ExecControlPlanes(readLogsCmd(f))
+	if err != nil {
+		return nil, err
+	}
+	var events []auditv1.Event
+	for i := range outs {
+		parsed, err := ParseEvents(strings.NewReader(outs[i]), f)
+		if err != nil {
+			return nil, err
+		}
+		// drop raw logs once parsed, only matched events are kept
+		outs[i] = ""
+		events = append(events, parsed...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return Timestamp(events[i]).Before(Timestamp(events[j]))
+	})
+	return events, nil
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const auditLog = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"1","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/kubeflow/pods","verb":"list","user":{"username":"kubernetes-admin","groups":["system:masters"]},"objectRef":{"resource":"pods","namespace":"kubeflow","apiVersion":"v1"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T10:00:00.000000Z","stageTimestamp":"2024-05-01T10:00:00.010000Z"}
+
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"2","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/kubeflow/pods/ml-pipeline-0/exec","verb":"create","user":{"username":"alice@example.com"},"objectRef":{"resource":"pods","subresource":"exec","namespace":"kubeflow","name":"ml-pipeline-0","apiVersion":"v1"},"responseStatus":{"code":101},"requestReceivedTimestamp":"2024-05-01T11:00:00.000000Z","stageTimestamp":"2024-05-01T11:00:01.000000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"3","stage":"ResponseComplete","requestURI":"/apis/apps/v1/namespaces/default/deployments/web","verb":"delete","user":{"username":"alice@example.com"},"objectRef":{"resource":"deployments","namespace":"default","name":"web","apiGroup":"apps","apiVersion":"v1"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T12:00:00.000000Z","stageTimestamp":"2024-05-01T12:00:00.100000Z"}
+{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4","stage":"ResponseComplete","requestURI":"/healthz","verb":"get","user":{"username":"system:anonymous"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2024-05-01T13:00:00.000000Z","stageTimestamp":"2024-05-01T13:00:00.001000Z"}
+`
+
+func auditIDs(t *testing.T, f Filter) []string {
+	events, err := ParseEvents(strings.NewReader(auditLog), f)
+	assert.NoError(t, err)
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, string(e.AuditID))
+	}
+	return ids
+}
+
+func TestParseEvents(t *testing.T) {
+	assert.EqualValues(t, []string{"1", "2", "3", "4"}, auditIDs(t, Filter{}))
+	assert.EqualValues(t, []string{"2", "3"}, auditIDs(t, Filter{User: "alice@example.com"}))
+	assert.EqualValues(t, []string{"3"}, auditIDs(t, Filter{Verb: "DELETE"}))
+	assert.EqualValues(t, []string{"1", "2"}, auditIDs(t, Filter{Resource: "pods"}))
+	assert.EqualValues(t, []string{"2"}, auditIDs(t, Filter{Resource: "pods/exec"}))
+	assert.EqualValues(t, []string{"4"}, auditIDs(t, Filter{Resource: "/healthz"}))
+	assert.EqualValues(t, []string{"1", "2"}, auditIDs(t, Filter{Namespace: "kubeflow"}))
+	assert.EqualValues(t, []string{"2", "3"}, auditIDs(t, Filter{
+		Since: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		Until: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	}))
+	assert.Nil(t, auditIDs(t, Filter{User: "alice@example.com", Namespace: "kube-system"}))
+
+	_, err := ParseEvents(strings.NewReader("{\"kind\":\"Event\"}\nnot json\n"), Filter{})
+	assert.ErrorContains(t, err, "invalid event at line 2")
+}
+
+func TestReadLogsCmd(t *testing.T) {
+	assert.Equal(t,
+		"[ -d /var/log/kubernetes ] || exit 0; find /var/log/kubernetes -maxdepth 1 -type f -name 'kube-apiserver-audit*.log' -exec cat {} +",
+		readLogsCmd(Filter{}),
+	)
+	since := time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		"[ -d /var/log/kubernetes ] || exit 0; find /var/log/kubernetes -maxdepth 1 -type f -name 'kube-apiserver-audit*.log' -newermt @1714561199 -exec cat {} +",
+		readLogsCmd(Filter{Since: since, User: "alice@example.com"}),
+	)
+}
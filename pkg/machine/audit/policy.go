@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	PresetMetadata              = "metadata"
+	PresetWritesRequestResponse = "writes-request-response"
+	PresetExcludeSystem         = "exclude-system"
+	// DefaultPreset is used when no policy is specified, which is the policy multikf always used
+	DefaultPreset = PresetMetadata
+
+	policyAPIVersion = "audit.k8s.io/v1"
+	policyKind       = "Policy"
+)
+
+// presets are shipped audit policies, see ListPresets
+var presets = map[string]string{
+	// metadata of every request
+	PresetMetadata: `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+`,
+	// request and response bodies of writes and metadata of reads, bodies of secrets, configmaps and tokens are never logged
+	PresetWritesRequestResponse: `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+- RequestReceived
+rules:
+- level: Metadata
+  resources:
+  - group: ""
+    resources: ["secrets", "configmaps"]
+  - group: authentication.k8s.io
+    resources: ["tokenreviews"]
+- level: Metadata
+  verbs: ["get", "list", "watch"]
+- level: RequestResponse
+`,
+	// metadata of requests, except health checks, discovery, leader elections and requests of k8s components
+	PresetExcludeSystem: `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+- RequestReceived
+rules:
+- level: None
+  nonResourceURLs:
+  - /healthz*
+  - /livez*
+  - /readyz*
+  - /version
+  - /api
+  - /api/*
+  - /apis
+  - /apis/*
+  - /openapi/*
+- level: None
+  resources:
+  - group: coordination.k8s.io
+    resources: ["leases"]
+- level: None
+  users:
+  - system:kube-proxy
+  - system:kube-controller-manager
+  - system:kube-scheduler
+  - system:apiserver
+- level: None
+  userGroups:
+  - system:nodes
+  - system:serviceaccounts:kube-system
+- level: Metadata
+`,
+}
+
+// ListPresets returns names of shipped audit policies
+func ListPresets() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolvePolicy returns the audit policy of a preset name or a policy file, the policy is validated
+func ResolvePolicy(presetOrFile string) (string, error) {
+	if presetOrFile == "" {
+		presetOrFile = DefaultPreset
+	}
+	if policy, found := presets[presetOrFile]; found {
+		return policy, nil
+	}
+	content, err := os.ReadFile(presetOrFile)
+	if err != nil {
+		return "", fmt.Errorf("audit: %s is neither a preset (%s) nor a readable policy file, err:%+v", presetOrFile, strings.Join(ListPresets(), ", "), err)
+	}
+	if err := ValidatePolicy(content); err != nil {
+		return "", fmt.Errorf("audit: invalid policy file %s, %+v", presetOrFile, err)
+	}
+	return string(content), nil
+}
+
+// ValidatePolicy validates the audit policy against audit.k8s.io/v1, unknown fields are rejected
+func ValidatePolicy(content []byte) error {
+	policy := auditv1.Policy{}
+	if err := yaml.UnmarshalStrict(content, &policy); err != nil {
+		return fmt.Errorf("audit: not a valid %s policy, err:%+v", policyAPIVersion, err)
+	}
+	if policy.APIVersion != policyAPIVersion || policy.Kind != policyKind {
+		return fmt.Errorf("audit: expect kind %s of %s, got kind %q of %q", policyKind, policyAPIVersion, policy.Kind, policy.APIVersion)
+	}
+	if len(policy.Rules) == 0 {
+		return fmt.Errorf("audit: policy has no rules")
+	}
+	if err := validateStages("omitStages", policy.OmitStages); err != nil {
+		return err
+	}
+	for i, rule := range policy.Rules {
+		switch rule.Level {
+		case auditv1.LevelNone, auditv1.LevelMetadata, auditv1.LevelRequest, auditv1.LevelRequestResponse:
+		default:
+			return fmt.Errorf("audit: rules[%d] has an invalid level %q, expect %s, %s, %s or %s", i, rule.Level, auditv1.LevelNone, auditv1.LevelMetadata, auditv1.LevelRequest, auditv1.LevelRequestResponse)
+		}
+		if len(rule.NonResourceURLs) > 0 && (len(rule.Resources) > 0 || len(rule.Namespaces) > 0) {
+			return fmt.Errorf("audit: rules[%d] could not have both nonResourceURLs and resources or namespaces", i)
+		}
+		if err := validateStages(fmt.Sprintf("rules[%d].omitStages", i), rule.OmitStages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateStages(field string, stages []auditv1.Stage) error {
+	for _, stage := range stages {
+		switch stage {
+		case auditv1.StageRequestReceived, auditv1.StageResponseStarted, auditv1.StageResponseComplete, auditv1.StagePanic:
+		default:
+			return fmt.Errorf("audit: %s has an invalid stage %q", field, stage)
+		}
+	}
+	return nil
+}
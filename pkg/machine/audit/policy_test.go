@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresets(t *testing.T) {
+	assert.EqualValues(t, []string{PresetExcludeSystem, PresetMetadata, PresetWritesRequestResponse}, ListPresets())
+	for _, name := range ListPresets() {
+		policy, err := ResolvePolicy(name)
+		assert.NoError(t, err, name)
+		assert.NoError(t, ValidatePolicy([]byte(policy)), name)
+	}
+	policy, err := ResolvePolicy("")
+	assert.NoError(t, err)
+	assert.EqualValues(t, presets[DefaultPreset], policy)
+}
+
+func TestResolvePolicyFromFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "policy.yaml")
+	content := "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Request\n  verbs: [\"delete\"]\n- level: None\n"
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	policy, err := ResolvePolicy(file)
+	assert.NoError(t, err)
+	assert.EqualValues(t, content, policy)
+
+	_, err = ResolvePolicy(filepath.Join(dir, "notfound.yaml"))
+	assert.ErrorContains(t, err, "neither a preset")
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	assert.NoError(t, os.WriteFile(invalid, []byte("apiVersion: audit.k8s.io/v1\nkind: Policy\nrules: []\n"), 0644))
+	_, err = ResolvePolicy(invalid)
+	assert.ErrorContains(t, err, "has no rules")
+}
+
+func TestValidatePolicy(t *testing.T) {
+	for policy, expected := range map[string]string{
+		"apiVersion: audit.k8s.io/v1beta1\nkind: Policy\nrules:\n- level: Metadata\n":                                                        "expect kind Policy of audit.k8s.io/v1",
+		"apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Everything\n":                                                           `invalid level "Everything"`,
+		"apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n  verb: [\"get\"]\n":                                          `unknown field "verb"`,
+		"apiVersion: audit.k8s.io/v1\nkind: Policy\nomitStages: [\"Done\"]\nrules:\n- level: Metadata\n":                                     `invalid stage "Done"`,
+		"apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: None\n  nonResourceURLs: [\"/healthz\"]\n  namespaces: [\"default\"]\n": "both nonResourceURLs and resources",
+	} {
+		assert.ErrorContains(t, ValidatePolicy([]byte(policy)), expected, policy)
+	}
+}
@@ -0,0 +1,162 @@
+package certs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+const (
+	// WarnBefore is the residual time under which certificates are reported as expiring
+	WarnBefore = 30 * 24 * time.Hour
+
+	expiresLayout = "Jan 02, 2006 15:04 MST"
+
+	checkExpirationCmd = "kubeadm certs check-expiration"
+	// renewCmd renews all kubeadm managed certificates, then restarts control-plane static pods
+	// (kubelet recreates them) and kubelet itself so the renewed certificates are loaded.
+	renewCmd = "kubeadm certs renew all && " +
+		"for c in kube-apiserver kube-controller-manager kube-scheduler etcd; do crictl ps --name \"^${c}$\" -q | xargs -r crictl stop; done && " +
+		"systemctl restart kubelet"
+)
+
+var (
+	verifyPollInterval = 5 * time.Second
+	verifyPollTimeout  = 3 * time.Minute
+
+	columnSeparator = regexp.MustCompile(`\s{2,}`)
+)
+
+// ErrNotSupported is returned when the machine is not able to run commands inside its control-plane node
+var ErrNotSupported = errors.New("certs: machine type is not supported")
+
+// Certificate is a row of `kubeadm certs check-expiration`
+type Certificate struct {
+	Name        string
+	Expires     time.Time
+	IsAuthority bool
+	Node        int // index of the control-plane node, starting from 1
+}
+
+// Residual returns the remaining valid time of the certificate, it is negative when expired
+func (c Certificate) Residual(now time.Time) time.Duration {
+	return c.Expires.Sub(now)
+}
+
+// ParseCheckExpiration parses output of `kubeadm certs check-expiration`
+func ParseCheckExpiration(out string) ([]Certificate, error) {
+	var certs []Certificate
+	inTable, isAuthority := false, false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			inTable = false
+			continue
+		case strings.HasPrefix(line, "CERTIFICATE AUTHORITY"):
+			inTable, isAuthority = true, true
+			continue
+		case strings.HasPrefix(line, "CERTIFICATE"):
+			inTable, isAuthority = true, false
+			continue
+		case !inTable, strings.HasPrefix(line, "!MISSING!"):
+			continue
+		}
+		fields := columnSeparator.Split(line, -1)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("certs: unexpected line %q", line)
+		}
+		expires, err := time.Parse(expiresLayout, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("certs: unexpected expiry date in line %q, err:%+v", line, err)
+		}
+		certs = append(certs, Certificate{Name: fields[0], Expires: expires, IsAuthority: isAuthority})
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("certs: no certificates found in output:\n%s", out)
+	}
+	return certs, nil
+}
+
+// parseControlPlanes parses outputs of `kubeadm certs check-expiration` of control-plane nodes in order
+func parseControlPlanes(outs []string) ([]Certificate, error) {
+	var certs []Certificate
+	for i, out := range outs {
+		nodeCerts, err := ParseCheckExpiration(out)
+		if err != nil {
+			return nil, fmt.Errorf("certs: control-plane node %d, err:%+v", i+1, err)
+		}
+		for _, c := range nodeCerts {
+			c.Node = i + 1
+			certs = append(certs, c)
+		}
+	}
+	return certs, nil
+}
+
+// ExpiringWithin returns certificates whose residual time is less than d
+func ExpiringWithin(certs []Certificate, now time.Time, d time.Duration) []Certificate {
+	var expiring []Certificate
+	for _, c := range certs {
+		if c.Residual(now) < d {
+			expiring = append(expiring, c)
+		}
+	}
+	return expiring
+}
+
+func executor(m machine.MachineCURD) (machine.ControlPlaneExecutor, error) {
+	exec, ok := m.(machine.ControlPlaneExecutor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotSupported, m.Type())
+	}
+	return exec, nil
+}
+
+// Check reports expiry dates of certificates of every control-plane node, as they are issued per node
+func Check(m machine.MachineCURD) ([]Certificate, error) {
+	exec, err := executor(m)
+	if err != nil {
+		return nil, err
+	}
+	outs, err := exec.ExecControlPlanes(checkExpirationCmd)
+	if err != nil {
+		return nil, err
+	}
+	return parseControlPlanes(outs)
+}
+
+// Renew renews certificates of every control-plane node, re-exports the machine's kubeconfig and waits until kubeapi is accessible
+func Renew(logger log.Logger, m machine.MachineCURD) error {
+	exec, err := executor(m)
+	if err != nil {
+		return err
+	}
+	logger.V(0).Infof("certs: renew certificates of %s\n", m.Name())
+	if outs, err := exec.ExecControlPlanes(renewCmd); err != nil {
+		logger.Errorf("certs: renew failed, output:%s\n", strings.Join(outs, "\n"))
+		return err
+	}
+	// admin.conf is renewed as well, so the kubeconfig should be exported again
+	if err := m.ExportKubeConfig(m.GetKubeConfig(), true); err != nil {
+		return err
+	}
+	logger.V(0).Infof("certs: wait for kubeapi of %s\n", m.Name())
+	deadline := time.Now().Add(verifyPollTimeout)
+	for {
+		_, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "--raw", "/readyz")
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("certs: kubeapi is not accessible after renewal, err:%+v", err)
+		}
+		time.Sleep(verifyPollInterval)
+	}
+}
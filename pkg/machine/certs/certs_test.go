@@ -0,0 +1,53 @@
+package certs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var checkExpirationOutput = `[check-expiration] Reading configuration from the cluster...
+[check-expiration] FYI: You can look at this config file with 'kubectl -n kube-system get cm kubeadm-config -o yaml'
+
+CERTIFICATE                EXPIRES                  RESIDUAL TIME   CERTIFICATE AUTHORITY   EXTERNALLY MANAGED
+admin.conf                 Mar 01, 2025 02:27 UTC   364d            ca                      no
+apiserver                  Mar 01, 2025 02:27 UTC   364d            ca                      no
+!MISSING! front-proxy-client
+scheduler.conf             Nov 01, 2026 02:27 UTC   14d             ca                      no
+
+CERTIFICATE AUTHORITY   EXPIRES                  RESIDUAL TIME   EXTERNALLY MANAGED
+ca                      Feb 15, 2033 17:36 UTC   8y              no
+etcd-ca                 Feb 15, 2033 17:36 UTC   8y              no
+`
+
+func TestParseCheckExpiration(t *testing.T) {
+	certs, err := ParseCheckExpiration(checkExpirationOutput)
+	assert.NoError(t, err)
+	assert.Len(t, certs, 5)
+	assert.EqualValues(t, Certificate{Name: "admin.conf", Expires: time.Date(2025, 3, 1, 2, 27, 0, 0, time.UTC)}, certs[0])
+	assert.EqualValues(t, "scheduler.conf", certs[2].Name)
+	assert.False(t, certs[2].IsAuthority)
+	assert.EqualValues(t, "ca", certs[3].Name)
+	assert.True(t, certs[3].IsAuthority)
+
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	expiring := ExpiringWithin(certs, now, WarnBefore)
+	assert.Len(t, expiring, 3)
+	assert.True(t, expiring[0].Residual(now) < 0)
+	assert.EqualValues(t, "scheduler.conf", expiring[2].Name)
+
+	_, err = ParseCheckExpiration("error: unable to read config\n")
+	assert.Error(t, err)
+}
+
+func TestParseControlPlanes(t *testing.T) {
+	certs, err := parseControlPlanes([]string{checkExpirationOutput, checkExpirationOutput})
+	assert.NoError(t, err)
+	assert.Len(t, certs, 10)
+	assert.EqualValues(t, 1, certs[0].Node)
+	assert.EqualValues(t, Certificate{Name: "admin.conf", Expires: time.Date(2025, 3, 1, 2, 27, 0, 0, time.UTC), Node: 2}, certs[5])
+
+	_, err = parseControlPlanes([]string{checkExpirationOutput, "error: unable to read config\n"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package machine
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseAPIServerCertSANs parses comma delimited ip addresses/hostnames which would be added into
+// the API server certificate, advertiseHost (if any) is always included.
+func ParseAPIServerCertSANs(sans string, advertiseHost string) ([]string, error) {
+	var certSANs []string
+	seen := map[string]bool{}
+	candidates := strings.Split(sans, ",")
+	if advertiseHost != "" {
+		candidates = append(candidates, advertiseHost)
+	}
+	for _, candidate := range candidates {
+		san := strings.TrimSpace(candidate)
+		if san == "" || seen[san] {
+			continue
+		}
+		if net.ParseIP(san) == nil {
+			if errs := validation.IsDNS1123Subdomain(san); len(errs) > 0 {
+				return nil, fmt.Errorf("certsans: %s is neither an ip address nor a hostname, err:%s", san, strings.Join(errs, ","))
+			}
+		}
+		seen[san] = true
+		certSANs = append(certSANs, san)
+	}
+	return certSANs, nil
+}
@@ -0,0 +1,24 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAPIServerCertSANs(t *testing.T) {
+	sans, err := ParseAPIServerCertSANs("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, sans)
+
+	sans, err = ParseAPIServerCertSANs("10.1.2.3, kf.example.com,10.1.2.3", "kf.example.com")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"10.1.2.3", "kf.example.com"}, sans)
+
+	sans, err = ParseAPIServerCertSANs("", "fd00::1")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"fd00::1"}, sans)
+
+	_, err = ParseAPIServerCertSANs("https://kf.example.com", "")
+	assert.Error(t, err)
+}
@@ -22,6 +22,8 @@ func (c *Cmd) Run(cmdAndArgs ...string) (*ioutil.CmdOutputStream, <-chan cmd.Sta
 	cmdOptions := cmd.Options{
 		Buffered:  false,
 		Streaming: true,
+		// go-cmd fails lines longer than its default 16KiB buffer
+		LineBufferSize: ioutil.MaxLineSize,
 	}
 	runcmd := cmd.NewCmdOptions(cmdOptions, cmdAndArgs[0], cmdAndArgs[1:]...)
 	statusChan1 := make(chan cmd.Status, 1)
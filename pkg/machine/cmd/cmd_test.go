@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kindcmd "sigs.k8s.io/kind/pkg/cmd"
+
+	"github.com/footprintai/multikf/pkg/machine/ioutil"
+)
+
+func TestRunLongLine(t *testing.T) {
+	// the line is written in chunks, go-cmd buffers the unterminated part of a line
+	sr, status, err := NewCmd(kindcmd.NewLogger()).Run("sh", "-c", "for i in 1 2 3 4 5; do head -c 5000 /dev/zero | tr '\\0' a; done; echo; echo b")
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(sr)
+	assert.NoError(t, err)
+	assert.EqualValues(t, strings.Repeat("a", 25000)+"\nb\n", string(out), "lines longer than go-cmd's default 16KiB buffer are kept")
+	assert.EqualValues(t, 0, (<-status).Exit)
+}
@@ -113,7 +113,7 @@ func (cli *CLI) InstallKubeflow(kubeConfigFile string, kfmanifestFile string) er
 		}
 		time.Sleep(20 * time.Second)
 	}
-	return nil
+	return fmt.Errorf("kubectl: apply %s failed after retries", kfmanifestFile)
 }
 
 func (cli *CLI) RemoveKubeflow(kubeConfigFile string, kfmanifestFile string) error {
@@ -130,6 +130,100 @@ func (cli *CLI) RemoveKubeflow(kubeConfigFile string, kfmanifestFile string) err
 	return err
 }
 
+func (cli *CLI) Apply(kubeConfigFile string, manifestFile string) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"apply",
+		"-f",
+		manifestFile,
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// Delete deletes resources in the manifest file, missing resources are ignored
+func (cli *CLI) Delete(kubeConfigFile string, manifestFile string) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"delete",
+		"-f",
+		manifestFile,
+		"--ignore-not-found",
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// RolloutStatus waits until the resource (e.g. daemonset/calico-node) is fully rolled out
+func (cli *CLI) RolloutStatus(kubeConfigFile string, namespace string, resource string, timeout time.Duration) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"rollout",
+		"status",
+		resource,
+		"-n",
+		namespace,
+		fmt.Sprintf("--timeout=%s", timeout),
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// RolloutRestart restarts pods of the resource (e.g. deployment/dex)
+func (cli *CLI) RolloutRestart(kubeConfigFile string, namespace string, resource string) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"rollout",
+		"restart",
+		resource,
+		"-n",
+		namespace,
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// WaitNodesReady waits until all nodes report Ready condition
+func (cli *CLI) WaitNodesReady(kubeConfigFile string, timeout time.Duration) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"wait",
+		"--for=condition=Ready",
+		"nodes",
+		"--all",
+		fmt.Sprintf("--timeout=%s", timeout),
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// WaitNamespacesDeleted waits until the namespaces are gone, terminating namespaces are kept until their finalizers complete
+func (cli *CLI) WaitNamespacesDeleted(kubeConfigFile string, namespaces []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, namespace := range namespaces {
+		for {
+			out, err := cli.Output(kubeConfigFile, "get", "namespace", namespace, "--ignore-not-found", "-o", "name")
+			if err != nil {
+				return err
+			}
+			if len(out) == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("kubectl: namespace %s is still terminating after %s", namespace, timeout)
+			}
+			cli.logger.V(1).Infof("kubectl: wait for namespace %s to be deleted\n", namespace)
+			time.Sleep(2 * time.Second)
+		}
+	}
+	return nil
+}
+
 func (cli *CLI) Portforward(kubeConfigFile, svc, namespace string, address string, fromPort, toPort int) error {
 	// TODO: auto reconnect
 	cmdAndArgs := []string{
@@ -175,6 +269,80 @@ func (cli *CLI) GetPods(kindConfigfile string, namespace string) error {
 	return ioutil.StderrOnError(sr)
 }
 
+// Output runs kubectl with args and returns its stdout, e.g. Output(kubeConfigFile, "get", "sa", "-o", "json")
+func (cli *CLI) Output(kubeConfigFile string, args ...string) ([]byte, error) {
+	cmdAndArgs := append([]string{cli.localKubectlBinaryPath}, args...)
+	cmdAndArgs = append(cmdAndArgs, "--kubeconfig", kubeConfigFile)
+	return cli.runCmdAndRead(cmdAndArgs)
+}
+
+// Exec runs the command inside the target (e.g. deploy/mysql) of the namespace and returns its stdout
+func (cli *CLI) Exec(kubeConfigFile string, namespace string, target string, command ...string) ([]byte, error) {
+	return cli.runCmdAndRead(cli.execArgs(kubeConfigFile, namespace, target, command))
+}
+
+// execArgs places kubectl's flags before "--", everything after it is passed to the command as is
+func (cli *CLI) execArgs(kubeConfigFile string, namespace string, target string, command []string) []string {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"exec",
+		"-n",
+		namespace,
+		target,
+		"--kubeconfig",
+		kubeConfigFile,
+		"--",
+	}
+	return append(cmdAndArgs, command...)
+}
+
+// DeleteBySelector deletes resources (e.g. sa,secret) matching the label selector in all namespaces
+func (cli *CLI) DeleteBySelector(kubeConfigFile string, resources string, selector string) error {
+	cmdAndArgs := []string{
+		cli.localKubectlBinaryPath,
+		"delete",
+		resources,
+		"--all-namespaces",
+		"-l",
+		selector,
+		"--ignore-not-found",
+		"--kubeconfig",
+		kubeConfigFile,
+	}
+	return cli.runCmdAndWait(cmdAndArgs)
+}
+
+// runCmdAndRead runs the command until it exits and returns its stdout, non-zero exit code would be returned as an error
+func (cli *CLI) runCmdAndRead(cmdAndArgs []string) ([]byte, error) {
+	sr, status, err := cli.runCmd(cmdAndArgs)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ioutil.ReadAll(sr)
+	if err != nil {
+		return nil, err
+	}
+	ps := <-status
+	if ps.Exit != 0 {
+		return nil, fmt.Errorf("kubectl: %s exited with code %d", cmdAndArgs[1], ps.Exit)
+	}
+	return out, nil
+}
+
+// runCmdAndWait runs the command until it exits, non-zero exit code would be returned as an error
+func (cli *CLI) runCmdAndWait(cmdAndArgs []string) error {
+	sr, status, err := cli.runCmd(cmdAndArgs)
+	if err != nil {
+		return err
+	}
+	ioutil.StderrOnError(sr)
+	ps := <-status
+	if ps.Exit != 0 {
+		return fmt.Errorf("kubectl: %s exited with code %d", cmdAndArgs[1], ps.Exit)
+	}
+	return nil
+}
+
 func (cli *CLI) runCmd(cmdAndArgs []string) (*ioutil.CmdOutputStream, <-chan gocmd.Status, error) {
 	return cmd.NewCmd(cli.logger).Run(cmdAndArgs...)
 }
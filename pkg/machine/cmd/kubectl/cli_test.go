@@ -0,0 +1,15 @@
+package kubectl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecArgs(t *testing.T) {
+	cli := &CLI{localKubectlBinaryPath: "/bin/kubectl"}
+	assert.EqualValues(t, []string{
+		"/bin/kubectl", "exec", "-n", "kubeflow", "deploy/mysql", "--kubeconfig", "/tmp/kubeconfig",
+		"--", "sh", "-c", "mysqldump --all-databases",
+	}, cli.execArgs("/tmp/kubeconfig", "kubeflow", "deploy/mysql", []string{"sh", "-c", "mysqldump --all-databases"}))
+}
@@ -0,0 +1,118 @@
+package cni
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/footprintai/multikf/pkg/k8s"
+	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+var (
+	cniRolloutTimeout = 5 * time.Minute
+)
+
+// rolloutTarget is the workload which should be rolled out before the cni is considered ready
+type rolloutTarget struct {
+	namespace string
+	resource  string
+}
+
+type cniManifest struct {
+	relFileName string
+	targets     []rolloutTarget
+}
+
+var cniManifests = map[k8s.CNI]cniManifest{
+	k8s.CNICalico: {
+		relFileName: "manifests/calico-v3.31.4.yaml",
+		targets: []rolloutTarget{
+			{namespace: "kube-system", resource: "daemonset/calico-node"},
+			{namespace: "kube-system", resource: "deployment/calico-kube-controllers"},
+		},
+	},
+	k8s.CNICilium: {
+		relFileName: "manifests/cilium-v1.16.5.yaml",
+		targets: []rolloutTarget{
+			{namespace: "kube-system", resource: "daemonset/cilium"},
+			{namespace: "kube-system", resource: "deployment/cilium-operator"},
+		},
+	},
+}
+
+func NewCNITemplateExecutor(c k8s.CNI) (pkgtemplate.TemplateExecutor, error) {
+	manifest, found := cniManifests[c]
+	if !found {
+		return nil, fmt.Errorf("cni: no manifest for %s", c)
+	}
+	manifestBytes, err := manifestFs.ReadFile(manifest.relFileName)
+	if err != nil {
+		return nil, err
+	}
+	return &CNIFileTemplate{
+		filename: fmt.Sprintf("cni-%s.yaml", c),
+		manifest: manifestBytes,
+	}, nil
+}
+
+// CNIFileTemplate dumps the embedded cni manifest as-is
+type CNIFileTemplate struct {
+	filename string
+	manifest []byte
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &CNIFileTemplate{}
+)
+
+func (c *CNIFileTemplate) Filename() string {
+	return c.filename
+}
+
+func (c *CNIFileTemplate) Execute(w io.Writer) error {
+	_, err := w.Write(c.manifest)
+	return err
+}
+
+func (c *CNIFileTemplate) Populate(v interface{}) error {
+	return nil
+}
+
+// Install applies cni manifests into the cluster and waits until the cni and all nodes are ready.
+// Nothing is installed when kind's default cni is used.
+func Install(logger log.Logger, kubecli *machinekubectlcmd.CLI, kubeConfigFile string, hostDir string, c k8s.CNI) error {
+	if c.IsDefault() {
+		return nil
+	}
+	tmpl, err := NewCNITemplateExecutor(c)
+	if err != nil {
+		return err
+	}
+	memFs := templatefs.NewMemoryFilesFs()
+	if err := memFs.Generate(nil, tmpl); err != nil {
+		return err
+	}
+	if err := templatefs.NewFolder(hostDir).DumpFiles(true, memFs.FS()); err != nil {
+		return err
+	}
+	logger.V(0).Infof("cni: install %s\n", c)
+	if err := kubecli.Apply(kubeConfigFile, filepath.Join(hostDir, tmpl.Filename())); err != nil {
+		return err
+	}
+	for _, target := range cniManifests[c].targets {
+		logger.V(1).Infof("cni: wait for %s/%s\n", target.namespace, target.resource)
+		if err := kubecli.RolloutStatus(kubeConfigFile, target.namespace, target.resource, cniRolloutTimeout); err != nil {
+			return err
+		}
+	}
+	return kubecli.WaitNodesReady(kubeConfigFile, cniRolloutTimeout)
+}
@@ -2,6 +2,9 @@ package docker
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
 
 	machinecmd "github.com/footprintai/multikf/pkg/machine/cmd"
 	"github.com/footprintai/multikf/pkg/machine/ioutil"
@@ -42,6 +45,59 @@ func (cli *DockerCli) GetClusterStatus(containername ContainerName) (string, err
 	return d.Status, nil
 }
 
+type dockerIPAMConfig struct {
+	Subnet  string `json:"Subnet"`
+	Gateway string `json:"Gateway"`
+}
+
+// GetNetworkIPv4Subnet returns the ipv4 subnet (in cidr format) of a docker network, e.g. kind
+func (cli *DockerCli) GetNetworkIPv4Subnet(network string) (string, error) {
+	cmdAndArgs := []string{
+		"docker",
+		"network",
+		"inspect",
+		network,
+		"--format={{json .IPAM.Config}}",
+	}
+	sr, _, err := cli.runCmd(cmdAndArgs)
+	if err != nil {
+		return "", err
+	}
+	blob, _ := ioutil.ReadAll(sr)
+	var configs []dockerIPAMConfig
+	if err := json.Unmarshal(blob, &configs); err != nil {
+		return "", err
+	}
+	for _, config := range configs {
+		ip, _, err := net.ParseCIDR(config.Subnet)
+		if err == nil && ip.To4() != nil {
+			return config.Subnet, nil
+		}
+	}
+	return "", fmt.Errorf("docker: no ipv4 subnet found in network %s", network)
+}
+
+// ListControlPlanes returns control-plane containers of the kind cluster, which are labeled by kind with the cluster and role
+func (cli *DockerCli) ListControlPlanes(clustername string) ([]ContainerName, error) {
+	cmdAndArgs := []string{
+		"docker",
+		"ps",
+		"-a",
+		"--filter", "label=io.x-k8s.kind.cluster=" + clustername,
+		"--filter", "label=io.x-k8s.kind.role=control-plane",
+		"--format={{.Names}}",
+	}
+	sr, _, err := cli.runCmd(cmdAndArgs)
+	if err != nil {
+		return nil, err
+	}
+	blob, _ := ioutil.ReadAll(sr)
+	return parseControlPlaneContainerNames(clustername, strings.Split(string(blob), "\n"))
+}
+
+// KindNetworkName is the docker network shared by all kind clusters
+const KindNetworkName = "kind"
+
 func (cli *DockerCli) RemoteExec(containername ContainerName, cmd string) (resp string, err error) {
 	cmdAndArgs := []string{
 		"docker",
@@ -59,6 +115,28 @@ func (cli *DockerCli) RemoteExec(containername ContainerName, cmd string) (resp
 	return string(all), nil
 }
 
+// RemoteExecAndWait is similar to RemoteExec, but non-zero exit code would be returned as an error
+func (cli *DockerCli) RemoteExecAndWait(containername ContainerName, cmd string) (resp string, err error) {
+	cmdAndArgs := []string{
+		"docker",
+		"exec",
+		containername.Name(),
+		"sh",
+		"-c",
+		cmd,
+	}
+	sr, status, err := cli.runCmd(cmdAndArgs)
+	if err != nil {
+		return "", err
+	}
+	all, _ := ioutil.ReadAll(sr)
+	ps := <-status
+	if ps.Exit != 0 {
+		return string(all), fmt.Errorf("docker: exec %q in %s exited with code %d", cmd, containername.Name(), ps.Exit)
+	}
+	return string(all), nil
+}
+
 func (cli *DockerCli) runCmd(cmdAndArgs []string) (ioutil.StreamReader, <-chan cmd.Status, error) {
 	return machinecmd.NewCmd(cli.logger).Run(cmdAndArgs...)
 }
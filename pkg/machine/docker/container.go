@@ -1,17 +1,111 @@
 package docker
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 func NewContainerName(clustername string) ContainerName {
-	return ContainerName{clustername: clustername}
+	return NewControlPlaneContainerName(clustername, 1)
+}
+
+// NewControlPlaneContainerName returns the container of the index-th control-plane node, index starts from 1
+func NewControlPlaneContainerName(clustername string, index int) ContainerName {
+	return ContainerName{clustername: clustername, index: index}
 }
 
 // ContainerName is a place holder for clustername and its underlying container name created by kind
 type ContainerName struct {
 	clustername string
+	index       int
 }
 
+// Name returns the container name, kind names control-plane nodes as <name>-control-plane, <name>-control-plane2, ...
 func (c ContainerName) Name() string {
+	if c.index > 1 {
+		return fmt.Sprintf("/%s-control-plane%d", c.clustername, c.index)
+	}
 	return fmt.Sprintf("/%s-control-plane", c.clustername)
 
 }
+
+// parseControlPlaneContainerNames parses control-plane container names of the cluster in the order of their indices
+func parseControlPlaneContainerNames(clustername string, names []string) ([]ContainerName, error) {
+	prefix := clustername + "-control-plane"
+	var containernames []ContainerName
+	for _, name := range names {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+		if name == "" {
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) {
+			return nil, fmt.Errorf("docker: container %s is not a control-plane node of %s", name, clustername)
+		}
+		index := 1
+		if suffix := strings.TrimPrefix(name, prefix); suffix != "" {
+			n, err := strconv.Atoi(suffix)
+			if err != nil || n < 2 {
+				return nil, fmt.Errorf("docker: container %s is not a control-plane node of %s", name, clustername)
+			}
+			index = n
+		}
+		containernames = append(containernames, NewControlPlaneContainerName(clustername, index))
+	}
+	sort.Slice(containernames, func(i, j int) bool {
+		return containernames[i].index < containernames[j].index
+	})
+	return containernames, nil
+}
+
+const (
+	statusRunning = "running"
+	// statusUnknown is reported for nodes whose status could not be inspected
+	statusUnknown = "unknown"
+)
+
+// firstRunning returns the first control-plane node which is running
+func firstRunning(containernames []ContainerName, statuses []string) (ContainerName, bool) {
+	for i, status := range statuses {
+		if status == statusRunning {
+			return containernames[i], true
+		}
+	}
+	return ContainerName{}, false
+}
+
+// execEach runs exec on every node even if some of them fail, outputs are returned per node
+// and errors are joined with the node's name.
+func execEach(containernames []ContainerName, exec func(ContainerName) (string, error)) ([]string, error) {
+	outs := make([]string, len(containernames))
+	var errs []error
+	for i, containername := range containernames {
+		out, err := exec(containername)
+		outs[i] = out
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", strings.TrimPrefix(containername.Name(), "/"), err))
+		}
+	}
+	return outs, errors.Join(errs...)
+}
+
+// aggregateStatus returns the status shared by all control-plane nodes,
+// otherwise status of each node, e.g. control-plane:running,control-plane2:exited
+func aggregateStatus(containernames []ContainerName, statuses []string) string {
+	same := true
+	for _, status := range statuses {
+		if status != statuses[0] {
+			same = false
+		}
+	}
+	if same && len(statuses) > 0 {
+		return statuses[0]
+	}
+	var tokens []string
+	for i, status := range statuses {
+		tokens = append(tokens, fmt.Sprintf("%s:%s", strings.TrimPrefix(containernames[i].Name(), "/"+containernames[i].clustername+"-"), status))
+	}
+	return strings.Join(tokens, ",")
+}
@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerName(t *testing.T) {
+	assert.EqualValues(t, "/test-control-plane", NewContainerName("test").Name())
+	assert.EqualValues(t, "/test-control-plane", NewControlPlaneContainerName("test", 1).Name())
+	assert.EqualValues(t, "/test-control-plane3", NewControlPlaneContainerName("test", 3).Name())
+}
+
+func TestParseControlPlaneContainerNames(t *testing.T) {
+	containernames, err := parseControlPlaneContainerNames("test", []string{"test-control-plane3", "test-control-plane", "test-control-plane2", ""})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []ContainerName{
+		NewControlPlaneContainerName("test", 1),
+		NewControlPlaneContainerName("test", 2),
+		NewControlPlaneContainerName("test", 3),
+	}, containernames)
+
+	for _, invalid := range []string{"test-worker", "test-control-planex", "test-control-plane1", "other-control-plane"} {
+		_, err := parseControlPlaneContainerNames("test", []string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	containernames := []ContainerName{
+		NewControlPlaneContainerName("test", 1),
+		NewControlPlaneContainerName("test", 2),
+	}
+	assert.EqualValues(t, "running", aggregateStatus(containernames, []string{"running", "running"}))
+	assert.EqualValues(t, "control-plane:running,control-plane2:exited", aggregateStatus(containernames, []string{"running", "exited"}))
+}
+
+func TestFirstRunning(t *testing.T) {
+	containernames := []ContainerName{
+		NewControlPlaneContainerName("test", 1),
+		NewControlPlaneContainerName("test", 2),
+	}
+	containername, found := firstRunning(containernames, []string{"exited", "running"})
+	assert.True(t, found)
+	assert.EqualValues(t, "/test-control-plane2", containername.Name())
+	_, found = firstRunning(containernames, []string{"exited", statusUnknown})
+	assert.False(t, found)
+}
+
+func TestExecEach(t *testing.T) {
+	containernames := []ContainerName{
+		NewControlPlaneContainerName("test", 1),
+		NewControlPlaneContainerName("test", 2),
+		NewControlPlaneContainerName("test", 3),
+	}
+	var executed []string
+	outs, err := execEach(containernames, func(containername ContainerName) (string, error) {
+		executed = append(executed, containername.Name())
+		if containername.Name() != "/test-control-plane2" {
+			return "failed " + containername.Name(), errors.New("boom")
+		}
+		return "ok", nil
+	})
+	assert.EqualValues(t, []string{"/test-control-plane", "/test-control-plane2", "/test-control-plane3"}, executed, "nodes after a failure are executed as well")
+	assert.EqualValues(t, []string{"failed /test-control-plane", "ok", "failed /test-control-plane3"}, outs)
+	assert.EqualError(t, err, "test-control-plane: boom\ntest-control-plane3: boom")
+
+	outs, err = execEach(containernames[1:2], func(ContainerName) (string, error) { return "ok", nil })
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"ok"}, outs)
+}
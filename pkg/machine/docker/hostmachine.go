@@ -9,6 +9,7 @@ import (
 	machinecmd "github.com/footprintai/multikf/pkg/machine/cmd"
 	machinekindcmd "github.com/footprintai/multikf/pkg/machine/cmd/kind"
 	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	"github.com/footprintai/multikf/pkg/machine/cni"
 	"github.com/footprintai/multikf/pkg/machine/docker/template"
 	"github.com/footprintai/multikf/pkg/machine/fsutil"
 	"sigs.k8s.io/kind/pkg/log"
@@ -99,7 +100,8 @@ type HostMachine struct {
 }
 
 var (
-	_ machine.MachineCURD = &HostMachine{}
+	_ machine.MachineCURD          = &HostMachine{}
+	_ machine.ControlPlaneExecutor = &HostMachine{}
 )
 
 func (h *HostMachine) ensureFiles() error {
@@ -121,22 +123,7 @@ func (h *HostMachine) prepareFiles() error {
 		return err
 	}
 	h.logger.V(1).Infof("hostmachine(%s): get port (%d) for kubeapi\n", h.name, kubeport)
-	tmplConfig := template.NewDockerHostmachineTemplateConfig(
-		h.name,
-		h.options.GetCPUs(),
-		h.options.GetMemory(),
-		-1,
-		kubeport,
-		h.options.GetKubeAPIIP(),
-		h.options.GetGPUs(),
-		h.options.GetExportPorts(),
-		h.options.AuditEnabled(),
-		filepath.Join(h.hostMachineDir, "audit-policy.yaml"),
-		h.options.GetWorkers(),
-		h.options.GetNodeLabels(),
-		h.options.GetLocalPath(),
-		h.options.GetNodeVersion(),
-	)
+	tmplConfig := template.NewDockerHostmachineTemplateConfig(h.name, h.options, -1, kubeport, filepath.Join(h.hostMachineDir, "audit-policy.yaml"))
 
 	vfolder := NewHostFolder(h.hostMachineDir)
 	if err := vfolder.GenerateFiles(tmplConfig); err != nil {
@@ -183,7 +170,7 @@ func (h *HostMachine) Up() error {
 	if err := h.kindcli.GetKubeConfig(h.name, kubeConfigPath); err != nil {
 		return err
 	}
-	return nil
+	return cni.Install(h.logger, h.kubectlcli, kubeConfigPath, h.hostMachineDir, h.options.GetCNI())
 }
 
 func (h *HostMachine) ensureKubeconfig() error {
@@ -201,32 +188,84 @@ func (h *HostMachine) ExportKubeConfig(path string, force bool) error {
 	return h.kindcli.GetKubeConfig(h.name, path)
 }
 
-func (h *HostMachine) Destroy() error {
-	return h.kindcli.RemoveCluster(h.name)
+func (h *HostMachine) ExecControlPlane(cmd string) (string, error) {
+	return h.dockercli.RemoteExecAndWait(h.containername, cmd)
 }
 
-func (h *HostMachine) Info() (*machine.MachineInfo, error) {
-	meminfo, err := machine.NewMemInfoParserHelper(h.dockercli.RemoteExec(h.containername, "cat /proc/meminfo"))
+func (h *HostMachine) ExecControlPlanes(cmd string) ([]string, error) {
+	return execEach(h.controlPlanes(), func(containername ContainerName) (string, error) {
+		return h.dockercli.RemoteExecAndWait(containername, cmd)
+	})
+}
+
+// listControlPlanes returns containers of all control-plane nodes, it fails if there is none
+func (h *HostMachine) listControlPlanes() ([]ContainerName, error) {
+	containernames, err := h.dockercli.ListControlPlanes(h.name)
 	if err != nil {
 		return nil, err
 	}
-	cpuinfo, err := machine.NewCpuInfoParserHelper(h.dockercli.RemoteExec(h.containername, "cat /proc/cpuinfo"))
-	if err != nil {
-		return nil, err
+	if len(containernames) == 0 {
+		return nil, fmt.Errorf("hostmachine(%s): no control-plane container found", h.name)
 	}
-	gpuinfo, err := machine.NewGpuInfoParserHelper(h.dockercli.RemoteExec(h.containername, "/usr/bin/nvidia-smi -x -q -a"))
+	return containernames, nil
+}
+
+// controlPlanes returns containers of all control-plane nodes, the first one is returned if they are not listable
+func (h *HostMachine) controlPlanes() []ContainerName {
+	containernames, err := h.listControlPlanes()
 	if err != nil {
-		h.logger.V(2).Infof("host: get cpu info failed, err:%s\n", err)
-		gpuinfo = &machine.GpuInfo{}
+		h.logger.V(1).Infof("hostmachine(%s): list control-plane nodes failed, use %s, err:%+v\n", h.name, h.containername.Name(), err)
+		return []ContainerName{h.containername}
 	}
-	status, err := h.dockercli.GetClusterStatus(h.containername)
+	return containernames
+}
+
+func (h *HostMachine) Destroy() error {
+	return h.kindcli.RemoveCluster(h.name)
+}
+
+// Info reports the status of control-plane nodes, cpus, memory and gpus are read from any running one since nodes share
+// the host's resources. Resources are left empty if they are not available, e.g. the machine is stopped, while it fails
+// if no control-plane container exists.
+func (h *HostMachine) Info() (*machine.MachineInfo, error) {
+	controlPlanes, err := h.listControlPlanes()
 	if err != nil {
 		return nil, err
 	}
-	return &machine.MachineInfo{
-		CpuInfo: cpuinfo,
-		MemInfo: meminfo,
-		GpuInfo: gpuinfo,
-		Status:  status,
-	}, nil
+	statuses := make([]string, len(controlPlanes))
+	for i, containername := range controlPlanes {
+		status, err := h.dockercli.GetClusterStatus(containername)
+		if err != nil {
+			h.logger.Warnf("hostmachine(%s): get status of %s failed, err:%+v\n", h.name, containername.Name(), err)
+			status = statusUnknown
+		}
+		statuses[i] = status
+	}
+	info := &machine.MachineInfo{
+		CpuInfo: &machine.CpuInfo{},
+		MemInfo: &machine.MemInfo{},
+		GpuInfo: &machine.GpuInfo{},
+		Status:  aggregateStatus(controlPlanes, statuses),
+	}
+	containername, found := firstRunning(controlPlanes, statuses)
+	if !found {
+		h.logger.V(1).Infof("hostmachine(%s): no running control-plane node, skip reading its resources\n", h.name)
+		return info, nil
+	}
+	if meminfo, err := machine.NewMemInfoParserHelper(h.dockercli.RemoteExec(containername, "cat /proc/meminfo")); err != nil {
+		h.logger.Warnf("hostmachine(%s): get mem info failed, err:%+v\n", h.name, err)
+	} else {
+		info.MemInfo = meminfo
+	}
+	if cpuinfo, err := machine.NewCpuInfoParserHelper(h.dockercli.RemoteExec(containername, "cat /proc/cpuinfo")); err != nil {
+		h.logger.Warnf("hostmachine(%s): get cpu info failed, err:%+v\n", h.name, err)
+	} else {
+		info.CpuInfo = cpuinfo
+	}
+	if gpuinfo, err := machine.NewGpuInfoParserHelper(h.dockercli.RemoteExec(containername, "/usr/bin/nvidia-smi -x -q -a")); err != nil {
+		h.logger.V(2).Infof("hostmachine(%s): get gpu info failed, err:%s\n", h.name, err)
+	} else {
+		info.GpuInfo = gpuinfo
+	}
+	return info, nil
 }
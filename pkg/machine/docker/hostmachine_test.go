@@ -73,10 +73,22 @@ func (n noConfigurer) AuditEnabled() bool {
 	return false
 }
 
+func (n noConfigurer) GetAuditPolicy() string {
+	return ""
+}
+
+func (n noConfigurer) GetControlPlanes() int {
+	return 1
+}
+
 func (n noConfigurer) GetWorkers() int {
 	return 0
 }
 
+func (n noConfigurer) GetWorkerGroups() []machine.WorkerGroup {
+	return nil
+}
+
 func (n noConfigurer) GetNodeVersion() k8s.KindK8sVersion {
 	return k8s.DefaultVersion()
 
@@ -89,3 +101,15 @@ func (n noConfigurer) GetNodeLabels() []machine.NodeLabel {
 func (n noConfigurer) GetLocalPath() string {
 	return ""
 }
+
+func (n noConfigurer) GetCNI() k8s.CNI {
+	return k8s.CNIDefault
+}
+
+func (n noConfigurer) GetAPIServerCertSANs() []string {
+	return nil
+}
+
+func (n noConfigurer) GetKindPatches() []string {
+	return nil
+}
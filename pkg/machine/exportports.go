@@ -0,0 +1,152 @@
+package machine
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	ProtocolTCP  = "TCP"
+	ProtocolUDP  = "UDP"
+	ProtocolSCTP = "SCTP"
+)
+
+const defaultListenAddress = "0.0.0.0"
+
+// ParseExportPorts parses comma delimited export ports, each of them in the format of
+//
+//	hostPort[-hostPortEnd]:containerPort[-containerPortEnd][/protocol][@listenAddress]
+//
+// e.g. 8443:443, 8000-8010:30000-30010/udp@127.0.0.1
+// port ranges are expanded into one pair per port, protocol is TCP if not specified.
+func ParseExportPorts(s string) ([]ExportPortPair, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return nil, nil
+	}
+	var exportPorts []ExportPortPair
+	// listen addresses bound by each host port and protocol
+	bound := map[string][]string{}
+	for _, token := range strings.Split(s, ",") {
+		pairs, err := parseExportPortToken(strings.TrimSpace(token))
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			listenAddress := pair.ListenAddress
+			if listenAddress == "" {
+				// kind binds to all interfaces when no listen address is given
+				listenAddress = defaultListenAddress
+			}
+			key := fmt.Sprintf("%d/%s", pair.HostPort, pair.Protocol)
+			for _, other := range bound[key] {
+				if listenAddressesOverlap(listenAddress, other) {
+					return nil, fmt.Errorf("exportport: host port %d/%s is exported more than once", pair.HostPort, pair.Protocol)
+				}
+			}
+			bound[key] = append(bound[key], listenAddress)
+			exportPorts = append(exportPorts, pair)
+		}
+	}
+	return exportPorts, nil
+}
+
+// listenAddressesOverlap returns true if both addresses can't be bound to the same port at once,
+// a wildcard address (e.g. 0.0.0.0) takes the port on every address.
+func listenAddressesOverlap(a string, b string) bool {
+	ipa, ipb := net.ParseIP(a), net.ParseIP(b)
+	return ipa.Equal(ipb) || ipa.IsUnspecified() || ipb.IsUnspecified()
+}
+
+func parseExportPortToken(token string) ([]ExportPortPair, error) {
+	var listenAddress string
+	if idx := strings.LastIndex(token, "@"); idx >= 0 {
+		listenAddress = token[idx+1:]
+		token = token[:idx]
+		if net.ParseIP(listenAddress) == nil {
+			return nil, fmt.Errorf("exportport: invalid listen address:%s", listenAddress)
+		}
+	}
+	protocol := ProtocolTCP
+	if idx := strings.LastIndex(token, "/"); idx >= 0 {
+		p, err := parseProtocol(token[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		protocol = p
+		token = token[:idx]
+	}
+	subtokens := strings.Split(token, ":")
+	if len(subtokens) != 2 {
+		return nil, fmt.Errorf("exportport: parse failed, expect: a:b but got:%s", token)
+	}
+	hostStart, hostEnd, err := parsePortRange(subtokens[0])
+	if err != nil {
+		return nil, err
+	}
+	containerStart, containerEnd, err := parsePortRange(subtokens[1])
+	if err != nil {
+		return nil, err
+	}
+	if hostEnd-hostStart != containerEnd-containerStart {
+		return nil, fmt.Errorf("exportport: host port range (%s) and container port range (%s) have different lengths", subtokens[0], subtokens[1])
+	}
+	var pairs []ExportPortPair
+	for offset := 0; offset <= hostEnd-hostStart; offset++ {
+		pairs = append(pairs, ExportPortPair{
+			HostPort:      hostStart + offset,
+			ContainerPort: containerStart + offset,
+			Protocol:      protocol,
+			ListenAddress: listenAddress,
+		})
+	}
+	return pairs, nil
+}
+
+func parseProtocol(s string) (string, error) {
+	switch strings.ToUpper(s) {
+	case ProtocolTCP:
+		return ProtocolTCP, nil
+	case ProtocolUDP:
+		return ProtocolUDP, nil
+	case ProtocolSCTP:
+		return ProtocolSCTP, nil
+	default:
+		return "", fmt.Errorf("exportport: unknown protocol:%s, possible value: tcp, udp and sctp", s)
+	}
+}
+
+// parsePortRange parses either a single port (8000) or a port range (8000-8010)
+func parsePortRange(s string) (int, int, error) {
+	tokens := strings.Split(s, "-")
+	if len(tokens) > 2 {
+		return 0, 0, fmt.Errorf("exportport: invalid port range:%s", s)
+	}
+	start, err := parsePort(tokens[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(tokens) == 1 {
+		return start, start, nil
+	}
+	end, err := parsePort(tokens[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("exportport: invalid port range, start > end:%s", s)
+	}
+	return start, end, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("exportport: invalid port:%s", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("exportport: port %d out of range (1-65535)", port)
+	}
+	return port, nil
+}
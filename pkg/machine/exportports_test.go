@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExportPorts(t *testing.T) {
+	pairs, err := ParseExportPorts("")
+	assert.NoError(t, err)
+	assert.Nil(t, pairs)
+
+	pairs, err = ParseExportPorts("8443:443,8000-8002:30000-30002/udp@127.0.0.1")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []ExportPortPair{
+		{HostPort: 8443, ContainerPort: 443, Protocol: ProtocolTCP},
+		{HostPort: 8000, ContainerPort: 30000, Protocol: ProtocolUDP, ListenAddress: "127.0.0.1"},
+		{HostPort: 8001, ContainerPort: 30001, Protocol: ProtocolUDP, ListenAddress: "127.0.0.1"},
+		{HostPort: 8002, ContainerPort: 30002, Protocol: ProtocolUDP, ListenAddress: "127.0.0.1"},
+	}, pairs)
+
+	// the same host port with different protocols are allowed
+	pairs, err = ParseExportPorts("53:30053/udp,53:30053/tcp@::1")
+	assert.NoError(t, err)
+	assert.Len(t, pairs, 2)
+	assert.EqualValues(t, "::1", pairs[1].ListenAddress)
+
+	// the same host port on different specific addresses are allowed
+	pairs, err = ParseExportPorts("8443:443@127.0.0.1,8443:444@192.168.1.2")
+	assert.NoError(t, err)
+	assert.Len(t, pairs, 2)
+
+	invalids := []string{
+		"8443",
+		"8443:abc",
+		"0:443",
+		"8443:70000",
+		"8000-8010:30000-30005",
+		"8010-8000:30010-30000",
+		"8443:443/icmp",
+		"8443:443@localhost",
+		"8443:443,8443:444",
+		"8443:443,8443:444@0.0.0.0",
+		"8443:443@0.0.0.0,8443:444@127.0.0.1",
+		"8443:443@127.0.0.1,8443:444",
+		"8443:443@::,8443:444@::1",
+	}
+	for _, invalid := range invalids {
+		_, err := ParseExportPorts(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,43 @@
+package fsutil
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	l, err := LockFile(path)
+	assert.NoError(t, err)
+
+	acquired := make(chan *FileLock)
+	go func() {
+		l2, err := LockFile(path)
+		assert.NoError(t, err)
+		acquired <- l2
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while being held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.NoError(t, l.Unlock())
+	select {
+	case l2 := <-acquired:
+		assert.NoError(t, l2.Unlock())
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock not acquired after unlock")
+	}
+}
+
+func TestLockFileCreatesDir(t *testing.T) {
+	l, err := LockFile(filepath.Join(t.TempDir(), "not-yet-created", "test.lock"))
+	assert.NoError(t, err)
+	assert.NoError(t, l.Unlock())
+}
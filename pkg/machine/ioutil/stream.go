@@ -9,6 +9,9 @@ import (
 	"sigs.k8s.io/kind/pkg/log"
 )
 
+// MaxLineSize is the longest line of command outputs, e.g. an audit event of a large object, longer lines fail the command
+const MaxLineSize = 1024 * 1024 * 10 /*10M*/
+
 type StreamReader interface {
 	Read(b []byte) (int, error) // read stream to buffer with Reader
 }
@@ -61,7 +64,7 @@ func StderrOnError(o *CmdOutputStream) error {
 
 func ReadAll(r io.Reader) ([]byte, error) {
 	buf := &bytes.Buffer{}
-	b := make([]byte, 1024*1024*10 /*10M buffer*/)
+	b := make([]byte, MaxLineSize+1 /*with the line break*/)
 	for {
 		n, err := r.Read(b)
 		if err == nil {
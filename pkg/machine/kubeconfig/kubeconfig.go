@@ -0,0 +1,76 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RewriteServer replaces the host of every cluster's server url with remoteHost, port is preserved.
+// e.g. https://0.0.0.0:16443 becomes https://10.1.2.3:16443
+func RewriteServer(kubeConfigBytes []byte, remoteHost string) ([]byte, error) {
+	if remoteHost == "" {
+		return nil, fmt.Errorf("kubeconfig: empty remote host")
+	}
+	config, err := clientcmd.Load(kubeConfigBytes)
+	if err != nil {
+		return nil, err
+	}
+	for name, cluster := range config.Clusters {
+		serverUrl, err := url.Parse(cluster.Server)
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig: invalid server url for cluster %s, err:%+v", name, err)
+		}
+		if port := serverUrl.Port(); port != "" {
+			serverUrl.Host = net.JoinHostPort(remoteHost, port)
+		} else {
+			serverUrl.Host = remoteHost
+		}
+		cluster.Server = serverUrl.String()
+	}
+	return clientcmd.Write(*config)
+}
+
+// RewriteServerFile rewrites server url of the kubeconfig file in place
+func RewriteServerFile(kubeConfigFile string, remoteHost string) error {
+	blob, err := os.ReadFile(kubeConfigFile)
+	if err != nil {
+		return err
+	}
+	rewritten, err := RewriteServer(blob, remoteHost)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(kubeConfigFile, rewritten, 0600)
+}
+
+// NewTokenKubeConfig generates a standalone kubeconfig for a bearer token identity,
+// cluster info (server/ca) is copied from the current context of the admin kubeconfig.
+func NewTokenKubeConfig(adminKubeConfigBytes []byte, name string, namespace string, token string) ([]byte, error) {
+	admin, err := clientcmd.Load(adminKubeConfigBytes)
+	if err != nil {
+		return nil, err
+	}
+	adminContext, found := admin.Contexts[admin.CurrentContext]
+	if !found {
+		return nil, fmt.Errorf("kubeconfig: current context (%s) not found", admin.CurrentContext)
+	}
+	cluster, found := admin.Clusters[adminContext.Cluster]
+	if !found {
+		return nil, fmt.Errorf("kubeconfig: cluster (%s) not found", adminContext.Cluster)
+	}
+	config := clientcmdapi.NewConfig()
+	config.Clusters[adminContext.Cluster] = cluster.DeepCopy()
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: token}
+	config.Contexts[name] = &clientcmdapi.Context{
+		Cluster:   adminContext.Cluster,
+		AuthInfo:  name,
+		Namespace: namespace,
+	}
+	config.CurrentContext = name
+	return clientcmd.Write(*config)
+}
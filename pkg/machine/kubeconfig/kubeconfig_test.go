@@ -0,0 +1,101 @@
+package kubeconfig
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var kindKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: Y2EK
+    server: https://0.0.0.0:16443
+  name: kind-test000
+contexts:
+- context:
+    cluster: kind-test000
+    user: kind-test000
+  name: kind-test000
+current-context: kind-test000
+users:
+- name: kind-test000
+  user:
+    client-certificate-data: Y2VydAo=
+    client-key-data: a2V5Cg==
+`
+
+func TestRewriteServer(t *testing.T) {
+	rewritten, err := RewriteServer([]byte(kindKubeConfig), "kf.example.com")
+	assert.NoError(t, err)
+	config, err := clientcmd.Load(rewritten)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "https://kf.example.com:16443", config.Clusters["kind-test000"].Server)
+	assert.EqualValues(t, "kind-test000", config.CurrentContext)
+
+	rewritten, err = RewriteServer([]byte(kindKubeConfig), "fd00::1")
+	assert.NoError(t, err)
+	config, err = clientcmd.Load(rewritten)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "https://[fd00::1]:16443", config.Clusters["kind-test000"].Server)
+
+	_, err = RewriteServer([]byte(kindKubeConfig), "")
+	assert.Error(t, err)
+}
+
+func TestNewTokenKubeConfig(t *testing.T) {
+	blob, err := NewTokenKubeConfig([]byte(kindKubeConfig), "alice", "team-a", "t0ken")
+	assert.NoError(t, err)
+	config, err := clientcmd.Load(blob)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "alice", config.CurrentContext)
+	assert.EqualValues(t, "team-a", config.Contexts["alice"].Namespace)
+	assert.EqualValues(t, "t0ken", config.AuthInfos["alice"].Token)
+	assert.Empty(t, config.AuthInfos["alice"].ClientCertificateData)
+	assert.EqualValues(t, "https://0.0.0.0:16443", config.Clusters[config.Contexts["alice"].Cluster].Server)
+}
+
+func TestMergeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	// merge into a non-existing file
+	assert.NoError(t, MergeFile(path, []byte(kindKubeConfig), "test000", false))
+	assert.NoError(t, UseContextFile(path, "test000"))
+	config, err := clientcmd.LoadFromFile(path)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "test000", config.CurrentContext)
+	assert.EqualValues(t, "test000", config.Contexts["test000"].Cluster)
+	assert.EqualValues(t, "test000", config.Contexts["test000"].AuthInfo)
+	assert.EqualValues(t, "https://0.0.0.0:16443", config.Clusters["test000"].Server)
+
+	// name collision unless replace
+	remote, err := RewriteServer([]byte(kindKubeConfig), "10.1.2.3")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, MergeFile(path, remote, "test000", false), ErrNameCollision)
+	assert.NoError(t, MergeFile(path, remote, "test000", true))
+	assert.NoError(t, MergeFile(path, []byte(kindKubeConfig), "test001", false))
+	config, err = clientcmd.LoadFromFile(path)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "https://10.1.2.3:16443", config.Clusters["test000"].Server)
+	assert.Len(t, config.Contexts, 2)
+
+	assert.Error(t, UseContextFile(path, "test002"))
+
+	// remove the current context
+	assert.NoError(t, RemoveFile(path, "test000"))
+	config, err = clientcmd.LoadFromFile(path)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "", config.CurrentContext)
+	assert.Len(t, config.Contexts, 1)
+	assert.Len(t, config.Clusters, 1)
+	assert.Len(t, config.AuthInfos, 1)
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("KUBECONFIG", strings.Join([]string{"/tmp/a", "/tmp/b"}, string(filepath.ListSeparator)))
+	assert.EqualValues(t, "/tmp/a", DefaultPath())
+}
@@ -0,0 +1,115 @@
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ErrNameCollision is returned when an unrelated cluster/user/context with the same name exists in the target kubeconfig
+var ErrNameCollision = errors.New("kubeconfig: name collision")
+
+// DefaultPath returns the kubeconfig used by kubectl, which is the first path in $KUBECONFIG or ~/.kube/config
+func DefaultPath() string {
+	for _, path := range filepath.SplitList(os.Getenv(clientcmd.RecommendedConfigPathEnvVar)) {
+		if path != "" {
+			return path
+		}
+	}
+	return clientcmd.RecommendedHomeFile
+}
+
+func loadOrNew(path string) (*clientcmdapi.Config, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return clientcmdapi.NewConfig(), nil
+	}
+	return clientcmd.LoadFromFile(path)
+}
+
+// Merge adds cluster/user/context of source's current context into target with name,
+// entries with the same name are replaced only when replace is true, otherwise ErrNameCollision is returned.
+func Merge(target *clientcmdapi.Config, source *clientcmdapi.Config, name string, replace bool) error {
+	sourceContext, found := source.Contexts[source.CurrentContext]
+	if !found {
+		return fmt.Errorf("kubeconfig: current context (%s) not found in source", source.CurrentContext)
+	}
+	cluster, found := source.Clusters[sourceContext.Cluster]
+	if !found {
+		return fmt.Errorf("kubeconfig: cluster (%s) not found in source", sourceContext.Cluster)
+	}
+	user, found := source.AuthInfos[sourceContext.AuthInfo]
+	if !found {
+		return fmt.Errorf("kubeconfig: user (%s) not found in source", sourceContext.AuthInfo)
+	}
+	context := sourceContext.DeepCopy()
+	context.Cluster = name
+	context.AuthInfo = name
+
+	if !replace {
+		_, clusterFound := target.Clusters[name]
+		_, userFound := target.AuthInfos[name]
+		_, contextFound := target.Contexts[name]
+		if clusterFound || userFound || contextFound {
+			return fmt.Errorf("%w: cluster/user/context %s exists", ErrNameCollision, name)
+		}
+	}
+	target.Clusters[name] = cluster.DeepCopy()
+	target.AuthInfos[name] = user.DeepCopy()
+	target.Contexts[name] = context
+	return nil
+}
+
+// MergeFile merges the kubeconfig blob into the kubeconfig file (created if not exist)
+func MergeFile(path string, kubeConfigBytes []byte, name string, replace bool) error {
+	source, err := clientcmd.Load(kubeConfigBytes)
+	if err != nil {
+		return err
+	}
+	target, err := loadOrNew(path)
+	if err != nil {
+		return err
+	}
+	if err := Merge(target, source, name, replace); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return clientcmd.WriteToFile(*target, path)
+}
+
+// RemoveFile removes the cluster/user/context with name from the kubeconfig file,
+// current context is unset if it points to the removed one.
+func RemoveFile(path string, name string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return err
+	}
+	delete(config.Clusters, name)
+	delete(config.AuthInfos, name)
+	delete(config.Contexts, name)
+	if config.CurrentContext == name {
+		config.CurrentContext = ""
+	}
+	return clientcmd.WriteToFile(*config, path)
+}
+
+// UseContextFile switches current context of the kubeconfig file
+func UseContextFile(path string, name string) error {
+	config, err := loadOrNew(path)
+	if err != nil {
+		return err
+	}
+	if _, found := config.Contexts[name]; !found {
+		return fmt.Errorf("kubeconfig: context %s not found in %s", name, path)
+	}
+	config.CurrentContext = name
+	return clientcmd.WriteToFile(*config, path)
+}
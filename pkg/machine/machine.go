@@ -19,10 +19,20 @@ type MachineConfiger interface {
 	GetExportPorts() []ExportPortPair
 	GetForceOverwriteConfig() bool
 	AuditEnabled() bool
+	// GetAuditPolicy returns the audit policy, the default one is used if it is empty
+	GetAuditPolicy() string
+	// GetControlPlanes returns the number of control-plane nodes, kind load balances kubeapi if there are more than one
+	GetControlPlanes() int
 	GetWorkers() int
+	// GetWorkerGroups returns worker groups added besides identical workers from GetWorkers
+	GetWorkerGroups() []WorkerGroup
 	GetNodeLabels() []NodeLabel
 	GetLocalPath() string
 	GetNodeVersion() k8s.KindK8sVersion
+	GetCNI() k8s.CNI
+	GetAPIServerCertSANs() []string
+	// GetKindPatches returns user yaml merged into the generated kind config
+	GetKindPatches() []string
 
 	// Info displays all configurations
 	Info() string
@@ -31,6 +41,8 @@ type MachineConfiger interface {
 type ExportPortPair struct {
 	HostPort      int
 	ContainerPort int
+	Protocol      string // TCP, UDP or SCTP, empty stands for TCP
+	ListenAddress string // optional host address to listen on
 }
 
 type NodeLabel struct {
@@ -62,6 +74,15 @@ type MachineCURD interface {
 	ExportKubeConfig(path string, forceOverwrite bool) error
 }
 
+// ControlPlaneExecutor is implemented by machines which could run shell commands inside its control-plane nodes
+type ControlPlaneExecutor interface {
+	// ExecControlPlane runs cmd on the first control-plane node
+	ExecControlPlane(cmd string) (string, error)
+	// ExecControlPlanes runs cmd on every control-plane node in order, outputs are returned per node even if some of
+	// them fail, errors of failed nodes are joined.
+	ExecControlPlanes(cmd string) ([]string, error)
+}
+
 type MachineInfo struct {
 	CpuInfo *CpuInfo
 	MemInfo *MemInfo
@@ -0,0 +1,94 @@
+package metallb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var (
+	// DefaultPoolSize is the number of addresses assigned to each machine
+	DefaultPoolSize = 16
+)
+
+// AddressRange represents a continuous ipv4 range, e.g. 172.18.255.240-172.18.255.255
+type AddressRange struct {
+	Start net.IP
+	End   net.IP
+}
+
+func (a AddressRange) String() string {
+	return fmt.Sprintf("%s-%s", a.Start, a.End)
+}
+
+func (a AddressRange) overlaps(b AddressRange) bool {
+	return ipToUint32(a.Start) <= ipToUint32(b.End) && ipToUint32(b.Start) <= ipToUint32(a.End)
+}
+
+func ParseAddressRange(s string) (AddressRange, error) {
+	tokens := strings.Split(s, "-")
+	if len(tokens) != 2 {
+		return AddressRange{}, fmt.Errorf("metallb: invalid address range, expect: a.b.c.d-e.f.g.h but got:%s", s)
+	}
+	start := net.ParseIP(tokens[0]).To4()
+	end := net.ParseIP(tokens[1]).To4()
+	if start == nil || end == nil {
+		return AddressRange{}, fmt.Errorf("metallb: invalid ipv4 address range:%s", s)
+	}
+	if ipToUint32(start) > ipToUint32(end) {
+		return AddressRange{}, fmt.Errorf("metallb: invalid address range, start > end:%s", s)
+	}
+	return AddressRange{Start: start, End: end}, nil
+}
+
+// AllocateAddressRange carves an address range with poolSize addresses from the subnet (in cidr format).
+// Ranges are allocated from the top of the subnet downward as docker assigns container addresses from the bottom,
+// ranges which overlap with any of the used ones are skipped.
+func AllocateAddressRange(subnet string, used []AddressRange, poolSize int) (AddressRange, error) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return AddressRange{}, err
+	}
+	if ipnet.IP.To4() == nil {
+		return AddressRange{}, fmt.Errorf("metallb: only ipv4 subnet is supported, got:%s", subnet)
+	}
+	if poolSize <= 0 {
+		return AddressRange{}, errors.New("metallb: pool size should be positive")
+	}
+	ones, bits := ipnet.Mask.Size()
+	first := ipToUint32(ipnet.IP.To4())
+	last := first + uint32(1<<(bits-ones)) - 1
+	// keep network address, gateway (first usable) and broadcast address away from pools
+	lowest := first + 2
+	highest := last - 1
+
+	for end := highest; end >= lowest+uint32(poolSize)-1; end -= uint32(poolSize) {
+		candidate := AddressRange{
+			Start: uint32ToIP(end - uint32(poolSize) + 1),
+			End:   uint32ToIP(end),
+		}
+		collided := false
+		for _, u := range used {
+			if candidate.overlaps(u) {
+				collided = true
+				break
+			}
+		}
+		if !collided {
+			return candidate, nil
+		}
+	}
+	return AddressRange{}, fmt.Errorf("metallb: no available address range in subnet %s", subnet)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
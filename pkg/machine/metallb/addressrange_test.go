@@ -0,0 +1,40 @@
+package metallb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateAddressRange(t *testing.T) {
+	first, err := AllocateAddressRange("172.18.0.0/16", nil, 16)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "172.18.255.239-172.18.255.254", first.String())
+
+	second, err := AllocateAddressRange("172.18.0.0/16", []AddressRange{first}, 16)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "172.18.255.223-172.18.255.238", second.String())
+
+	// a released range could be reused
+	reused, err := AllocateAddressRange("172.18.0.0/16", []AddressRange{second}, 16)
+	assert.NoError(t, err)
+	assert.EqualValues(t, first.String(), reused.String())
+
+	_, err = AllocateAddressRange("172.18.0.0/28", nil, 16)
+	assert.Error(t, err)
+
+	_, err = AllocateAddressRange("fc00:f853:ccd:e793::/64", nil, 16)
+	assert.Error(t, err)
+}
+
+func TestParseAddressRange(t *testing.T) {
+	r, err := ParseAddressRange("172.18.255.239-172.18.255.254")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "172.18.255.239-172.18.255.254", r.String())
+
+	_, err = ParseAddressRange("172.18.255.254-172.18.255.239")
+	assert.Error(t, err)
+
+	_, err = ParseAddressRange("172.18.255.238")
+	assert.Error(t, err)
+}
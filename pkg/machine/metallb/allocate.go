@@ -0,0 +1,109 @@
+package metallb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/docker"
+	"github.com/footprintai/multikf/pkg/machine/fsutil"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+// allocationLockFile guards address range allocation across concurrent `add` runs sharing a root dir
+const allocationLockFile = ".metallb.lock"
+
+// InstallOnMachine installs metallb with an address range carved from kind's docker network,
+// the range is recorded in machine state and reused afterward.
+func InstallOnMachine(logger log.Logger, rootDir string, verbose bool, m machine.MachineCURD) error {
+	if m.Type() != machine.MachineTypeDocker {
+		return errors.New("metallb: only docker machine is supported")
+	}
+	addressRange, err := allocateForMachine(logger, rootDir, verbose, m)
+	if err != nil {
+		return err
+	}
+	return Install(logger, m.GetKubeCli(), m.GetKubeConfig(), m.HostDir(), addressRange)
+}
+
+// allocateForMachine returns the machine's recorded range, or allocates a free one while holding
+// the root dir lock so that the scan of used ranges and the save are atomic.
+func allocateForMachine(logger log.Logger, rootDir string, verbose bool, m machine.MachineCURD) (AddressRange, error) {
+	lock, err := fsutil.LockFile(filepath.Join(rootDir, allocationLockFile))
+	if err != nil {
+		return AddressRange{}, err
+	}
+	defer lock.Unlock()
+
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return AddressRange{}, err
+	}
+	if state.LoadBalancerAddressRange != "" {
+		return ParseAddressRange(state.LoadBalancerAddressRange)
+	}
+	dockercli, err := docker.NewDockerCli(logger, verbose)
+	if err != nil {
+		return AddressRange{}, err
+	}
+	subnet, err := dockercli.GetNetworkIPv4Subnet(docker.KindNetworkName)
+	if err != nil {
+		return AddressRange{}, err
+	}
+	used, err := listUsedAddressRanges(logger, rootDir, verbose, m.Name())
+	if err != nil {
+		return AddressRange{}, err
+	}
+	addressRange, err := AllocateAddressRange(subnet, used, DefaultPoolSize)
+	if err != nil {
+		return AddressRange{}, err
+	}
+	state.LoadBalancerAddressRange = addressRange.String()
+	if err := state.Save(m.HostDir()); err != nil {
+		return AddressRange{}, err
+	}
+	return addressRange, nil
+}
+
+// listUsedAddressRanges collects address ranges assigned to all machines except the excluded one, an unreadable
+// state is an error as skipping it could hand out an overlapping range.
+func listUsedAddressRanges(logger log.Logger, rootDir string, verbose bool, excludedMachineName string) ([]AddressRange, error) {
+	var used []AddressRange
+	var errs []error
+	machine.ForEachProvisioner(func(p machine.Provisioner) {
+		vag, err := machine.NewMachineFactory(p, logger, rootDir, verbose)
+		if err != nil {
+			logger.Errorf("machine.metallb: failed, err:%+v\n", err)
+			return
+		}
+		machines, err := vag.ListMachines()
+		if err != nil {
+			logger.Errorf("machine.metallb: failed, err:%+v\n", err)
+			return
+		}
+		for _, m := range machines {
+			if m.Name() == excludedMachineName {
+				continue
+			}
+			state, err := machine.LoadMachineState(m.HostDir())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("machine.metallb: load state of %s failed, err:%+v", m.Name(), err))
+				continue
+			}
+			if state.LoadBalancerAddressRange == "" {
+				continue
+			}
+			addressRange, err := ParseAddressRange(state.LoadBalancerAddressRange)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("machine.metallb: invalid address range for %s, err:%+v", m.Name(), err))
+				continue
+			}
+			used = append(used, addressRange)
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return used, nil
+}
@@ -0,0 +1,122 @@
+package metallb
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+var (
+	metallbRolloutTimeout = 5 * time.Minute
+	metallbNamespace      = "metallb-system"
+)
+
+func NewMetalLBTemplateExecutor() (pkgtemplate.TemplateExecutor, error) {
+	manifestBytes, err := manifestFs.ReadFile("manifests/metallb-native-v0.14.8.yaml")
+	if err != nil {
+		return nil, err
+	}
+	return &MetalLBFileTemplate{manifest: manifestBytes}, nil
+}
+
+// MetalLBFileTemplate dumps the embedded metallb manifest as-is
+type MetalLBFileTemplate struct {
+	manifest []byte
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &MetalLBFileTemplate{}
+)
+
+func (m *MetalLBFileTemplate) Filename() string {
+	return "metallb-native.yaml"
+}
+
+func (m *MetalLBFileTemplate) Execute(w io.Writer) error {
+	_, err := w.Write(m.manifest)
+	return err
+}
+
+func (m *MetalLBFileTemplate) Populate(v interface{}) error {
+	return nil
+}
+
+func NewMetalLBPoolTemplateExecutor() (*MetalLBPoolFileTemplate, error) {
+	tmplBytes, err := manifestFs.ReadFile("manifests/metallb-pool-template.yaml")
+	if err != nil {
+		return nil, err
+	}
+	return &MetalLBPoolFileTemplate{poolFileTemplate: string(tmplBytes)}, nil
+}
+
+// MetalLBPoolFileTemplate renders IPAddressPool and L2Advertisement for an address range
+type MetalLBPoolFileTemplate struct {
+	AddressRange     string
+	poolFileTemplate string
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &MetalLBPoolFileTemplate{}
+)
+
+func (m *MetalLBPoolFileTemplate) Filename() string {
+	return "metallb-pool.yaml"
+}
+
+func (m *MetalLBPoolFileTemplate) Execute(w io.Writer) error {
+	tmpl, err := template.New("metallbpool").Delims("[[", "]]").Parse(m.poolFileTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, m)
+}
+
+func (m *MetalLBPoolFileTemplate) Populate(v interface{}) error {
+	r, isAddressRange := v.(AddressRange)
+	if !isAddressRange {
+		return fmt.Errorf("not an AddressRange")
+	}
+	m.AddressRange = r.String()
+	return nil
+}
+
+// Install applies metallb manifests and configures an address pool with the given range
+func Install(logger log.Logger, kubecli *machinekubectlcmd.CLI, kubeConfigFile string, hostDir string, addressRange AddressRange) error {
+	nativeTmpl, err := NewMetalLBTemplateExecutor()
+	if err != nil {
+		return err
+	}
+	poolTmpl, err := NewMetalLBPoolTemplateExecutor()
+	if err != nil {
+		return err
+	}
+	memFs := templatefs.NewMemoryFilesFs()
+	if err := memFs.Generate(addressRange, nativeTmpl, poolTmpl); err != nil {
+		return err
+	}
+	if err := templatefs.NewFolder(hostDir).DumpFiles(true, memFs.FS()); err != nil {
+		return err
+	}
+	logger.V(0).Infof("metallb: install with address range %s\n", addressRange)
+	if err := kubecli.Apply(kubeConfigFile, filepath.Join(hostDir, nativeTmpl.Filename())); err != nil {
+		return err
+	}
+	for _, resource := range []string{"deployment/controller", "daemonset/speaker"} {
+		logger.V(1).Infof("metallb: wait for %s/%s\n", metallbNamespace, resource)
+		if err := kubecli.RolloutStatus(kubeConfigFile, metallbNamespace, resource, metallbRolloutTimeout); err != nil {
+			return err
+		}
+	}
+	return kubecli.Apply(kubeConfigFile, filepath.Join(hostDir, poolTmpl.Filename()))
+}
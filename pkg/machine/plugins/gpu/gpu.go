@@ -0,0 +1,297 @@
+package gpu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+	"github.com/footprintai/multikf/pkg/machine/plugins/helm"
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+)
+
+const (
+	TypePluginGPU plugins.TypePlugin = "gpu"
+
+	// ParamMode is either device-plugin (default) or operator
+	ParamMode = "mode"
+	// ParamReplicas is the number of time-slicing replicas per gpu (default: 1, no time-slicing)
+	ParamReplicas = "replicas"
+	// ParamOperatorVersion is the chart version of nvidia's gpu-operator, used in operator mode
+	ParamOperatorVersion = "operator_version"
+	// ParamVerify runs a job requesting nvidia.com/gpu after installation (default: true)
+	ParamVerify = "verify"
+)
+
+// Mode is how gpus are exposed to kubernetes
+type Mode string
+
+const (
+	// ModeDevicePlugin runs nvidia's device plugin only, nodes' containerd should already use nvidia runtime
+	ModeDevicePlugin Mode = "device-plugin"
+	// ModeOperator runs nvidia's gpu-operator, which also configures containerd inside nodes with nvidia runtime
+	ModeOperator Mode = "operator"
+)
+
+func (m Mode) String() string {
+	return string(m)
+}
+
+func ParseMode(s string) (Mode, error) {
+	for _, mode := range []Mode{ModeDevicePlugin, ModeOperator} {
+		if strings.EqualFold(s, mode.String()) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("gpu: unsupported mode %q, available modes: %s,%s", s, ModeDevicePlugin, ModeOperator)
+}
+
+const (
+	devicePluginNamespace  = "nvidia-device-plugin"
+	operatorNamespace      = "gpu-operator"
+	operatorRelease        = "gpu-operator"
+	operatorChart          = "gpu-operator"
+	operatorRepo           = "https://helm.ngc.nvidia.com/nvidia"
+	defaultOperatorVersion = "v24.9.2"
+
+	verifyJobName = "multikf-gpu-verify"
+	verifyImage   = "nvcr.io/nvidia/cuda:12.4.1-base-ubuntu22.04"
+)
+
+var (
+	gpuTimeout      = 5 * time.Minute
+	gpuPollInterval = 5 * time.Second
+)
+
+func init() {
+	plugins.RegisterPlugin(TypePluginGPU, newGPUInstaller)
+}
+
+func newGPUInstaller(logger log.Logger) plugins.Installer {
+	return &gpuInstaller{logger: logger}
+}
+
+type gpuInstaller struct {
+	logger log.Logger
+}
+
+var (
+	_ plugins.Installer       = &gpuInstaller{}
+	_ plugins.NamespacesOwner = &gpuInstaller{}
+	_ plugins.Dependent       = &gpuInstaller{}
+)
+
+func (g *gpuInstaller) Description() string {
+	return "nvidia device plugin or gpu-operator with optional time-slicing, plugin version stands for the device plugin version"
+}
+
+func (g *gpuInstaller) Versions() []plugins.TypePluginVersion {
+	return []plugins.TypePluginVersion{"v0.17.0", "v0.16.2"}
+}
+
+// configFromPlugin builds the config from plugin parameters
+func configFromPlugin(p plugins.Plugin) (Config, error) {
+	mode, err := ParseMode(plugins.GetParam(p, ParamMode, ModeDevicePlugin.String()))
+	if err != nil {
+		return Config{}, err
+	}
+	replicas, err := strconv.Atoi(plugins.GetParam(p, ParamReplicas, "1"))
+	if err != nil {
+		return Config{}, fmt.Errorf("gpu: invalid %s, err:%+v", ParamReplicas, err)
+	}
+	c := Config{
+		Mode:      mode,
+		Version:   p.PluginVersion().String(),
+		Replicas:  replicas,
+		Namespace: devicePluginNamespace,
+	}
+	if mode == ModeOperator {
+		c.Namespace = operatorNamespace
+	}
+	return c, c.Validate()
+}
+
+// generateManifests renders manifests of the mode under the machine's HostDir, paths are returned in apply order
+func (g *gpuInstaller) generateManifests(m machine.MachineCURD, c Config) ([]string, error) {
+	names := []string{manifestDevicePluginConfig, manifestDevicePlugin, manifestVerifyJob}
+	if c.Mode == ModeOperator {
+		names = []string{manifestDevicePluginConfig, manifestOperatorValues, manifestVerifyJob}
+	}
+	var tmpls []pkgtemplate.TemplateExecutor
+	for _, name := range names {
+		tmpl, err := newGPUTemplateExecutor(name)
+		if err != nil {
+			return nil, err
+		}
+		tmpls = append(tmpls, tmpl)
+	}
+	return plugins.GenerateManifests(m.HostDir(), c, tmpls...)
+}
+
+func (g *gpuInstaller) operatorPlugin(p plugins.Plugin, valuesFile string) plugins.Plugin {
+	return plugins.NewPlugin(
+		helm.TypePluginHelm,
+		plugins.NewTypePluginVersion(plugins.GetParam(p, ParamOperatorVersion, defaultOperatorVersion)),
+		map[string]string{
+			helm.ParamRelease:   operatorRelease,
+			helm.ParamChart:     operatorChart,
+			helm.ParamRepo:      operatorRepo,
+			helm.ParamNamespace: operatorNamespace,
+			helm.ParamValues:    plugins.ListParam(valuesFile),
+		},
+	)
+}
+
+func (g *gpuInstaller) Install(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	files, err := g.generateManifests(m, c)
+	if err != nil {
+		return err
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	if err := kubecli.Apply(kubeConfig, files[0]); err != nil {
+		return err
+	}
+	switch c.Mode {
+	case ModeOperator:
+		helmInstaller, err := plugins.NewInstaller(helm.TypePluginHelm, g.logger)
+		if err != nil {
+			return err
+		}
+		g.logger.V(0).Infof("gpu: install gpu-operator with device plugin %s and %d time-slicing replicas\n", c.Version, c.Replicas)
+		if err := helmInstaller.Install(m, g.operatorPlugin(p, files[1])); err != nil {
+			return err
+		}
+	default:
+		g.logger.V(0).Infof("gpu: install device plugin %s with %d time-slicing replicas\n", c.Version, c.Replicas)
+		if err := kubecli.Apply(kubeConfig, files[1]); err != nil {
+			return err
+		}
+		if err := kubecli.RolloutStatus(kubeConfig, c.Namespace, "daemonset/nvidia-device-plugin", gpuTimeout); err != nil {
+			return err
+		}
+	}
+	if err := g.waitAllocatable(m); err != nil {
+		return err
+	}
+	if plugins.GetParam(p, ParamVerify, "true") == "false" {
+		return nil
+	}
+	return g.verify(m, c, files[2])
+}
+
+// waitAllocatable waits until any node advertises nvidia.com/gpu
+func (g *gpuInstaller) waitAllocatable(m machine.MachineCURD) error {
+	deadline := time.Now().Add(gpuTimeout)
+	for {
+		out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "nodes", "-o", `jsonpath={.items[*].status.allocatable.nvidia\.com/gpu}`)
+		if err == nil && allocatableGPUs(string(out)) > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("gpu: no node advertises nvidia.com/gpu after %s, check gpus are passed into nodes (--use_gpus)", gpuTimeout)
+		}
+		time.Sleep(gpuPollInterval)
+	}
+}
+
+// allocatableGPUs sums up allocatable gpus from a space separated jsonpath output
+func allocatableGPUs(s string) int {
+	var total int
+	for _, field := range strings.Fields(s) {
+		if n, err := strconv.Atoi(field); err == nil {
+			total += n
+		}
+	}
+	return total
+}
+
+// verify runs a job requesting one gpu and waits for its completion
+func (g *gpuInstaller) verify(m machine.MachineCURD, c Config, jobFile string) error {
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	if err := kubecli.Delete(kubeConfig, jobFile); err != nil {
+		return err
+	}
+	g.logger.V(0).Infof("gpu: verify gpu scheduling with job %s/%s\n", c.Namespace, verifyJobName)
+	if err := kubecli.Apply(kubeConfig, jobFile); err != nil {
+		return err
+	}
+	if _, err := kubecli.Output(kubeConfig, "wait", "--for=condition=complete", "job/"+verifyJobName, "-n", c.Namespace, fmt.Sprintf("--timeout=%s", gpuTimeout)); err != nil {
+		logs, _ := kubecli.Output(kubeConfig, "logs", "job/"+verifyJobName, "-n", c.Namespace)
+		return fmt.Errorf("gpu: verification job failed, err:%+v, logs:%s", err, logs)
+	}
+	logs, err := kubecli.Output(kubeConfig, "logs", "job/"+verifyJobName, "-n", c.Namespace)
+	if err == nil {
+		g.logger.V(0).Infof("gpu: verification passed:\n%s", logs)
+	}
+	return nil
+}
+
+func (g *gpuInstaller) Uninstall(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	files, err := g.generateManifests(m, c)
+	if err != nil {
+		return err
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	if err := kubecli.Delete(kubeConfig, files[2]); err != nil {
+		return err
+	}
+	switch c.Mode {
+	case ModeOperator:
+		helmInstaller, err := plugins.NewInstaller(helm.TypePluginHelm, g.logger)
+		if err != nil {
+			return err
+		}
+		if err := helmInstaller.Uninstall(m, g.operatorPlugin(p, files[1])); err != nil {
+			return err
+		}
+	default:
+		if err := kubecli.Delete(kubeConfig, files[1]); err != nil {
+			return err
+		}
+	}
+	return kubecli.Delete(kubeConfig, files[0])
+}
+
+// Dependencies returns helm in operator mode, which installs gpu-operator's chart
+func (g *gpuInstaller) Dependencies(p plugins.Plugin) []plugins.TypePlugin {
+	c, err := configFromPlugin(p)
+	if err != nil || c.Mode != ModeOperator {
+		return nil
+	}
+	return []plugins.TypePlugin{helm.TypePluginHelm}
+}
+
+// OwnedNamespaces returns the namespace of the plugin's mode, which is deleted along with the config manifest
+func (g *gpuInstaller) OwnedNamespaces(m machine.MachineCURD, p plugins.Plugin) []string {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return nil
+	}
+	return []string{c.Namespace}
+}
+
+// Status reports the status of daemonsets in the namespace of either mode
+func (g *gpuInstaller) Status(m machine.MachineCURD) (plugins.Status, error) {
+	for _, namespace := range []string{devicePluginNamespace, operatorNamespace} {
+		status, err := plugins.DaemonSetsStatus(m, namespace)
+		if err != nil {
+			return "", err
+		}
+		if status != plugins.StatusNotInstalled {
+			return status, nil
+		}
+	}
+	return plugins.StatusNotInstalled, nil
+}
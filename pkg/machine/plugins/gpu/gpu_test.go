@@ -0,0 +1,113 @@
+package gpu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+func TestConfigFromPlugin(t *testing.T) {
+	c, err := configFromPlugin(plugins.NewPlugin(TypePluginGPU, "v0.17.0", nil))
+	assert.NoError(t, err)
+	assert.EqualValues(t, Config{Mode: ModeDevicePlugin, Version: "v0.17.0", Replicas: 1, Namespace: devicePluginNamespace}, c)
+
+	c, err = configFromPlugin(plugins.NewPlugin(TypePluginGPU, "v0.17.0", map[string]string{ParamMode: "operator", ParamReplicas: "4"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, Config{Mode: ModeOperator, Version: "v0.17.0", Replicas: 4, Namespace: operatorNamespace}, c)
+
+	for _, invalid := range []map[string]string{
+		{ParamMode: "mig"},
+		{ParamReplicas: "0"},
+		{ParamReplicas: "two"},
+	} {
+		_, err := configFromPlugin(plugins.NewPlugin(TypePluginGPU, "v0.17.0", invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+// deviceConfig is the config file of nvidia's device plugin
+type deviceConfig struct {
+	Sharing *struct {
+		TimeSlicing struct {
+			Resources []struct {
+				Name     string `json:"name"`
+				Replicas int    `json:"replicas"`
+			} `json:"resources"`
+		} `json:"timeSlicing"`
+	} `json:"sharing"`
+}
+
+func renderDeviceConfig(t *testing.T, replicas int) deviceConfig {
+	blob, err := Render(Config{Mode: ModeDevicePlugin, Version: "v0.17.0", Replicas: replicas, Namespace: devicePluginNamespace}, manifestDevicePluginConfig)
+	assert.NoError(t, err)
+	docs := strings.Split(string(blob), "\n---\n")
+	assert.Len(t, docs, 2)
+	configMap := struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Data map[string]string `json:"data"`
+	}{}
+	assert.NoError(t, yaml.Unmarshal([]byte(docs[1]), &configMap))
+	assert.EqualValues(t, devicePluginNamespace, configMap.Metadata.Namespace)
+	config := deviceConfig{}
+	assert.NoError(t, yaml.Unmarshal([]byte(configMap.Data["any"]), &config))
+	return config
+}
+
+func TestRenderTimeSlicing(t *testing.T) {
+	assert.Nil(t, renderDeviceConfig(t, 1).Sharing)
+
+	config := renderDeviceConfig(t, 4)
+	assert.NotNil(t, config.Sharing)
+	assert.Len(t, config.Sharing.TimeSlicing.Resources, 1)
+	assert.EqualValues(t, "nvidia.com/gpu", config.Sharing.TimeSlicing.Resources[0].Name)
+	assert.EqualValues(t, 4, config.Sharing.TimeSlicing.Resources[0].Replicas)
+}
+
+func TestRenderManifests(t *testing.T) {
+	c := Config{Mode: ModeOperator, Version: "v0.17.0", Replicas: 2, Namespace: operatorNamespace}
+
+	blob, err := Render(c, manifestDevicePlugin)
+	assert.NoError(t, err)
+	assert.Contains(t, string(blob), "image: nvcr.io/nvidia/k8s-device-plugin:v0.17.0")
+	assert.Contains(t, string(blob), "--config-file=/config/any")
+
+	blob, err = Render(c, manifestOperatorValues)
+	assert.NoError(t, err)
+	values := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(blob, &values))
+	assert.EqualValues(t, false, values["driver"].(map[string]interface{})["enabled"])
+	assert.EqualValues(t, "v0.17.0", values["devicePlugin"].(map[string]interface{})["version"])
+
+	blob, err = Render(c, manifestVerifyJob)
+	assert.NoError(t, err)
+	job := struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Resources struct {
+							Limits map[string]string `json:"limits"`
+						} `json:"resources"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}{}
+	assert.NoError(t, yaml.Unmarshal(blob, &job))
+	assert.EqualValues(t, operatorNamespace, job.Metadata.Namespace)
+	assert.EqualValues(t, "1", job.Spec.Template.Spec.Containers[0].Resources.Limits["nvidia.com/gpu"])
+}
+
+func TestAllocatableGPUs(t *testing.T) {
+	assert.EqualValues(t, 0, allocatableGPUs(""))
+	assert.EqualValues(t, 5, allocatableGPUs("1 4"))
+}
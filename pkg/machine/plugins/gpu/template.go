@@ -0,0 +1,77 @@
+package gpu
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+const (
+	manifestDevicePluginConfig = "device-plugin-config.yaml"
+	manifestDevicePlugin       = "device-plugin.yaml"
+	manifestOperatorValues     = "operator-values.yaml"
+	manifestVerifyJob          = "verify-job.yaml"
+)
+
+// Config is rendered into manifests of the gpu plugin
+type Config struct {
+	Mode Mode
+	// Version is the version of nvidia's k8s-device-plugin, it is also used by the operator
+	Version string
+	// Replicas is the number of time-slicing replicas per gpu, 1 disables time-slicing
+	Replicas  int
+	Namespace string
+}
+
+func (c Config) Validate() error {
+	if _, err := ParseMode(c.Mode.String()); err != nil {
+		return err
+	}
+	if c.Version == "" {
+		return fmt.Errorf("gpu: empty device plugin version")
+	}
+	if c.Replicas < 1 {
+		return fmt.Errorf("gpu: time-slicing replicas should be at least 1, got %d", c.Replicas)
+	}
+	return nil
+}
+
+// templateData is the data of the gpu plugin's manifests
+type templateData struct {
+	Config
+	ConfigMapName string
+	ConfigKey     string
+	VerifyJobName string
+	VerifyImage   string
+}
+
+func toTemplateData(v interface{}) (interface{}, error) {
+	c, isConfig := v.(Config)
+	if !isConfig {
+		return nil, fmt.Errorf("not a gpu Config")
+	}
+	return templateData{
+		Config:        c,
+		ConfigMapName: "nvidia-device-plugin-config",
+		ConfigKey:     "any",
+		VerifyJobName: verifyJobName,
+		VerifyImage:   verifyImage,
+	}, nil
+}
+
+func newGPUTemplateExecutor(name string) (*plugins.ManifestTemplate, error) {
+	return plugins.NewManifestTemplate(manifestFs, TypePluginGPU, name, toTemplateData, nil)
+}
+
+// Render renders the named manifest with the config, it requires no gpu and is used by tests as well
+func Render(c Config, name string) ([]byte, error) {
+	tmpl, err := newGPUTemplateExecutor(name)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.RenderManifest(tmpl, c)
+}
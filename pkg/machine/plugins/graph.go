@@ -0,0 +1,306 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+// Dependent is implemented by installers requiring other plugins, dependencies are installed first and removed last.
+// kubeflow's own prerequisites (cert-manager, istio and dex) are part of its manifest and not separate plugins.
+type Dependent interface {
+	Dependencies(p Plugin) []TypePlugin
+}
+
+// Conflicter is implemented by installers which could not coexist with other plugins on a machine
+type Conflicter interface {
+	Conflicts(p Plugin) []TypePlugin
+}
+
+// Precursor is implemented by installers which other plugins make use of if they are added together, e.g. pvcs of kubeflow
+// are bound by the default storageclass of storage. Precursors are installed first and removed last, unlike dependencies
+// they are not required.
+type Precursor interface {
+	Precedes(p Plugin) []TypePlugin
+}
+
+// Embedder is implemented by installers which other installers run in process, e.g. helm installs charts of gpu and monitoring,
+// depending on them requires them to be registered only as nothing is installed on the machine for the dependency itself.
+type Embedder interface {
+	Embedded() bool
+}
+
+// NamespacesOwner is implemented by installers whose uninstallation deletes namespaces,
+// removal waits until those namespaces are gone so the next plugin doesn't race with their finalizers.
+// Namespaces are collected before uninstallation, so they could be read from what is installed on the machine.
+type NamespacesOwner interface {
+	OwnedNamespaces(m machine.MachineCURD, p Plugin) []string
+}
+
+var (
+	namespaceDeletionTimeout = 5 * time.Minute
+)
+
+// pluginNode is a requested plugin with its installer
+type pluginNode struct {
+	p         Plugin
+	installer Installer
+}
+
+func (n pluginNode) dependencies() []TypePlugin {
+	if dependent, ok := n.installer.(Dependent); ok {
+		return dependent.Dependencies(n.p)
+	}
+	return nil
+}
+
+func (n pluginNode) conflicts() []TypePlugin {
+	if conflicter, ok := n.installer.(Conflicter); ok {
+		return conflicter.Conflicts(n.p)
+	}
+	return nil
+}
+
+func (n pluginNode) precedes() []TypePlugin {
+	if precursor, ok := n.installer.(Precursor); ok {
+		return precursor.Precedes(n.p)
+	}
+	return nil
+}
+
+func (n pluginNode) ownedNamespaces(m machine.MachineCURD) []string {
+	if owner, ok := n.installer.(NamespacesOwner); ok {
+		return owner.OwnedNamespaces(m, n.p)
+	}
+	return nil
+}
+
+// isEmbedded returns whether the plugin type is registered and embedded by other installers
+func isEmbedded(t TypePlugin) bool {
+	installer, err := NewInstaller(t, log.NoopLogger{})
+	if err != nil {
+		return false
+	}
+	embedder, ok := installer.(Embedder)
+	return ok && embedder.Embedded()
+}
+
+// CheckGraph returns an error if plugins depend on, precede or conflict with plugin types which are not registered,
+// or depend on each other in a cycle, it is used to validate installers linked into the binary.
+func CheckGraph(logger log.Logger, plugins ...Plugin) error {
+	_, err := InstallOrder(logger, plugins...)
+	return err
+}
+
+// InstallOrder returns plugin types in the order AddPlugins installs plugins added together, RemovePlugins uses the reverse order
+func InstallOrder(logger log.Logger, plugins ...Plugin) ([]TypePlugin, error) {
+	var nodes []pluginNode
+	for _, p := range plugins {
+		installer, err := NewInstaller(p.PluginType(), logger)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, pluginNode{p: p, installer: installer})
+	}
+	for _, n := range nodes {
+		for _, t := range append(append(n.dependencies(), n.precedes()...), n.conflicts()...) {
+			if _, found := pluginRegister[t]; !found {
+				return nil, fmt.Errorf("plugins: %s refers to %s which is not registered", n.p.PluginType(), t)
+			}
+		}
+	}
+	sorted, err := sortByDependencies(nodes, nil)
+	if err != nil {
+		return nil, err
+	}
+	var types []TypePlugin
+	for _, n := range sorted {
+		types = append(types, n.p.PluginType())
+	}
+	return types, nil
+}
+
+// checkConflicts returns an error if a node conflicts with another node or with an installed plugin
+func checkConflicts(nodes []pluginNode, installed map[TypePlugin]bool) error {
+	requested := map[TypePlugin]bool{}
+	for _, n := range nodes {
+		requested[n.p.PluginType()] = true
+	}
+	for _, n := range nodes {
+		for _, conflict := range n.conflicts() {
+			if conflict == n.p.PluginType() {
+				continue
+			}
+			if requested[conflict] {
+				return fmt.Errorf("plugins: %s conflicts with %s, they could not be installed together", n.p.PluginType(), conflict)
+			}
+			if installed[conflict] {
+				return fmt.Errorf("plugins: %s conflicts with %s which is installed, remove %s first", n.p.PluginType(), conflict, conflict)
+			}
+		}
+	}
+	return nil
+}
+
+// sortByDependencies returns nodes in topological order so dependencies and precursors come first, the requested order is kept otherwise.
+// A dependency should be either embedded, requested or satisfied, missing dependencies and cycles are reported as errors.
+func sortByDependencies(nodes []pluginNode, satisfied map[TypePlugin]bool) ([]pluginNode, error) {
+	indicesOfType := map[TypePlugin][]int{}
+	for i, n := range nodes {
+		indicesOfType[n.p.PluginType()] = append(indicesOfType[n.p.PluginType()], i)
+	}
+	// edges[i] are nodes depending on node i
+	edges := make([][]int, len(nodes))
+	indegrees := make([]int, len(nodes))
+	var missing []string
+	for i, n := range nodes {
+		for _, dependency := range n.dependencies() {
+			if dependency == n.p.PluginType() || isEmbedded(dependency) {
+				continue
+			}
+			if indices, found := indicesOfType[dependency]; found {
+				for _, j := range indices {
+					edges[j] = append(edges[j], i)
+					indegrees[i]++
+				}
+				continue
+			}
+			if !satisfied[dependency] {
+				missing = append(missing, fmt.Sprintf("%s requires %s", n.p.PluginType(), dependency))
+			}
+		}
+		for _, follower := range n.precedes() {
+			if follower == n.p.PluginType() {
+				continue
+			}
+			for _, j := range indicesOfType[follower] {
+				edges[i] = append(edges[i], j)
+				indegrees[j]++
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("plugins: missing dependencies, %s, install them first or add them together", strings.Join(missing, ", "))
+	}
+	sorted := make([]pluginNode, 0, len(nodes))
+	done := make([]bool, len(nodes))
+	for len(sorted) < len(nodes) {
+		next := -1
+		for i := range nodes {
+			if !done[i] && indegrees[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("plugins: dependency cycle %s", findCycle(nodes, edges, done))
+		}
+		done[next] = true
+		sorted = append(sorted, nodes[next])
+		for _, j := range edges[next] {
+			indegrees[j]--
+		}
+	}
+	return sorted, nil
+}
+
+// findCycle returns a cycle among nodes not done yet, e.g. a -> b -> a
+func findCycle(nodes []pluginNode, edges [][]int, done []bool) string {
+	// dependsOn[i] are nodes which node i depends on
+	dependsOn := make([][]int, len(nodes))
+	for i, dependents := range edges {
+		for _, j := range dependents {
+			dependsOn[j] = append(dependsOn[j], i)
+		}
+	}
+	visiting := make([]bool, len(nodes))
+	var path []int
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		if visiting[i] {
+			for k, j := range path {
+				if j == i {
+					return append(append([]int{}, path[k:]...), i)
+				}
+			}
+		}
+		visiting[i] = true
+		path = append(path, i)
+		for _, j := range dependsOn[i] {
+			if done[j] {
+				continue
+			}
+			if cycle := visit(j); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		return nil
+	}
+	for i := range nodes {
+		if done[i] {
+			continue
+		}
+		if cycle := visit(i); cycle != nil {
+			var names []string
+			for _, j := range cycle {
+				names = append(names, nodes[j].p.PluginType().String())
+			}
+			return strings.Join(names, " -> ")
+		}
+	}
+	return ""
+}
+
+// installedNodes returns plugins recorded as installed in the machine state
+func installedNodes(logger log.Logger, state *machine.MachineState) []pluginNode {
+	var nodes []pluginNode
+	for _, recorded := range state.Plugins {
+		if recorded.Status != RecordStatusInstalled {
+			continue
+		}
+		installer, err := NewInstaller(TypePlugin(recorded.Name), logger)
+		if err != nil {
+			// plugins which are no longer available are not part of the graph
+			continue
+		}
+		nodes = append(nodes, pluginNode{
+			p:         NewPlugin(TypePlugin(recorded.Name), NewTypePluginVersion(recorded.Version), recorded.Params),
+			installer: installer,
+		})
+	}
+	return nodes
+}
+
+// typesOf returns plugin types of nodes as a set
+func typesOf(nodes []pluginNode) map[TypePlugin]bool {
+	types := map[TypePlugin]bool{}
+	for _, n := range nodes {
+		types[n.p.PluginType()] = true
+	}
+	return types
+}
+
+// checkRequiredBy returns an error if remaining plugins depend on a plugin type which no longer exists after removal
+func checkRequiredBy(removing []pluginNode, remaining []pluginNode) error {
+	remainingTypes, removingTypes := typesOf(remaining), typesOf(removing)
+	var required []string
+	for _, n := range remaining {
+		for _, dependency := range n.dependencies() {
+			if remainingTypes[dependency] || !removingTypes[dependency] || isEmbedded(dependency) {
+				continue
+			}
+			required = append(required, fmt.Sprintf("%s is required by %s", dependency, n.p.PluginType()))
+		}
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		return fmt.Errorf("plugins: could not remove, %s, remove them together", strings.Join(required, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+// fakeGraphInstaller records installations and removals into a shared log
+type fakeGraphInstaller struct {
+	fakeInstaller
+	name      string
+	requires  []TypePlugin
+	precedes  []TypePlugin
+	conflicts []TypePlugin
+	embedded  bool
+	events    *[]string
+}
+
+func (f *fakeGraphInstaller) Dependencies(p Plugin) []TypePlugin { return f.requires }
+func (f *fakeGraphInstaller) Precedes(p Plugin) []TypePlugin     { return f.precedes }
+func (f *fakeGraphInstaller) Conflicts(p Plugin) []TypePlugin    { return f.conflicts }
+func (f *fakeGraphInstaller) Embedded() bool                     { return f.embedded }
+func (f *fakeGraphInstaller) Install(m machine.MachineCURD, p Plugin) error {
+	*f.events = append(*f.events, "+"+f.name)
+	return nil
+}
+func (f *fakeGraphInstaller) Uninstall(m machine.MachineCURD, p Plugin) error {
+	*f.events = append(*f.events, "-"+f.name)
+	return nil
+}
+
+func registerGraphInstallers(t *testing.T, events *[]string, installers map[TypePlugin]*fakeGraphInstaller) {
+	for typePlugin, installer := range installers {
+		installer.name, installer.events = typePlugin.String(), events
+		installer := installer
+		assert.NoError(t, RegisterPlugin(typePlugin, func(log.Logger) Installer { return installer }))
+	}
+	t.Cleanup(func() {
+		for typePlugin := range installers {
+			delete(pluginRegister, typePlugin)
+		}
+	})
+}
+
+func TestPluginsInDependencyOrder(t *testing.T) {
+	var events []string
+	registerGraphInstallers(t, &events, map[TypePlugin]*fakeGraphInstaller{
+		"g-app":     {requires: []TypePlugin{"g-mesh", "g-certs"}},
+		"g-mesh":    {requires: []TypePlugin{"g-certs"}},
+		"g-certs":   {},
+		"g-rival":   {conflicts: []TypePlugin{"g-mesh"}},
+		"g-orphan":  {requires: []TypePlugin{"g-missing"}},
+		"g-cycle-a": {requires: []TypePlugin{"g-cycle-b"}},
+		"g-cycle-b": {requires: []TypePlugin{"g-cycle-a"}},
+		"g-chart":   {requires: []TypePlugin{"g-helm"}},
+		"g-helm":    {embedded: true},
+		"g-disk":    {precedes: []TypePlugin{"g-app", "g-db"}},
+		"g-db":      {},
+	})
+	m := fakeMachine{hostDir: t.TempDir()}
+
+	err := AddPlugins(log.NoopLogger{}, m, NewPlugin("g-app", "", nil), NewPlugin("g-mesh", "", nil))
+	assert.ErrorContains(t, err, "g-app requires g-certs, g-mesh requires g-certs")
+	err = AddPlugins(log.NoopLogger{}, m, NewPlugin("g-cycle-a", "", nil), NewPlugin("g-cycle-b", "", nil))
+	assert.ErrorContains(t, err, "dependency cycle g-cycle-a -> g-cycle-b -> g-cycle-a")
+	err = AddPlugins(log.NoopLogger{}, m, NewPlugin("g-orphan", "", nil))
+	assert.ErrorContains(t, err, "g-orphan requires g-missing")
+	assert.Empty(t, events, "nothing is installed on errors")
+
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-app", "", nil), NewPlugin("g-mesh", "", nil), NewPlugin("g-certs", "", nil)))
+	assert.EqualValues(t, []string{"+g-certs", "+g-mesh", "+g-app"}, events)
+
+	err = AddPlugins(log.NoopLogger{}, m, NewPlugin("g-rival", "", nil))
+	assert.ErrorContains(t, err, "g-rival conflicts with g-mesh which is installed")
+
+	err = RemovePlugins(log.NoopLogger{}, m, NewPlugin("g-certs", "", nil))
+	assert.ErrorContains(t, err, "g-certs is required by g-app, g-certs is required by g-mesh")
+
+	events = nil
+	assert.NoError(t, RemovePlugins(log.NoopLogger{}, m, NewPlugin("g-certs", "", nil), NewPlugin("g-app", "", nil), NewPlugin("g-mesh", "", nil)))
+	assert.EqualValues(t, []string{"-g-app", "-g-mesh", "-g-certs"}, events)
+	installed, err := InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.Empty(t, installed)
+
+	// installed dependencies are satisfied
+	events = nil
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-certs", "", nil)))
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-mesh", "", nil)))
+	assert.EqualValues(t, []string{"+g-certs", "+g-mesh"}, events)
+
+	// embedded dependencies are satisfied by being registered
+	events = nil
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-chart", "", nil)))
+	assert.NoError(t, RemovePlugins(log.NoopLogger{}, m, NewPlugin("g-chart", "", nil)))
+	assert.EqualValues(t, []string{"+g-chart", "-g-chart"}, events)
+	assert.NoError(t, CheckGraph(log.NoopLogger{}, NewPlugin("g-chart", "", nil), NewPlugin("g-mesh", "", nil), NewPlugin("g-certs", "", nil)))
+	assert.ErrorContains(t, CheckGraph(log.NoopLogger{}, NewPlugin("g-orphan", "", nil)), "g-orphan refers to g-missing which is not registered")
+
+	// precursors go first if they are added together, and are not required otherwise
+	events = nil
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-db", "", nil), NewPlugin("g-disk", "", nil)))
+	assert.NoError(t, RemovePlugins(log.NoopLogger{}, m, NewPlugin("g-db", "", nil), NewPlugin("g-disk", "", nil)))
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin("g-db", "", nil)))
+	assert.EqualValues(t, []string{"+g-disk", "+g-db", "-g-db", "-g-disk", "+g-db"}, events)
+	order, err := InstallOrder(log.NoopLogger{}, NewPlugin("g-db", "", nil), NewPlugin("g-certs", "", nil), NewPlugin("g-disk", "", nil))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []TypePlugin{"g-certs", "g-disk", "g-db"}, order)
+}
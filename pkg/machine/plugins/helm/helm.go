@@ -0,0 +1,305 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+const (
+	TypePluginHelm plugins.TypePlugin = "helm"
+
+	// ParamRelease is the release name, required
+	ParamRelease = "release"
+	// ParamChart is a local chart (.tgz or directory), a chart name in ParamRepo, or an oci:// reference
+	ParamChart = "chart"
+	// ParamRepo is the chart repository url
+	ParamRepo = "repo"
+	// ParamNamespace is the namespace of the release (default: same as release name)
+	ParamNamespace = "namespace"
+	// ParamValues are values files encoded by plugins.ListParam
+	ParamValues = "values"
+	// ParamSet are value overrides in helm's --set format, e.g. a=1,b.c=2
+	ParamSet = "set"
+)
+
+var (
+	helmTimeout = 10 * time.Minute
+)
+
+func init() {
+	plugins.RegisterPlugin(TypePluginHelm, newHelmInstaller)
+}
+
+func newHelmInstaller(logger log.Logger) plugins.Installer {
+	return &helmInstaller{logger: logger}
+}
+
+type helmInstaller struct {
+	logger log.Logger
+}
+
+var (
+	_ plugins.Installer = &helmInstaller{}
+	_ plugins.Embedder  = &helmInstaller{}
+)
+
+func (h *helmInstaller) Description() string {
+	return "helm chart from a local .tgz/directory or a repository, plugin version stands for the chart version"
+}
+
+// Versions returns nothing as versions are chart specific
+func (h *helmInstaller) Versions() []plugins.TypePluginVersion {
+	return nil
+}
+
+// SecretParams excludes --set values from the plugin record as they may carry credentials,
+// they are kept in the helm release record for upgrades.
+func (h *helmInstaller) SecretParams() []string {
+	return []string{ParamSet}
+}
+
+// Embedded marks helm as run in process by gpu and monitoring, which depend on it without installing any release of their own
+func (h *helmInstaller) Embedded() bool {
+	return true
+}
+
+// InstanceName returns the release name so each release is recorded separately
+func (h *helmInstaller) InstanceName(p plugins.Plugin) string {
+	return plugins.GetParam(p, ParamRelease, "")
+}
+
+// releaseFromPlugin builds the release from plugin parameters, unspecified fields fall back to the recorded release,
+// so a release could be upgraded with only changed parameters.
+func releaseFromPlugin(p plugins.Plugin, recorded *machine.HelmRelease) (machine.HelmRelease, error) {
+	valuesFiles, err := plugins.GetListParam(p, ParamValues)
+	if err != nil {
+		return machine.HelmRelease{}, err
+	}
+	r := machine.HelmRelease{
+		Name:         plugins.GetParam(p, ParamRelease, ""),
+		Namespace:    plugins.GetParam(p, ParamNamespace, ""),
+		Chart:        plugins.GetParam(p, ParamChart, ""),
+		Repo:         plugins.GetParam(p, ParamRepo, ""),
+		ChartVersion: p.PluginVersion().String(),
+		ValuesFiles:  valuesFiles,
+		Sets:         plugins.GetParam(p, ParamSet, ""),
+	}
+	if r.Name == "" {
+		return r, errors.New("helm: release is required, e.g. --set release=<name>")
+	}
+	if recorded != nil {
+		if r.Chart == "" {
+			r.Chart, r.Repo = recorded.Chart, recorded.Repo
+		}
+		if r.Namespace == "" {
+			r.Namespace = recorded.Namespace
+		}
+		if r.ChartVersion == "" {
+			r.ChartVersion = recorded.ChartVersion
+		}
+		if len(r.ValuesFiles) == 0 {
+			r.ValuesFiles = recorded.ValuesFiles
+		}
+		if r.Sets == "" {
+			r.Sets = recorded.Sets
+		}
+	}
+	if r.Chart == "" {
+		return r, errors.New("helm: chart is required, e.g. --set chart=./mychart.tgz")
+	}
+	if r.Namespace == "" {
+		r.Namespace = r.Name
+	}
+	// local charts and values files are resolved now as later upgrades may run in another working directory
+	if isLocalChart(r) {
+		abs, err := filepath.Abs(r.Chart)
+		if err != nil {
+			return r, err
+		}
+		r.Chart = abs
+	}
+	for i, valuesFile := range r.ValuesFiles {
+		abs, err := filepath.Abs(valuesFile)
+		if err != nil {
+			return r, err
+		}
+		r.ValuesFiles[i] = abs
+	}
+	return r, nil
+}
+
+// isLocalChart returns true if the chart is a .tgz or directory on disk rather than a chart in a repository
+func isLocalChart(r machine.HelmRelease) bool {
+	if r.Repo != "" || strings.Contains(r.Chart, "://") {
+		return false
+	}
+	_, err := os.Stat(r.Chart)
+	return err == nil
+}
+
+func newSettings(m machine.MachineCURD, namespace string) *cli.EnvSettings {
+	settings := cli.New()
+	settings.KubeConfig = m.GetKubeConfig()
+	settings.SetNamespace(namespace)
+	return settings
+}
+
+func (h *helmInstaller) newActionConfig(settings *cli.EnvSettings) (*action.Configuration, error) {
+	actionConfig := &action.Configuration{}
+	debugLog := func(format string, v ...interface{}) {
+		h.logger.V(1).Infof("helm: "+format+"\n", v...)
+	}
+	if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), "secret", debugLog); err != nil {
+		return nil, err
+	}
+	registryClient, err := registry.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	actionConfig.RegistryClient = registryClient
+	return actionConfig, nil
+}
+
+// loadChart locates (downloads if needed) and loads the chart with its values merged
+func loadChart(settings *cli.EnvSettings, pathOptions *action.ChartPathOptions, r machine.HelmRelease) (*chart.Chart, map[string]interface{}, error) {
+	pathOptions.RepoURL = r.Repo
+	pathOptions.Version = r.ChartVersion
+	chartPath, err := pathOptions.LocateChart(r.Chart, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	valueOpts := &values.Options{ValueFiles: r.ValuesFiles}
+	if r.Sets != "" {
+		valueOpts.Values = []string{r.Sets}
+	}
+	vals, err := valueOpts.MergeValues(getter.All(settings))
+	if err != nil {
+		return nil, nil, err
+	}
+	return chrt, vals, nil
+}
+
+// Install installs the release, or upgrades it if the release exists
+func (h *helmInstaller) Install(m machine.MachineCURD, p plugins.Plugin) error {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return err
+	}
+	r, err := releaseFromPlugin(p, state.FindHelmRelease(plugins.GetParam(p, ParamRelease, "")))
+	if err != nil {
+		return err
+	}
+	settings := newSettings(m, r.Namespace)
+	actionConfig, err := h.newActionConfig(settings)
+	if err != nil {
+		return err
+	}
+	_, err = action.NewHistory(actionConfig).Run(r.Name)
+	switch {
+	case errors.Is(err, driver.ErrReleaseNotFound):
+		install := action.NewInstall(actionConfig)
+		install.ReleaseName = r.Name
+		install.Namespace = r.Namespace
+		install.CreateNamespace = true
+		install.Wait = true
+		install.Timeout = helmTimeout
+		chrt, vals, err := loadChart(settings, &install.ChartPathOptions, r)
+		if err != nil {
+			return err
+		}
+		h.logger.V(0).Infof("helm: install release %s with chart %s in namespace %s\n", r.Name, chrt.Metadata.Name, r.Namespace)
+		if _, err := install.Run(chrt, vals); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		upgrade := action.NewUpgrade(actionConfig)
+		upgrade.Namespace = r.Namespace
+		upgrade.Wait = true
+		upgrade.Timeout = helmTimeout
+		chrt, vals, err := loadChart(settings, &upgrade.ChartPathOptions, r)
+		if err != nil {
+			return err
+		}
+		h.logger.V(0).Infof("helm: upgrade release %s with chart %s in namespace %s\n", r.Name, chrt.Metadata.Name, r.Namespace)
+		if _, err := upgrade.Run(r.Name, chrt, vals); err != nil {
+			return err
+		}
+	}
+	r.UpdatedAt = time.Now().UTC()
+	state.PutHelmRelease(r)
+	return state.Save(m.HostDir())
+}
+
+func (h *helmInstaller) Uninstall(m machine.MachineCURD, p plugins.Plugin) error {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return err
+	}
+	name := plugins.GetParam(p, ParamRelease, "")
+	recorded := state.FindHelmRelease(name)
+	if recorded == nil {
+		return fmt.Errorf("helm: release %q is not installed by multikf", name)
+	}
+	actionConfig, err := h.newActionConfig(newSettings(m, recorded.Namespace))
+	if err != nil {
+		return err
+	}
+	uninstall := action.NewUninstall(actionConfig)
+	uninstall.Wait = true
+	uninstall.Timeout = helmTimeout
+	h.logger.V(0).Infof("helm: uninstall release %s in namespace %s\n", recorded.Name, recorded.Namespace)
+	if _, err := uninstall.Run(recorded.Name); err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return err
+	}
+	state.RemoveHelmRelease(name)
+	return state.Save(m.HostDir())
+}
+
+// Status reports ready only if all recorded releases are deployed
+func (h *helmInstaller) Status(m machine.MachineCURD) (plugins.Status, error) {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return "", err
+	}
+	if len(state.HelmReleases) == 0 {
+		return plugins.StatusNotInstalled, nil
+	}
+	for _, r := range state.HelmReleases {
+		actionConfig, err := h.newActionConfig(newSettings(m, r.Namespace))
+		if err != nil {
+			return "", err
+		}
+		rel, err := action.NewStatus(actionConfig).Run(r.Name)
+		if err != nil {
+			return "", err
+		}
+		if rel.Info.Status != release.StatusDeployed {
+			return plugins.StatusNotReady, nil
+		}
+	}
+	return plugins.StatusReady, nil
+}
@@ -0,0 +1,93 @@
+package helm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+func TestReleaseFromPlugin(t *testing.T) {
+	_, err := releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "", map[string]string{ParamChart: "./chart"}), nil)
+	assert.Error(t, err)
+	_, err = releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "", map[string]string{ParamRelease: "foo"}), nil)
+	assert.Error(t, err)
+
+	r, err := releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "1.2.3", map[string]string{
+		ParamRelease: "foo",
+		ParamChart:   "bar",
+		ParamRepo:    "https://charts.example.com",
+		ParamValues:  "a.yaml, b.yaml",
+		ParamSet:     "replicaCount=2",
+	}), nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "foo", r.Namespace)
+	assert.EqualValues(t, "1.2.3", r.ChartVersion)
+	assert.Len(t, r.ValuesFiles, 2)
+	assert.True(t, filepath.IsAbs(r.ValuesFiles[0]))
+
+	// upgrade with recorded chart and values
+	upgraded, err := releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "", map[string]string{
+		ParamRelease: "foo",
+		ParamSet:     "replicaCount=3",
+	}), &r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "bar", upgraded.Chart)
+	assert.EqualValues(t, "https://charts.example.com", upgraded.Repo)
+	assert.EqualValues(t, "1.2.3", upgraded.ChartVersion)
+	assert.EqualValues(t, r.ValuesFiles, upgraded.ValuesFiles)
+	assert.EqualValues(t, "replicaCount=3", upgraded.Sets)
+
+	// values files with comma in path
+	withComma, err := releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "", map[string]string{
+		ParamRelease: "foo",
+		ParamChart:   "bar",
+		ParamValues:  plugins.ListParam("/tmp/a,b.yaml", "/tmp/c.yaml"),
+	}), nil)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"/tmp/a,b.yaml", "/tmp/c.yaml"}, withComma.ValuesFiles)
+
+	// local chart is recorded with its absolute path
+	chartDir, err := os.MkdirTemp(".", "chart")
+	assert.NoError(t, err)
+	defer os.RemoveAll(chartDir)
+	local, err := releaseFromPlugin(plugins.NewPlugin(TypePluginHelm, "", map[string]string{
+		ParamRelease: "foo",
+		ParamChart:   chartDir,
+	}), nil)
+	assert.NoError(t, err)
+	abs, err := filepath.Abs(chartDir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, abs, local.Chart)
+}
+
+func TestLoadLocalChart(t *testing.T) {
+	dir := t.TempDir()
+	chartDir := filepath.Join(dir, "hello")
+	assert.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: hello\nversion: 0.1.0\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(chartDir, "values.yaml"), []byte("replicaCount: 1\nimage: nginx\n"), 0644))
+	valuesFile := filepath.Join(dir, "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesFile, []byte("image: busybox\n"), 0644))
+
+	r := machine.HelmRelease{Name: "hello", Namespace: "hello", Chart: chartDir, ValuesFiles: []string{valuesFile}, Sets: "replicaCount=2"}
+	chrt, vals, err := loadChart(cli.New(), &action.ChartPathOptions{}, r)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "hello", chrt.Metadata.Name)
+	assert.EqualValues(t, map[string]interface{}{"image": "busybox", "replicaCount": int64(2)}, vals)
+
+	state := &machine.MachineState{}
+	state.PutHelmRelease(r)
+	r.Sets = "replicaCount=3"
+	state.PutHelmRelease(r)
+	assert.Len(t, state.HelmReleases, 1)
+	assert.EqualValues(t, "replicaCount=3", state.FindHelmRelease("hello").Sets)
+	state.RemoveHelmRelease("hello")
+	assert.Nil(t, state.FindHelmRelease("hello"))
+}
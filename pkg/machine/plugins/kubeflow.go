@@ -0,0 +1,420 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	kfmanifests "github.com/footprintai/multikf/kfmanifests"
+	"github.com/footprintai/multikf/pkg/kustomize"
+	"github.com/footprintai/multikf/pkg/machine"
+	kubeflowplugin "github.com/footprintai/multikf/pkg/machine/plugins/kubeflow"
+	"github.com/footprintai/multikf/pkg/machine/plugins/source"
+	"github.com/footprintai/multikf/pkg/template"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+)
+
+const (
+	// KubeflowParamPassword is the password of kubeflow's default user
+	KubeflowParamPassword = "password"
+	// KubeflowParamOverlay is a kustomize overlay directory applied on top of the kubeflow manifest,
+	// the overlay refers the manifest as `../base` in its resources
+	KubeflowParamOverlay = "overlay"
+	// KubeflowParamComponents is a profile (full, lite, pipelines-only) or a comma delimited component list,
+	// e.g. pipelines,notebooks,katib,training-operator,kserve (default: full)
+	KubeflowParamComponents = "components"
+	// KubeflowParamAuthServicePVCSize is the pvc size of authservice in GiB
+	KubeflowParamAuthServicePVCSize = "authservice_pvc_size"
+	// KubeflowParamKatibMySQLPVCSize is the pvc size of katib's mysql in GiB
+	KubeflowParamKatibMySQLPVCSize = "katib_mysql_pvc_size"
+	// KubeflowParamPipelineMinioPVCSize is the pvc size of pipeline's minio in GiB
+	KubeflowParamPipelineMinioPVCSize = "pipeline_minio_pvc_size"
+	// KubeflowParamPipelineMySQLPVCSize is the pvc size of pipeline's mysql in GiB
+	KubeflowParamPipelineMySQLPVCSize = "pipeline_mysql_pvc_size"
+
+	kubeflowDefaultPassword = "12341234"
+	kubeflowNamespace       = "kubeflow"
+
+	// kubeflowAppliedManifestFile keeps the manifest applied by the last install or upgrade,
+	// uninstall deletes objects from it instead of re-rendering a manifest which may differ.
+	kubeflowAppliedManifestFile = "kubeflow-applied.yaml"
+	kubeflowUpgradeApplyFile    = "kubeflow-upgrade-apply.yaml"
+	kubeflowUpgradePruneFile    = "kubeflow-upgrade-prune.yaml"
+)
+
+// kubeflowDatabaseDeployments are mysql deployments exported before upgrading
+var kubeflowDatabaseDeployments = []string{"mysql", "katib-mysql"}
+
+func init() {
+	RegisterPlugin(TypePluginKubeflow, newKubeflowInstaller)
+}
+
+func newKubeflowInstaller(logger log.Logger) Installer {
+	return &kubeflowInstaller{logger: logger}
+}
+
+type kubeflowInstaller struct {
+	logger log.Logger
+}
+
+var (
+	_ Installer       = &kubeflowInstaller{}
+	_ Upgrader        = &kubeflowInstaller{}
+	_ NamespacesOwner = &kubeflowInstaller{}
+	_ SourceSupporter = &kubeflowInstaller{}
+)
+
+// KubeflowPVCSizeParams lists parameters of kubeflow's pvc sizes
+func KubeflowPVCSizeParams() []string {
+	return []string{
+		KubeflowParamAuthServicePVCSize,
+		KubeflowParamKatibMySQLPVCSize,
+		KubeflowParamPipelineMinioPVCSize,
+		KubeflowParamPipelineMySQLPVCSize,
+	}
+}
+
+// kubeflowConfig feeds the plugin's parameters to the kubeflow template
+type kubeflowConfig struct {
+	p        Plugin
+	pvcSizes map[string]int
+}
+
+func newKubeflowConfig(p Plugin) (kubeflowConfig, error) {
+	pvcSizes := map[string]int{}
+	for _, param := range KubeflowPVCSizeParams() {
+		value := GetParam(p, param, "")
+		if value == "" {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(value, "Gi"))
+		if err != nil || size <= 0 {
+			return kubeflowConfig{}, fmt.Errorf("plugins: invalid %s %q, expect a positive size in GiB", param, value)
+		}
+		pvcSizes[param] = size
+	}
+	return kubeflowConfig{p: p, pvcSizes: pvcSizes}, nil
+}
+
+func (k kubeflowConfig) GetDefaultPassword() string {
+	return GetParam(k.p, KubeflowParamPassword, kubeflowDefaultPassword)
+}
+
+func (k kubeflowConfig) GetAuthServicePVCSizeInG() int {
+	return k.pvcSizes[KubeflowParamAuthServicePVCSize]
+}
+
+func (k kubeflowConfig) GetKatibMySQLPVCSizeInG() int {
+	return k.pvcSizes[KubeflowParamKatibMySQLPVCSize]
+}
+
+func (k kubeflowConfig) GetPipelineMinioPVCSizeInG() int {
+	return k.pvcSizes[KubeflowParamPipelineMinioPVCSize]
+}
+
+func (k kubeflowConfig) GetPipelineMySQLPVCSizeInG() int {
+	return k.pvcSizes[KubeflowParamPipelineMySQLPVCSize]
+}
+
+func (k *kubeflowInstaller) Description() string {
+	return "kubeflow with dex authentication, pipelines, notebooks and katib"
+}
+
+func (k *kubeflowInstaller) Versions() []TypePluginVersion {
+	var versions []TypePluginVersion
+	for _, version := range kfmanifests.ListVersions() {
+		versions = append(versions, NewTypePluginVersion(version))
+	}
+	return versions
+}
+
+// SecretParams excludes the default user's password from the machine state
+func (k *kubeflowInstaller) SecretParams() []string {
+	return []string{KubeflowParamPassword}
+}
+
+// PathParams records the overlay and a local source with their absolute paths
+func (k *kubeflowInstaller) PathParams() []string {
+	return []string{KubeflowParamOverlay, source.ParamLocation}
+}
+
+// generateManifest renders the kubeflow manifest of the plugin's version under the machine's HostDir
+func (k *kubeflowInstaller) generateManifest(m machine.MachineCURD, p Plugin) (string, error) {
+	version := p.PluginVersion().String()
+	manifests, err := k.loadManifests(m, p)
+	if err != nil {
+		return "", err
+	}
+	var tmpl template.TemplateExecutor = kubeflowplugin.NewKubeflowTemplateExecutor(kfmanifests.VersionBaseFileName(version), manifests)
+	config, err := newKubeflowConfig(p)
+	if err != nil {
+		return "", err
+	}
+	components, err := kubeflowplugin.ParseComponents(GetParam(p, KubeflowParamComponents, "full"))
+	if err != nil {
+		return "", err
+	}
+	memFs := templatefs.NewMemoryFilesFs()
+	if err := memFs.Generate(config, tmpl); err != nil {
+		return "", err
+	}
+	if err := templatefs.NewFolder(m.HostDir()).DumpFiles(true, memFs.FS()); err != nil {
+		return "", err
+	}
+	manifestFile := filepath.Join(m.HostDir(), tmpl.Filename())
+	if overlay := GetParam(p, KubeflowParamOverlay, ""); overlay != "" {
+		if manifestFile, err = k.buildOverlay(m, manifestFile, overlay); err != nil {
+			return "", err
+		}
+	}
+	if kubeflowplugin.IsFull(components) {
+		return manifestFile, nil
+	}
+	return k.filterComponents(manifestFile, components)
+}
+
+// loadManifests returns the manifest template of the plugin's source if specified, otherwise the embedded one of its version
+func (k *kubeflowInstaller) loadManifests(m machine.MachineCURD, p Plugin) (string, error) {
+	location := GetParam(p, source.ParamLocation, "")
+	if location == "" {
+		manifests, err := kfmanifests.GetVersion(p.PluginVersion().String())
+		if err != nil {
+			return "", errors.New("plugins: no version found")
+		}
+		return manifests, nil
+	}
+	// sources are cached under multikf's root dir, which is the parent of machines' folders
+	cacheDir := filepath.Join(filepath.Dir(m.HostDir()), source.CacheDirName)
+	dir, err := source.Fetch(k.logger, cacheDir, source.Source{Location: location, SHA256: GetParam(p, source.ParamSHA256, "")})
+	if err != nil {
+		return "", err
+	}
+	return source.ReadManifests(dir)
+}
+
+// SupportsSource allows kubeflow releases not embedded in multikf
+func (k *kubeflowInstaller) SupportsSource() bool {
+	return true
+}
+
+// filterComponents keeps resources of the components only, the file name is derived from components
+// so removal deletes exactly the same resources.
+func (k *kubeflowInstaller) filterComponents(manifestFile string, components []kubeflowplugin.Component) (string, error) {
+	manifest, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, c := range components {
+		names = append(names, c.String())
+	}
+	k.logger.V(0).Infof("plugins: select kubeflow components %s\n", strings.Join(names, ","))
+	filtered, err := kubeflowplugin.FilterManifest(manifest, components)
+	if err != nil {
+		return "", err
+	}
+	filteredFile := strings.TrimSuffix(manifestFile, filepath.Ext(manifestFile)) + "-" + strings.Join(names, "_") + ".yaml"
+	if err := os.WriteFile(filteredFile, filtered, 0644); err != nil {
+		return "", err
+	}
+	return filteredFile, nil
+}
+
+// buildOverlay builds the user's kustomize overlay against the rendered manifest
+func (k *kubeflowInstaller) buildOverlay(m machine.MachineCURD, manifestFile string, overlay string) (string, error) {
+	base, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return "", err
+	}
+	k.logger.V(0).Infof("plugins: build kubeflow manifest with overlay %s\n", overlay)
+	built, err := kustomize.BuildOverlay(base, overlay)
+	if err != nil {
+		return "", err
+	}
+	overlayFile := strings.TrimSuffix(manifestFile, filepath.Ext(manifestFile)) + "-overlay.yaml"
+	if err := os.WriteFile(overlayFile, built, 0644); err != nil {
+		return "", err
+	}
+	return overlayFile, nil
+}
+
+// saveAppliedManifest copies the manifest into HostDir before it is applied, so a partially applied one is removable as well
+func (k *kubeflowInstaller) saveAppliedManifest(m machine.MachineCURD, manifestFile string) error {
+	manifest, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.HostDir(), kubeflowAppliedManifestFile), manifest, 0644)
+}
+
+// appliedManifestFile returns the manifest saved by install or upgrade, machines installed by previous versions
+// of multikf have none and the manifest is rendered from the plugin instead.
+func (k *kubeflowInstaller) appliedManifestFile(m machine.MachineCURD, p Plugin) (string, error) {
+	appliedFile := filepath.Join(m.HostDir(), kubeflowAppliedManifestFile)
+	if _, err := os.Stat(appliedFile); err == nil {
+		return appliedFile, nil
+	}
+	k.logger.Warnf("plugins: %s not found, render kubeflow %s instead\n", appliedFile, p.PluginVersion())
+	return k.generateManifest(m, p)
+}
+
+func (k *kubeflowInstaller) Install(m machine.MachineCURD, p Plugin) error {
+	manifestFile, err := k.generateManifest(m, p)
+	if err != nil {
+		return err
+	}
+	if err := k.saveAppliedManifest(m, manifestFile); err != nil {
+		return err
+	}
+	return m.GetKubeCli().InstallKubeflow(m.GetKubeConfig(), manifestFile)
+}
+
+func (k *kubeflowInstaller) Uninstall(m machine.MachineCURD, p Plugin) error {
+	manifestFile, err := k.appliedManifestFile(m, p)
+	if err != nil {
+		return err
+	}
+	if err := m.GetKubeCli().RemoveKubeflow(m.GetKubeConfig(), manifestFile); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(m.HostDir(), kubeflowAppliedManifestFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Upgrade diffs manifests of both versions, applies the new manifest in dependency order and prunes objects
+// which are gone, pvcs and profiles are left as is and dex's static users are carried into the new dex config.
+func (k *kubeflowInstaller) Upgrade(m machine.MachineCURD, from Plugin, to Plugin, opts UpgradeOptions) error {
+	oldManifestFile, err := k.appliedManifestFile(m, from)
+	if err != nil {
+		return err
+	}
+	newManifestFile, err := k.generateManifest(m, to)
+	if err != nil {
+		return err
+	}
+	oldManifest, err := os.ReadFile(oldManifestFile)
+	if err != nil {
+		return err
+	}
+	newManifest, err := os.ReadFile(newManifestFile)
+	if err != nil {
+		return err
+	}
+	plan, err := kubeflowplugin.NewUpgradePlan(oldManifest, newManifest)
+	if err != nil {
+		return err
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	users := kubeflowplugin.NewUserManager(k.logger, kubecli, kubeConfig, m.HostDir())
+	liveDexConfig, err := users.DexConfig()
+	if err != nil {
+		return fmt.Errorf("plugins: read dex config failed, err:%+v", err)
+	}
+	withDex, err := plan.CarryDexUsers(liveDexConfig)
+	if err != nil {
+		return err
+	}
+	if opts.Out != nil {
+		if err := plan.Write(opts.Out); err != nil {
+			return err
+		}
+	}
+	applyFile := filepath.Join(m.HostDir(), kubeflowUpgradeApplyFile)
+	if err := os.WriteFile(applyFile, plan.ApplyManifest(), 0644); err != nil {
+		return err
+	}
+	pruneFile := filepath.Join(m.HostDir(), kubeflowUpgradePruneFile)
+	if err := os.WriteFile(pruneFile, plan.PruneManifest(opts.PruneCascading), 0644); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		k.logger.V(0).Infof("plugins: dry run, manifests are written to %s and %s\n", applyFile, pruneFile)
+		return nil
+	}
+	if opts.ExportDB {
+		if err := k.exportDatabases(m); err != nil {
+			return err
+		}
+	}
+	if err := kubecli.InstallKubeflow(kubeConfig, applyFile); err != nil {
+		return err
+	}
+	if len(plan.Cascading) > 0 && !opts.PruneCascading {
+		k.logger.Warnf("plugins: %d namespaces and crds removed in %s are left, pass --prune_cascading to prune them with objects inside\n", len(plan.Cascading), to.PluginVersion())
+	}
+	if len(plan.PruneManifest(opts.PruneCascading)) > 0 {
+		k.logger.V(0).Infof("plugins: prune objects removed in %s\n", to.PluginVersion())
+		if err := kubecli.Delete(kubeConfig, pruneFile); err != nil {
+			return err
+		}
+	}
+	if withDex {
+		if err := users.RestartDex(); err != nil {
+			return err
+		}
+	}
+	// the old manifest is kept until the upgrade succeeds, so a failed upgrade could be retried or removed
+	return k.saveAppliedManifest(m, newManifestFile)
+}
+
+// exportDatabases dumps mysql databases of pipelines and katib into HostDir/backup-<timestamp>/<deployment>.sql,
+// deployments not installed (e.g. excluded components) are skipped.
+func (k *kubeflowInstaller) exportDatabases(m machine.MachineCURD) error {
+	backupDir := filepath.Join(m.HostDir(), "backup-"+time.Now().Format("20060102150405"))
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	for _, deployment := range kubeflowDatabaseDeployments {
+		if _, err := kubecli.Output(kubeConfig, "get", "deployment", deployment, "-n", kubeflowNamespace); err != nil {
+			k.logger.V(0).Infof("plugins: skip exporting %s, not installed\n", deployment)
+			continue
+		}
+		dump, err := kubecli.Exec(kubeConfig, kubeflowNamespace, "deploy/"+deployment,
+			"sh", "-c", "mysqldump -u root ${MYSQL_ROOT_PASSWORD:+-p$MYSQL_ROOT_PASSWORD} --all-databases")
+		if err != nil {
+			return fmt.Errorf("plugins: export databases of %s failed, err:%+v", deployment, err)
+		}
+		dumpFile := filepath.Join(backupDir, deployment+".sql")
+		if err := os.WriteFile(dumpFile, dump, 0600); err != nil {
+			return err
+		}
+		k.logger.V(0).Infof("plugins: databases of %s are exported to %s\n", deployment, dumpFile)
+	}
+	return nil
+}
+
+// OwnedNamespaces returns namespaces created by the applied manifest, e.g. istio-system, auth, cert-manager and knative-*,
+// kubeflow's namespace is returned only if the manifest is unreadable.
+func (k *kubeflowInstaller) OwnedNamespaces(m machine.MachineCURD, p Plugin) []string {
+	namespaces, err := k.appliedNamespaces(m, p)
+	if err != nil {
+		k.logger.Warnf("plugins: unable to read kubeflow's namespaces, err:%+v\n", err)
+		return []string{kubeflowNamespace}
+	}
+	return namespaces
+}
+
+func (k *kubeflowInstaller) appliedNamespaces(m machine.MachineCURD, p Plugin) ([]string, error) {
+	manifestFile, err := k.appliedManifestFile(m, p)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	return kubeflowplugin.ManifestNamespaces(manifest)
+}
+
+func (k *kubeflowInstaller) Status(m machine.MachineCURD) (Status, error) {
+	return DeploymentsStatus(m, kubeflowNamespace)
+}
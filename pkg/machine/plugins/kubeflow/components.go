@@ -0,0 +1,319 @@
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Component is a group of kubeflow resources which could be installed on demand
+type Component string
+
+const (
+	// ComponentCore contains namespaces, istio, dex, profiles and the central dashboard, it is always installed
+	ComponentCore             Component = "core"
+	ComponentPipelines        Component = "pipelines"
+	ComponentNotebooks        Component = "notebooks"
+	ComponentKatib            Component = "katib"
+	ComponentTrainingOperator Component = "training-operator"
+	ComponentKserve           Component = "kserve"
+	ComponentKnative          Component = "knative"
+)
+
+func (c Component) String() string {
+	return string(c)
+}
+
+// profiles are named component lists
+var profiles = map[string][]Component{
+	"full":           {ComponentPipelines, ComponentNotebooks, ComponentKatib, ComponentTrainingOperator, ComponentKserve},
+	"lite":           {ComponentPipelines, ComponentNotebooks},
+	"pipelines-only": {ComponentPipelines},
+}
+
+// dependencies lists components required by a component besides core
+var dependencies = map[Component][]Component{
+	ComponentKserve: {ComponentKnative},
+}
+
+func listComponents() []Component {
+	return []Component{ComponentCore, ComponentPipelines, ComponentNotebooks, ComponentKatib, ComponentTrainingOperator, ComponentKserve, ComponentKnative}
+}
+
+// ListProfileString returns names of profiles
+func ListProfileString() []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseComponents parses a profile name (full, lite, pipelines-only) or a comma delimited component list,
+// core and dependencies are always included, the result is sorted.
+func ParseComponents(s string) ([]Component, error) {
+	var selected []Component
+	if profile, found := profiles[strings.TrimSpace(s)]; found {
+		selected = profile
+	} else {
+		for _, token := range strings.Split(s, ",") {
+			token = strings.TrimSpace(token)
+			if token == "" {
+				continue
+			}
+			component, err := parseComponent(token)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, component)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("kubeflow: no component selected")
+	}
+	resolved := map[Component]bool{ComponentCore: true}
+	var resolve func(c Component)
+	resolve = func(c Component) {
+		if resolved[c] {
+			return
+		}
+		resolved[c] = true
+		for _, dependency := range dependencies[c] {
+			resolve(dependency)
+		}
+	}
+	for _, c := range selected {
+		resolve(c)
+	}
+	var components []Component
+	for c := range resolved {
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i] < components[j] })
+	return components, nil
+}
+
+func parseComponent(s string) (Component, error) {
+	var names []string
+	for _, c := range listComponents() {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+		names = append(names, c.String())
+	}
+	return "", fmt.Errorf("kubeflow: unsupported component %q, available components: %s, or profiles: %s", s, strings.Join(names, ","), strings.Join(ListProfileString(), ","))
+}
+
+// IsFull returns true if components contain every component, in which case the manifest is applied as is
+func IsFull(components []Component) bool {
+	return len(components) == len(listComponents())
+}
+
+// object is the part of a manifest document used for classification
+type object struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		Group string `json:"group"`
+	} `json:"spec"`
+}
+
+func (o object) String() string {
+	if o.Metadata.Namespace == "" {
+		return o.Kind + "/" + o.Metadata.Name
+	}
+	return o.Kind + "/" + o.Metadata.Namespace + "/" + o.Metadata.Name
+}
+
+func (o object) isCRD(group string, plurals ...string) bool {
+	if o.Kind != "CustomResourceDefinition" || !strings.HasSuffix(o.Spec.Group, group) {
+		return false
+	}
+	if len(plurals) == 0 {
+		return true
+	}
+	for _, plural := range plurals {
+		if o.Metadata.Name == plural+"."+o.Spec.Group {
+			return true
+		}
+	}
+	return false
+}
+
+// componentLabelKeys are upstream labels identifying the application of an object, checked in order,
+// e.g. `app.kubernetes.io/part-of: kubeflow` is too generic and falls through to kustomize's component label.
+var componentLabelKeys = []string{
+	"application-crd-id",
+	"app.kubernetes.io/part-of",
+	"kustomize.component",
+	"app.kubernetes.io/component",
+	"app.kubernetes.io/name",
+	"app",
+}
+
+// componentLabelValues maps values of componentLabelKeys to components
+var componentLabelValues = map[string]Component{
+	"kubeflow-pipelines":     ComponentPipelines,
+	"ml-pipeline":            ComponentPipelines,
+	"katib":                  ComponentKatib,
+	"training-operator":      ComponentTrainingOperator,
+	"kserve":                 ComponentKserve,
+	"kserve-models-web-app":  ComponentKserve,
+	"knative-serving":        ComponentKnative,
+	"knative-eventing":       ComponentKnative,
+	"notebook-controller":    ComponentNotebooks,
+	"jupyter-web-app":        ComponentNotebooks,
+	"tensorboard-controller": ComponentNotebooks,
+	"tensorboards-web-app":   ComponentNotebooks,
+	"volumes-web-app":        ComponentNotebooks,
+	"pvcviewer-controller":   ComponentNotebooks,
+	"centraldashboard":       ComponentCore,
+	"profiles":               ComponentCore,
+	"poddefaults":            ComponentCore,
+	"admission-webhook":      ComponentCore,
+	"kubeflow-roles":         ComponentCore,
+	"istio":                  ComponentCore,
+	"dex":                    ComponentCore,
+	"oauth2-proxy":           ComponentCore,
+	"cert-manager":           ComponentCore,
+}
+
+// componentNamespaces are namespaces created for a single component, objects inside belong to that component
+var componentNamespaces = map[string]Component{
+	"kserve":           ComponentKserve,
+	"knative-serving":  ComponentKnative,
+	"knative-eventing": ComponentKnative,
+	"istio-system":     ComponentCore,
+	"auth":             ComponentCore,
+	"cert-manager":     ComponentCore,
+	"oauth2-proxy":     ComponentCore,
+}
+
+// componentCRDs classifies crds, which carry no upstream labels, by their groups and plurals
+var componentCRDs = []struct {
+	component Component
+	match     func(o object) bool
+}{
+	{ComponentKserve, func(o object) bool { return o.isCRD("kserve.io") }},
+	{ComponentKnative, func(o object) bool { return o.isCRD("knative.dev") }},
+	{ComponentKatib, func(o object) bool { return o.isCRD("kubeflow.org", "experiments", "suggestions", "trials") }},
+	{ComponentTrainingOperator, func(o object) bool {
+		return o.isCRD("kubeflow.org", "tfjobs", "pytorchjobs", "mpijobs", "xgboostjobs", "paddlejobs", "jaxjobs")
+	}},
+	{ComponentNotebooks, func(o object) bool { return o.isCRD("kubeflow.org", "notebooks", "tensorboards", "pvcviewers") }},
+	{ComponentPipelines, func(o object) bool {
+		return o.isCRD("argoproj.io") || o.isCRD("metacontroller.k8s.io") || o.isCRD("pipelines.kubeflow.org") ||
+			o.isCRD("kubeflow.org", "scheduledworkflows", "viewers")
+	}},
+	{ComponentCore, func(o object) bool {
+		return o.isCRD("istio.io") || o.isCRD("cert-manager.io") || o.isCRD("kubeflow.org", "profiles", "poddefaults")
+	}},
+}
+
+// classify returns the component of the object by its upstream labels, then by crd group or namespace,
+// false is returned if nothing matches so unknown objects are never installed into a wrong component.
+func classify(o object) (Component, bool) {
+	if _, labeled := o.Metadata.Labels["katib.kubeflow.org/component"]; labeled {
+		return ComponentKatib, true
+	}
+	for _, key := range componentLabelKeys {
+		if c, found := componentLabelValues[o.Metadata.Labels[key]]; found {
+			return c, true
+		}
+	}
+	for _, rule := range componentCRDs {
+		if rule.match(o) {
+			return rule.component, true
+		}
+	}
+	if o.Kind == "Namespace" {
+		if c, found := componentNamespaces[o.Metadata.Name]; found {
+			return c, true
+		}
+		if o.Metadata.Name == "kubeflow" {
+			return ComponentCore, true
+		}
+	}
+	if c, found := componentNamespaces[o.Metadata.Namespace]; found {
+		return c, true
+	}
+	return "", false
+}
+
+// ManifestNamespaces returns names of Namespace objects created by the multi-document manifest in their order
+func ManifestNamespaces(manifest []byte) ([]string, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifest)))
+	var namespaces []string
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		o := object{}
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return nil, err
+		}
+		if o.Kind == "Namespace" {
+			namespaces = append(namespaces, o.Metadata.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+// FilterManifest keeps documents of the multi-document manifest which belong to the components,
+// an error listing unclassified objects is returned if any object belongs to no component.
+func FilterManifest(manifest []byte, components []Component) ([]byte, error) {
+	selected := map[Component]bool{}
+	for _, c := range components {
+		selected[c] = true
+	}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifest)))
+	var (
+		docs         [][]byte
+		unclassified []string
+	)
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		o := object{}
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return nil, err
+		}
+		if o.Kind == "" {
+			// empty or comment only document
+			continue
+		}
+		c, classified := classify(o)
+		if !classified {
+			unclassified = append(unclassified, o.String())
+			continue
+		}
+		if selected[c] {
+			docs = append(docs, bytes.TrimSpace(doc))
+		}
+	}
+	if len(unclassified) > 0 {
+		return nil, fmt.Errorf("kubeflow: unable to select components, objects belong to no component: %s", strings.Join(unclassified, ","))
+	}
+	return append(bytes.Join(docs, []byte("\n---\n")), '\n'), nil
+}
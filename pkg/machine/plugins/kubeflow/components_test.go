@@ -0,0 +1,183 @@
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/footprintai/multikf/kfmanifests"
+)
+
+func TestParseComponents(t *testing.T) {
+	components, err := ParseComponents("pipelines-only")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []Component{ComponentCore, ComponentPipelines}, components)
+
+	components, err = ParseComponents("lite")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []Component{ComponentCore, ComponentNotebooks, ComponentPipelines}, components)
+	assert.False(t, IsFull(components))
+
+	// kserve depends on knative
+	components, err = ParseComponents("kserve, katib")
+	assert.NoError(t, err)
+	assert.EqualValues(t, []Component{ComponentCore, ComponentKatib, ComponentKnative, ComponentKserve}, components)
+
+	components, err = ParseComponents("full")
+	assert.NoError(t, err)
+	assert.True(t, IsFull(components))
+
+	_, err = ParseComponents("pipelines,spark")
+	assert.Error(t, err)
+	_, err = ParseComponents(" , ")
+	assert.Error(t, err)
+}
+
+const testManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: kubeflow
+---
+# comment only
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ml-pipeline
+  namespace: kubeflow
+  labels:
+    application-crd-id: kubeflow-pipelines
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: katib-mysql
+  namespace: kubeflow
+  labels:
+    katib.kubeflow.org/component: mysql
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mysql
+  namespace: kubeflow
+  labels:
+    app.kubernetes.io/part-of: kubeflow
+    application-crd-id: kubeflow-pipelines
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: notebooks.kubeflow.org
+spec:
+  group: kubeflow.org
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: pytorchjobs.kubeflow.org
+spec:
+  group: kubeflow.org
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: inferenceservices.serving.kserve.io
+spec:
+  group: serving.kserve.io
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: activator
+  namespace: knative-serving
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: centraldashboard
+  namespace: kubeflow
+  labels:
+    app.kubernetes.io/part-of: kubeflow
+    kustomize.component: centraldashboard
+`
+
+func names(manifest []byte) []string {
+	var names []string
+	for _, line := range strings.Split(string(manifest), "\n") {
+		if strings.HasPrefix(line, "  name: ") {
+			names = append(names, strings.TrimPrefix(line, "  name: "))
+		}
+	}
+	return names
+}
+
+func TestFilterManifest(t *testing.T) {
+	components, err := ParseComponents("pipelines-only")
+	assert.NoError(t, err)
+	filtered, err := FilterManifest([]byte(testManifest), components)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"kubeflow", "ml-pipeline", "mysql", "centraldashboard"}, names(filtered))
+
+	components, err = ParseComponents("notebooks,katib,training-operator,kserve")
+	assert.NoError(t, err)
+	filtered, err = FilterManifest([]byte(testManifest), components)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		"kubeflow",
+		"katib-mysql",
+		"notebooks.kubeflow.org",
+		"pytorchjobs.kubeflow.org",
+		"inferenceservices.serving.kserve.io",
+		"activator",
+		"centraldashboard",
+	}, names(filtered))
+
+	// objects are never classified by names
+	_, err = FilterManifest([]byte(testManifest+`---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ml-pipeline-ui
+  namespace: kubeflow
+`), components)
+	assert.ErrorContains(t, err, "Deployment/kubeflow/ml-pipeline-ui")
+}
+
+// TestFilterEmbeddedManifests makes sure every object of embedded manifests belongs to a component, for each profile
+func TestFilterEmbeddedManifests(t *testing.T) {
+	versions := kfmanifests.ListVersions()
+	assert.NotEmpty(t, versions)
+	for _, version := range versions {
+		manifest, err := kfmanifests.GetVersion(version)
+		assert.NoError(t, err, version)
+		kt := NewKubeflowTemplateExecutor(kfmanifests.VersionBaseFileName(version), manifest)
+		assert.NoError(t, kt.Populate(statisKfConfig{}))
+		buf := &bytes.Buffer{}
+		assert.NoError(t, kt.Execute(buf), version)
+		for _, profile := range ListProfileString() {
+			components, err := ParseComponents(profile)
+			assert.NoError(t, err)
+			_, err = FilterManifest(buf.Bytes(), components)
+			assert.NoError(t, err, "%s %s", version, profile)
+		}
+	}
+}
+
+func TestManifestNamespaces(t *testing.T) {
+	namespaces, err := ManifestNamespaces([]byte(testManifest + `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: istio-system
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: knative-serving
+`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"kubeflow", "istio-system", "knative-serving"}, namespaces)
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	dexNamespace     = "auth"
+	dexConfigMapName = "dex"
+	dexConfigKey     = "config.yaml"
+	dexDeployment    = "deployment/dex"
+)
+
+// ErrUserNotFound is returned when the email is not one of dex static users
+var ErrUserNotFound = errors.New("kubeflow: user not found")
+
+// DexStaticPassword is an entry of `staticPasswords` in dex config
+type DexStaticPassword struct {
+	Email    string `json:"email"`
+	Hash     string `json:"hash"`
+	Username string `json:"username"`
+	UserID   string `json:"userID"`
+}
+
+// NewDexStaticPassword creates a static user with bcrypt hashed password
+func NewDexStaticPassword(email string, password string) (DexStaticPassword, error) {
+	if err := validateEmail(email); err != nil {
+		return DexStaticPassword{}, err
+	}
+	if password == "" {
+		return DexStaticPassword{}, fmt.Errorf("kubeflow: empty password for %s", email)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return DexStaticPassword{}, err
+	}
+	userID := sha256.Sum256([]byte(email))
+	return DexStaticPassword{
+		Email:    email,
+		Hash:     string(hash),
+		Username: strings.SplitN(email, "@", 2)[0],
+		UserID:   hex.EncodeToString(userID[:16]),
+	}, nil
+}
+
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("kubeflow: invalid email %q", email)
+	}
+	return nil
+}
+
+func unmarshalDexConfig(config []byte) (map[string]interface{}, []DexStaticPassword, error) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(config, &raw); err != nil {
+		return nil, nil, fmt.Errorf("kubeflow: invalid dex config, err:%+v", err)
+	}
+	parsed := struct {
+		StaticPasswords []DexStaticPassword `json:"staticPasswords"`
+	}{}
+	if err := yaml.Unmarshal(config, &parsed); err != nil {
+		return nil, nil, fmt.Errorf("kubeflow: invalid dex static passwords, err:%+v", err)
+	}
+	return raw, parsed.StaticPasswords, nil
+}
+
+func marshalDexConfig(raw map[string]interface{}, passwords []DexStaticPassword) ([]byte, error) {
+	raw["staticPasswords"] = passwords
+	return yaml.Marshal(raw)
+}
+
+// ListDexStaticPasswords returns static users in dex config
+func ListDexStaticPasswords(config []byte) ([]DexStaticPassword, error) {
+	_, passwords, err := unmarshalDexConfig(config)
+	return passwords, err
+}
+
+// AddDexStaticPassword adds the static user into dex config, an existing user with the same email is replaced (e.g. password changed)
+func AddDexStaticPassword(config []byte, p DexStaticPassword) ([]byte, error) {
+	raw, passwords, err := unmarshalDexConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	replaced := false
+	for i := range passwords {
+		if passwords[i].Email == p.Email {
+			p.UserID = passwords[i].UserID
+			passwords[i] = p
+			replaced = true
+		}
+	}
+	if !replaced {
+		passwords = append(passwords, p)
+	}
+	return marshalDexConfig(raw, passwords)
+}
+
+// RemoveDexStaticPassword removes the static user with email from dex config
+func RemoveDexStaticPassword(config []byte, email string) ([]byte, error) {
+	raw, passwords, err := unmarshalDexConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var kept []DexStaticPassword
+	for _, p := range passwords {
+		if p.Email != email {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(passwords) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+	}
+	return marshalDexConfig(raw, kept)
+}
+
+// dexConfigMapManifest wraps dex config into its configmap
+func dexConfigMapManifest(config []byte) ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      dexConfigMapName,
+			"namespace": dexNamespace,
+		},
+		"data": map[string]string{
+			dexConfigKey: string(config),
+		},
+	})
+}
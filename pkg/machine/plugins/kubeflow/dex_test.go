@@ -0,0 +1,75 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var dexConfig = `issuer: http://dex.auth.svc.cluster.local:5556/dex
+storage:
+  type: kubernetes
+  config:
+    inCluster: true
+enablePasswordDB: true
+staticPasswords:
+- email: user@example.com
+  hash: $2y$12$4K/VkmDd1q1Orb3xAt82zu8gk7Ad6ReFR4LCP9UeYE90NLiN9Df72
+  username: user
+  userID: "15841185641784"
+`
+
+func TestDexStaticPasswords(t *testing.T) {
+	alice, err := NewDexStaticPassword("alice@example.com", "s3cret")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "alice", alice.Username)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(alice.Hash), []byte("s3cret")))
+
+	config, err := AddDexStaticPassword([]byte(dexConfig), alice)
+	assert.NoError(t, err)
+	passwords, err := ListDexStaticPasswords(config)
+	assert.NoError(t, err)
+	assert.Len(t, passwords, 2)
+	assert.EqualValues(t, "15841185641784", passwords[0].UserID)
+	assert.EqualValues(t, alice, passwords[1])
+	assert.Contains(t, string(config), "issuer: http://dex.auth.svc.cluster.local:5556/dex")
+	assert.Contains(t, string(config), "inCluster: true")
+
+	// password of the existing user is replaced, userID is kept
+	user, err := NewDexStaticPassword("user@example.com", "changed")
+	assert.NoError(t, err)
+	config, err = AddDexStaticPassword(config, user)
+	assert.NoError(t, err)
+	passwords, err = ListDexStaticPasswords(config)
+	assert.NoError(t, err)
+	assert.Len(t, passwords, 2)
+	assert.EqualValues(t, "15841185641784", passwords[0].UserID)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwords[0].Hash), []byte("changed")))
+
+	config, err = RemoveDexStaticPassword(config, "user@example.com")
+	assert.NoError(t, err)
+	passwords, err = ListDexStaticPasswords(config)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []DexStaticPassword{alice}, passwords)
+
+	_, err = RemoveDexStaticPassword(config, "user@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = NewDexStaticPassword("not an email", "s3cret")
+	assert.Error(t, err)
+	_, err = NewDexStaticPassword("alice@example.com", "")
+	assert.Error(t, err)
+}
+
+func TestParseUserPassword(t *testing.T) {
+	email, password, err := ParseUserPassword("alice@example.com:pa:ss")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "alice@example.com", email)
+	assert.EqualValues(t, "pa:ss", password)
+
+	for _, invalid := range []string{"alice@example.com", "alice@example.com:", ":pass", "alice:pass"} {
+		_, _, err := ParseUserPassword(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -60,11 +60,31 @@ type kubeflowConfig interface {
 	pkgtemplate.DefaultPasswordGetter
 }
 
+// PVCSizeGetter overrides pvc sizes of kubeflow components in GiB, zero keeps the default size
+type PVCSizeGetter interface {
+	GetAuthServicePVCSizeInG() int
+	GetKatibMySQLPVCSizeInG() int
+	GetPipelineMinioPVCSizeInG() int
+	GetPipelineMySQLPVCSizeInG() int
+}
+
 func (k *KubeflowFileTemplate) Populate(v interface{}) error {
 	if _, isConfiger := v.(kubeflowConfig); !isConfiger {
 		return fmt.Errorf("not implements kubeflowConfig interface")
 	}
 	c := v.(kubeflowConfig)
 	k.DefaultSaltedPassword = mustBcryptGenerated(c.GetDefaultPassword())
+	if sizeGetter, ok := v.(PVCSizeGetter); ok {
+		overrideIfSet(&k.AuthServicePVCSizeInG, sizeGetter.GetAuthServicePVCSizeInG())
+		overrideIfSet(&k.KatibMySQLPVCSizeInG, sizeGetter.GetKatibMySQLPVCSizeInG())
+		overrideIfSet(&k.PipelineMinioPVCSizeInG, sizeGetter.GetPipelineMinioPVCSizeInG())
+		overrideIfSet(&k.PipelineMySQLPVCSizeInG, sizeGetter.GetPipelineMySQLPVCSizeInG())
+	}
 	return nil
 }
+
+func overrideIfSet(size *int, override int) {
+	if override > 0 {
+		*size = override
+	}
+}
@@ -1,10 +1,11 @@
 package template
 
 import (
+	"bytes"
 	"testing"
 
 	_ "github.com/footprintai/multikf/kfmanifests"
-	_ "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/assert"
 )
 
 type statisKfConfig struct{}
@@ -13,6 +14,15 @@ func (s statisKfConfig) GetDefaultPassword() string {
 	return "12341234"
 }
 
+type pvcSizeKfConfig struct {
+	statisKfConfig
+}
+
+func (s pvcSizeKfConfig) GetAuthServicePVCSizeInG() int   { return 1 }
+func (s pvcSizeKfConfig) GetKatibMySQLPVCSizeInG() int    { return 0 }
+func (s pvcSizeKfConfig) GetPipelineMinioPVCSizeInG() int { return 50 }
+func (s pvcSizeKfConfig) GetPipelineMySQLPVCSizeInG() int { return 5 }
+
 func TestKubeflowTemplate(t *testing.T) {
 	//kt := NewKubeflowTemplate()
 	//assert.NoError(t, kt.Populate(statisKfConfig{}))
@@ -20,3 +30,19 @@ func TestKubeflowTemplate(t *testing.T) {
 	//assert.NoError(t, kt.Execute(buf))
 	//assert.EqualValues(t, kfmanifests.KF14, buf.String())
 }
+
+func TestKubeflowTemplatePVCSizes(t *testing.T) {
+	const tmpl = "[[.AuthServicePVCSizeInG]],[[.KatibMySQLPVCSizeInG]],[[.PipelineMinioPVCSizeInG]],[[.PipelineMySQLPVCSizeInG]]"
+
+	kt := newKubeflowTemplateWithTemplate("kf.yaml", tmpl)
+	assert.NoError(t, kt.Populate(statisKfConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assert.EqualValues(t, "10,10,20,20", buf.String())
+
+	kt = newKubeflowTemplateWithTemplate("kf.yaml", tmpl)
+	assert.NoError(t, kt.Populate(pvcSizeKfConfig{}))
+	buf.Reset()
+	assert.NoError(t, kt.Execute(buf))
+	assert.EqualValues(t, "1,10,50,5", buf.String())
+}
@@ -0,0 +1,113 @@
+package template
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+var nonProfileNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ProfileName derives the profile (and namespace) name from an email the way kubeflow names its default
+// profile, e.g. user@example.com becomes kubeflow-user-example-com
+func ProfileName(email string) string {
+	name := nonProfileNameChars.ReplaceAllString(strings.ToLower(email), "-")
+	return "kubeflow-" + strings.Trim(name, "-")
+}
+
+// Profile is a kubeflow profile owned by a single user
+type Profile struct {
+	Name  string
+	Owner string
+	// Quota is resourceQuotaSpec.hard of the profile, e.g. cpu: 4, memory: 8Gi, requests.nvidia.com/gpu: 1
+	Quota map[string]string
+}
+
+func (p Profile) Validate() error {
+	for k, v := range p.Quota {
+		if _, err := resource.ParseQuantity(v); err != nil {
+			return fmt.Errorf("kubeflow: invalid quota %s=%s, err:%+v", k, v, err)
+		}
+	}
+	return validateEmail(p.Owner)
+}
+
+func NewProfileTemplateExecutor() (*ProfileFileTemplate, error) {
+	tmplBytes, err := manifestFs.ReadFile("manifests/profile-template.yaml")
+	if err != nil {
+		return nil, err
+	}
+	return &ProfileFileTemplate{profileFileTemplate: string(tmplBytes)}, nil
+}
+
+// ProfileFileTemplate renders a kubeflow Profile with optional resource quota
+type ProfileFileTemplate struct {
+	Name                string
+	Owner               string
+	Quota               map[string]string
+	profileFileTemplate string
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &ProfileFileTemplate{}
+)
+
+func (p *ProfileFileTemplate) Filename() string {
+	return fmt.Sprintf("kubeflow-profile-%s.yaml", p.Name)
+}
+
+func (p *ProfileFileTemplate) Execute(w io.Writer) error {
+	tmpl, err := template.New("profile").Delims("[[", "]]").Parse(p.profileFileTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, p)
+}
+
+func (p *ProfileFileTemplate) Populate(v interface{}) error {
+	profile, isProfile := v.(Profile)
+	if !isProfile {
+		return fmt.Errorf("not a Profile")
+	}
+	p.Name = profile.Name
+	p.Owner = profile.Owner
+	p.Quota = profile.Quota
+	return nil
+}
+
+type profileList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Owner struct {
+				Name string `json:"name"`
+			} `json:"owner"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// parseProfileList returns profile names by owner from the output of `kubectl get profiles -o json`
+func parseProfileList(blob []byte) (map[string][]string, error) {
+	list := &profileList{}
+	if err := json.Unmarshal(blob, list); err != nil {
+		return nil, err
+	}
+	profilesByOwner := map[string][]string{}
+	for _, item := range list.Items {
+		profilesByOwner[item.Spec.Owner.Name] = append(profilesByOwner[item.Spec.Owner.Name], item.Metadata.Name)
+	}
+	return profilesByOwner, nil
+}
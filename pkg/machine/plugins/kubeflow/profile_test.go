@@ -0,0 +1,48 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileTemplate(t *testing.T) {
+	assert.EqualValues(t, "kubeflow-user-example-com", ProfileName("user@example.com"))
+	assert.EqualValues(t, "kubeflow-alice-smith-example-com", ProfileName("Alice.Smith+@example.com"))
+
+	tmpl, err := NewProfileTemplateExecutor()
+	assert.NoError(t, err)
+	assert.NoError(t, tmpl.Populate(Profile{Name: "kubeflow-alice", Owner: "alice+kf@example.com"}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, tmpl.Execute(buf))
+	assert.EqualValues(t, `apiVersion: kubeflow.org/v1
+kind: Profile
+metadata:
+  name: kubeflow-alice
+spec:
+  owner:
+    kind: User
+    name: alice+kf@example.com
+`, buf.String())
+
+	assert.NoError(t, tmpl.Populate(Profile{Name: "kubeflow-alice", Owner: "alice@example.com", Quota: map[string]string{"cpu": "4", "memory": "8Gi"}}))
+	buf.Reset()
+	assert.NoError(t, tmpl.Execute(buf))
+	assert.Contains(t, buf.String(), `  resourceQuotaSpec:
+    hard:
+      cpu: "4"
+      memory: "8Gi"
+`)
+
+	assert.Error(t, Profile{Name: "kubeflow-alice", Owner: "alice@example.com", Quota: map[string]string{"cpu": "four"}}.Validate())
+}
+
+func TestParseProfileList(t *testing.T) {
+	profiles, err := parseProfileList([]byte(`{"items":[
+{"metadata":{"name":"kubeflow-user-example-com"},"spec":{"owner":{"kind":"User","name":"user@example.com"}}},
+{"metadata":{"name":"team-a"},"spec":{"owner":{"kind":"User","name":"user@example.com"}}}
+]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[string][]string{"user@example.com": {"kubeflow-user-example-com", "team-a"}}, profiles)
+}
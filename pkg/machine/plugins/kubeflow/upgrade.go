@@ -0,0 +1,326 @@
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// ObjectKey identifies an object across manifests of different versions
+type ObjectKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (k ObjectKey) String() string {
+	kind := k.Kind
+	if k.Group != "" {
+		kind += "." + k.Group
+	}
+	if k.Namespace == "" {
+		return kind + "/" + k.Name
+	}
+	return kind + "/" + k.Namespace + "/" + k.Name
+}
+
+// Object is a document of a rendered manifest
+type Object struct {
+	Key ObjectKey
+	Raw []byte
+	// content is the decoded document used for comparison
+	content map[string]interface{}
+}
+
+// ParseObjects splits the multi-document manifest into objects, empty documents are skipped
+func ParseObjects(manifest []byte) ([]Object, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifest)))
+	var objects []Object
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content := map[string]interface{}{}
+		if err := yaml.Unmarshal(doc, &content); err != nil {
+			return nil, err
+		}
+		o := object{}
+		if err := yaml.Unmarshal(doc, &o); err != nil {
+			return nil, err
+		}
+		if o.Kind == "" {
+			continue
+		}
+		apiVersion, _ := content["apiVersion"].(string)
+		objects = append(objects, Object{
+			Key: ObjectKey{
+				Group:     groupOf(apiVersion),
+				Kind:      o.Kind,
+				Namespace: o.Metadata.Namespace,
+				Name:      o.Metadata.Name,
+			},
+			Raw:     bytes.TrimSpace(doc),
+			content: content,
+		})
+	}
+	return objects, nil
+}
+
+// groupOf returns the group of apiVersion, e.g. apps of apps/v1, version is ignored so objects moving to a newer version are matched
+func groupOf(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+// isProtected returns true for objects holding user data, they are never pruned
+func isProtected(k ObjectKey) bool {
+	switch {
+	case k.Group == "" && k.Kind == "PersistentVolumeClaim":
+		return true
+	case k.Group == "kubeflow.org" && k.Kind == "Profile":
+		return true
+	}
+	return false
+}
+
+// isCascading returns true for objects whose deletion deletes other objects, e.g. pruning a namespace deletes
+// pvcs inside and pruning crd profiles.kubeflow.org deletes every Profile, they are pruned only if requested.
+func isCascading(k ObjectKey) bool {
+	switch {
+	case k.Group == "" && k.Kind == "Namespace":
+		return true
+	case k.Group == "apiextensions.k8s.io" && k.Kind == "CustomResourceDefinition":
+		return true
+	}
+	return false
+}
+
+// isKeptAsIs returns true for existing objects which should not be overwritten by a new version, pvcs are mostly immutable
+func isKeptAsIs(k ObjectKey) bool {
+	return k.Group == "" && k.Kind == "PersistentVolumeClaim"
+}
+
+func isDexConfig(k ObjectKey) bool {
+	return k.Group == "" && k.Kind == "ConfigMap" && k.Namespace == dexNamespace && k.Name == dexConfigMapName
+}
+
+// applyPriority orders kinds so dependencies are applied first, unknown kinds go between workloads and webhooks
+var applyPriority = map[string]int{
+	"Namespace":                      0,
+	"CustomResourceDefinition":       1,
+	"PriorityClass":                  2,
+	"StorageClass":                   2,
+	"ServiceAccount":                 3,
+	"ClusterRole":                    4,
+	"ClusterRoleBinding":             5,
+	"Role":                           4,
+	"RoleBinding":                    5,
+	"ConfigMap":                      6,
+	"Secret":                         6,
+	"PersistentVolumeClaim":          7,
+	"Service":                        8,
+	"Deployment":                     9,
+	"StatefulSet":                    9,
+	"DaemonSet":                      9,
+	"Job":                            10,
+	"MutatingWebhookConfiguration":   12,
+	"ValidatingWebhookConfiguration": 12,
+}
+
+const unknownKindPriority = 11
+
+func priorityOf(k ObjectKey) int {
+	if p, found := applyPriority[k.Kind]; found {
+		return p
+	}
+	return unknownKindPriority
+}
+
+// SortForApply sorts objects in dependency order, the order within the same priority is kept
+func SortForApply(objects []Object) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return priorityOf(objects[i].Key) < priorityOf(objects[j].Key)
+	})
+}
+
+// UpgradePlan is the object level difference between two rendered manifests
+type UpgradePlan struct {
+	Added     []Object
+	Changed   []Object
+	Unchanged []Object
+	// Kept are existing objects which are left as is, e.g. pvcs
+	Kept []Object
+	// Removed are objects only in the old manifest, they are pruned unless protected
+	Removed []Object
+	// Protected are removed objects holding user data, they are never pruned
+	Protected []Object
+	// Cascading are removed namespaces and crds, pruning them deletes objects inside as well
+	Cascading []Object
+}
+
+// NewUpgradePlan compares the old and new manifests
+func NewUpgradePlan(oldManifest []byte, newManifest []byte) (*UpgradePlan, error) {
+	oldObjects, err := ParseObjects(oldManifest)
+	if err != nil {
+		return nil, fmt.Errorf("kubeflow: parse old manifest failed, err:%+v", err)
+	}
+	newObjects, err := ParseObjects(newManifest)
+	if err != nil {
+		return nil, fmt.Errorf("kubeflow: parse new manifest failed, err:%+v", err)
+	}
+	oldByKey := map[ObjectKey]Object{}
+	for _, o := range oldObjects {
+		oldByKey[o.Key] = o
+	}
+	plan := &UpgradePlan{}
+	newKeys := map[ObjectKey]bool{}
+	for _, o := range newObjects {
+		newKeys[o.Key] = true
+		old, found := oldByKey[o.Key]
+		switch {
+		case !found:
+			plan.Added = append(plan.Added, o)
+		case isKeptAsIs(o.Key):
+			plan.Kept = append(plan.Kept, old)
+		case reflect.DeepEqual(old.content, o.content):
+			plan.Unchanged = append(plan.Unchanged, o)
+		default:
+			plan.Changed = append(plan.Changed, o)
+		}
+	}
+	for _, o := range oldObjects {
+		if newKeys[o.Key] {
+			continue
+		}
+		switch {
+		case isProtected(o.Key):
+			plan.Protected = append(plan.Protected, o)
+		case isCascading(o.Key):
+			plan.Cascading = append(plan.Cascading, o)
+		default:
+			plan.Removed = append(plan.Removed, o)
+		}
+	}
+	return plan, nil
+}
+
+// CarryDexUsers replaces static users of the new dex config with users of the live config, so users added by
+// `kubeflow user` are kept while the rest of the config (e.g. oauth2-proxy's client since v1.9) follows the new version.
+// It returns false if the new manifest has no dex config.
+func (p *UpgradePlan) CarryDexUsers(liveConfig []byte) (bool, error) {
+	_, livePasswords, err := unmarshalDexConfig(liveConfig)
+	if err != nil {
+		return false, err
+	}
+	for _, objects := range [][]Object{p.Added, p.Changed, p.Unchanged} {
+		for i := range objects {
+			if !isDexConfig(objects[i].Key) {
+				continue
+			}
+			if len(livePasswords) == 0 {
+				return true, nil
+			}
+			return true, objects[i].replaceDexStaticPasswords(livePasswords)
+		}
+	}
+	return false, nil
+}
+
+// replaceDexStaticPasswords rewrites static users in the dex configmap
+func (o *Object) replaceDexStaticPasswords(passwords []DexStaticPassword) error {
+	data, _ := o.content["data"].(map[string]interface{})
+	config, _ := data[dexConfigKey].(string)
+	raw, _, err := unmarshalDexConfig([]byte(config))
+	if err != nil {
+		return err
+	}
+	updated, err := marshalDexConfig(raw, passwords)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+		o.content["data"] = data
+	}
+	data[dexConfigKey] = string(updated)
+	manifest, err := yaml.Marshal(o.content)
+	if err != nil {
+		return err
+	}
+	o.Raw = bytes.TrimSpace(manifest)
+	return nil
+}
+
+// ApplyManifest returns added, changed and unchanged objects in apply order
+func (p *UpgradePlan) ApplyManifest() []byte {
+	var objects []Object
+	objects = append(objects, p.Added...)
+	objects = append(objects, p.Changed...)
+	objects = append(objects, p.Unchanged...)
+	SortForApply(objects)
+	return joinObjects(objects)
+}
+
+// PruneManifest returns removed objects in reverse apply order, cascading ones are included only if withCascading
+func (p *UpgradePlan) PruneManifest(withCascading bool) []byte {
+	objects := append([]Object{}, p.Removed...)
+	if withCascading {
+		objects = append(objects, p.Cascading...)
+	}
+	SortForApply(objects)
+	for i, j := 0, len(objects)-1; i < j; i, j = i+1, j-1 {
+		objects[i], objects[j] = objects[j], objects[i]
+	}
+	return joinObjects(objects)
+}
+
+func joinObjects(objects []Object) []byte {
+	if len(objects) == 0 {
+		return nil
+	}
+	var docs [][]byte
+	for _, o := range objects {
+		docs = append(docs, o.Raw)
+	}
+	return append(bytes.Join(docs, []byte("\n---\n")), '\n')
+}
+
+// Write prints the plan as a diff, `+` added, `~` changed, `-` pruned, `=` kept
+func (p *UpgradePlan) Write(w io.Writer) error {
+	sections := []struct {
+		mark    string
+		objects []Object
+		suffix  string
+	}{
+		{"+", p.Added, ""},
+		{"~", p.Changed, ""},
+		{"-", p.Removed, ""},
+		{"=", p.Kept, " (kept as is)"},
+		{"=", p.Protected, " (protected, not pruned)"},
+		{"=", p.Cascading, " (deletes objects inside, pruned with --prune_cascading only)"},
+	}
+	for _, section := range sections {
+		for _, o := range section.objects {
+			if _, err := fmt.Fprintf(w, "%s %s%s\n", section.mark, o.Key, section.suffix); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d to add, %d to change, %d unchanged, %d to prune, %d kept, %d cascading\n",
+		len(p.Added), len(p.Changed), len(p.Unchanged), len(p.Removed), len(p.Kept)+len(p.Protected), len(p.Cascading))
+	return err
+}
@@ -0,0 +1,252 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const oldKubeflowManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ml-pipeline
+  namespace: kubeflow
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kubeflow
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: minio-pvc
+  namespace: kubeflow
+spec:
+  resources:
+    requests:
+      storage: 20Gi
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: legacy-pvc
+  namespace: kubeflow
+---
+apiVersion: kubeflow.org/v1
+kind: Profile
+metadata:
+  name: kubeflow-user-example-com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dex
+  namespace: auth
+data:
+  config.yaml: |
+    issuer: http://dex.auth.svc.cluster.local:5556/dex
+    staticPasswords:
+    - email: user@example.com
+      hash: old
+      username: user
+      userID: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: legacy-controller
+  namespace: kubeflow
+`
+
+const newKubeflowManifest = `apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: admission-webhook
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ml-pipeline
+  namespace: kubeflow
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: kubeflow
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: minio-pvc
+  namespace: kubeflow
+spec:
+  resources:
+    requests:
+      storage: 10Gi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: dex
+  namespace: auth
+data:
+  config.yaml: |
+    issuer: http://dex.auth.svc.cluster.local:5556/dex
+    staticClients:
+    - id: kubeflow-oidc-authservice
+      name: oauth2-proxy
+    staticPasswords:
+    - email: user@example.com
+      hash: new
+      username: user
+      userID: "1"
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: notebooks.kubeflow.org
+`
+
+func keysOf(objects []Object) []string {
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key.String())
+	}
+	return keys
+}
+
+func TestUpgradePlan(t *testing.T) {
+	plan, err := NewUpgradePlan([]byte(oldKubeflowManifest), []byte(newKubeflowManifest))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		"MutatingWebhookConfiguration.admissionregistration.k8s.io/admission-webhook",
+		"CustomResourceDefinition.apiextensions.k8s.io/notebooks.kubeflow.org",
+	}, keysOf(plan.Added))
+	assert.EqualValues(t, []string{"Deployment.apps/kubeflow/ml-pipeline", "ConfigMap/auth/dex"}, keysOf(plan.Changed))
+	assert.EqualValues(t, []string{"Namespace/kubeflow"}, keysOf(plan.Unchanged))
+	assert.EqualValues(t, []string{"PersistentVolumeClaim/kubeflow/minio-pvc"}, keysOf(plan.Kept))
+	assert.EqualValues(t, []string{"Deployment.apps/kubeflow/legacy-controller"}, keysOf(plan.Removed))
+	assert.EqualValues(t, []string{"PersistentVolumeClaim/kubeflow/legacy-pvc", "Profile.kubeflow.org/kubeflow-user-example-com"}, keysOf(plan.Protected))
+
+	// namespaces and crds go first, webhooks last, kept objects are not applied
+	applied, err := ParseObjects(plan.ApplyManifest())
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		"Namespace/kubeflow",
+		"CustomResourceDefinition.apiextensions.k8s.io/notebooks.kubeflow.org",
+		"ConfigMap/auth/dex",
+		"Deployment.apps/kubeflow/ml-pipeline",
+		"MutatingWebhookConfiguration.admissionregistration.k8s.io/admission-webhook",
+	}, keysOf(applied))
+
+	pruned, err := ParseObjects(plan.PruneManifest(false))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Deployment.apps/kubeflow/legacy-controller"}, keysOf(pruned))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, plan.Write(buf))
+	assert.Contains(t, buf.String(), "~ Deployment.apps/kubeflow/ml-pipeline\n")
+	assert.Contains(t, buf.String(), "= Profile.kubeflow.org/kubeflow-user-example-com (protected, not pruned)\n")
+	assert.Contains(t, buf.String(), "2 to add, 2 to change, 1 unchanged, 1 to prune, 3 kept, 0 cascading\n")
+}
+
+func TestCarryDexUsers(t *testing.T) {
+	plan, err := NewUpgradePlan([]byte(oldKubeflowManifest), []byte(newKubeflowManifest))
+	assert.NoError(t, err)
+	alice, err := NewDexStaticPassword("alice@example.com", "secret")
+	assert.NoError(t, err)
+	liveConfig := `issuer: http://dex.auth.svc.cluster.local:5556/dex
+staticPasswords:
+- email: user@example.com
+  hash: old
+  username: user
+  userID: "1"
+`
+	liveConfigBytes, err := AddDexStaticPassword([]byte(liveConfig), alice)
+	assert.NoError(t, err)
+
+	withDex, err := plan.CarryDexUsers(liveConfigBytes)
+	assert.NoError(t, err)
+	assert.True(t, withDex)
+	applied, err := ParseObjects(plan.ApplyManifest())
+	assert.NoError(t, err)
+	var dexConfig []byte
+	for _, o := range applied {
+		if o.Key.String() == "ConfigMap/auth/dex" {
+			dexConfig = []byte(o.content["data"].(map[string]interface{})[dexConfigKey].(string))
+		}
+	}
+	// users come from the live config, the rest follows the new version
+	passwords, err := ListDexStaticPasswords(dexConfig)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"user@example.com", "alice@example.com"}, []string{passwords[0].Email, passwords[1].Email})
+	assert.EqualValues(t, "old", passwords[0].Hash)
+	assert.Contains(t, string(dexConfig), "oauth2-proxy")
+
+	// a manifest without dex
+	plan, err = NewUpgradePlan([]byte(oldKubeflowManifest), []byte(""))
+	assert.NoError(t, err)
+	withDex, err = plan.CarryDexUsers(liveConfigBytes)
+	assert.NoError(t, err)
+	assert.False(t, withDex)
+}
+
+func TestPruneOrder(t *testing.T) {
+	plan, err := NewUpgradePlan([]byte(newKubeflowManifest), []byte(""))
+	assert.NoError(t, err)
+	pruned, err := ParseObjects(plan.PruneManifest(true))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		"MutatingWebhookConfiguration.admissionregistration.k8s.io/admission-webhook",
+		"Deployment.apps/kubeflow/ml-pipeline",
+		"ConfigMap/auth/dex",
+		"CustomResourceDefinition.apiextensions.k8s.io/notebooks.kubeflow.org",
+		"Namespace/kubeflow",
+	}, keysOf(pruned))
+	assert.Nil(t, plan.ApplyManifest())
+}
+
+func TestCascadingNotPruned(t *testing.T) {
+	// profiles' crd and a namespace are gone in the new version
+	oldManifest := oldKubeflowManifest + `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: profiles.kubeflow.org
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: legacy
+`
+	plan, err := NewUpgradePlan([]byte(oldManifest), []byte(newKubeflowManifest))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Deployment.apps/kubeflow/legacy-controller"}, keysOf(plan.Removed))
+	assert.EqualValues(t, []string{
+		"CustomResourceDefinition.apiextensions.k8s.io/profiles.kubeflow.org",
+		"Namespace/legacy",
+	}, keysOf(plan.Cascading))
+
+	pruned, err := ParseObjects(plan.PruneManifest(false))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Deployment.apps/kubeflow/legacy-controller"}, keysOf(pruned))
+
+	pruned, err = ParseObjects(plan.PruneManifest(true))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		"Deployment.apps/kubeflow/legacy-controller",
+		"CustomResourceDefinition.apiextensions.k8s.io/profiles.kubeflow.org",
+		"Namespace/legacy",
+	}, keysOf(pruned))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, plan.Write(buf))
+	assert.Contains(t, buf.String(), "= Namespace/legacy (deletes objects inside, pruned with --prune_cascading only)\n")
+	assert.Contains(t, buf.String(), "1 to prune, 3 kept, 2 cascading\n")
+}
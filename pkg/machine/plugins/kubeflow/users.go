@@ -0,0 +1,213 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+)
+
+var (
+	dexRolloutTimeout = 3 * time.Minute
+)
+
+// User is a kubeflow user which logs in with dex static password and owns a profile
+type User struct {
+	Email    string
+	Password string
+	Profile  Profile
+}
+
+// ListedUser is a dex static user and profiles it owns
+type ListedUser struct {
+	Email    string
+	Username string
+	Profiles []string
+}
+
+// UserManager manages dex static users and their profiles of a kubeflow deployment
+type UserManager struct {
+	logger         log.Logger
+	kubecli        *machinekubectlcmd.CLI
+	kubeConfigFile string
+	hostDir        string
+}
+
+func NewUserManager(logger log.Logger, kubecli *machinekubectlcmd.CLI, kubeConfigFile string, hostDir string) *UserManager {
+	return &UserManager{
+		logger:         logger,
+		kubecli:        kubecli,
+		kubeConfigFile: kubeConfigFile,
+		hostDir:        hostDir,
+	}
+}
+
+// DexConfig returns dex's live config
+func (u *UserManager) DexConfig() ([]byte, error) {
+	return u.kubecli.Output(u.kubeConfigFile, "get", "configmap", dexConfigMapName, "-n", dexNamespace, "-o", `jsonpath={.data.config\.yaml}`)
+}
+
+func (u *UserManager) applyDexConfig(config []byte) error {
+	manifest, err := dexConfigMapManifest(config)
+	if err != nil {
+		return err
+	}
+	manifestFile := filepath.Join(u.hostDir, "kubeflow-dex-config.yaml")
+	if err := os.WriteFile(manifestFile, manifest, 0600); err != nil {
+		return err
+	}
+	return u.kubecli.Apply(u.kubeConfigFile, manifestFile)
+}
+
+// RestartDex restarts dex and waits until it rolls out, dex reads its config at startup only
+func (u *UserManager) RestartDex() error {
+	if err := u.kubecli.RolloutRestart(u.kubeConfigFile, dexNamespace, dexDeployment); err != nil {
+		return err
+	}
+	return u.kubecli.RolloutStatus(u.kubeConfigFile, dexNamespace, dexDeployment, dexRolloutTimeout)
+}
+
+// updateDexConfig applies the config mutated by fn and restarts dex, the original config is restored
+// if dex fails to roll out with the new one.
+func (u *UserManager) updateDexConfig(fn func(config []byte) ([]byte, error)) error {
+	origin, err := u.DexConfig()
+	if err != nil {
+		return err
+	}
+	updated, err := fn(origin)
+	if err != nil {
+		return err
+	}
+	if err := u.applyDexConfig(updated); err != nil {
+		return err
+	}
+	if err := u.RestartDex(); err != nil {
+		u.logger.Errorf("kubeflow: dex failed to restart with new config, restore the original one, err:%+v\n", err)
+		if restoreErr := u.applyDexConfig(origin); restoreErr != nil {
+			return restoreErr
+		}
+		if restoreErr := u.RestartDex(); restoreErr != nil {
+			return restoreErr
+		}
+		return err
+	}
+	return nil
+}
+
+// Add adds (or updates password of) dex static users and creates their profiles
+func (u *UserManager) Add(users ...User) error {
+	var staticPasswords []DexStaticPassword
+	for _, user := range users {
+		if err := user.Profile.Validate(); err != nil {
+			return err
+		}
+		p, err := NewDexStaticPassword(user.Email, user.Password)
+		if err != nil {
+			return err
+		}
+		staticPasswords = append(staticPasswords, p)
+	}
+	u.logger.V(0).Infof("kubeflow: add %d users into dex\n", len(staticPasswords))
+	if err := u.updateDexConfig(func(config []byte) ([]byte, error) {
+		var err error
+		for _, p := range staticPasswords {
+			if config, err = AddDexStaticPassword(config, p); err != nil {
+				return nil, err
+			}
+		}
+		return config, nil
+	}); err != nil {
+		return err
+	}
+	for _, user := range users {
+		tmpl, err := NewProfileTemplateExecutor()
+		if err != nil {
+			return err
+		}
+		memFs := templatefs.NewMemoryFilesFs()
+		if err := memFs.Generate(user.Profile, tmpl); err != nil {
+			return err
+		}
+		if err := templatefs.NewFolder(u.hostDir).DumpFiles(true, memFs.FS()); err != nil {
+			return err
+		}
+		u.logger.V(0).Infof("kubeflow: create profile %s for %s\n", user.Profile.Name, user.Email)
+		if err := u.kubecli.Apply(u.kubeConfigFile, filepath.Join(u.hostDir, tmpl.Filename())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *UserManager) listProfiles() (map[string][]string, error) {
+	out, err := u.kubecli.Output(u.kubeConfigFile, "get", "profiles", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseProfileList(out)
+}
+
+// Remove removes the dex static user, profiles owned by the user (including their namespaces) are deleted unless keepProfiles
+func (u *UserManager) Remove(email string, keepProfiles bool) error {
+	if err := u.updateDexConfig(func(config []byte) ([]byte, error) {
+		return RemoveDexStaticPassword(config, email)
+	}); err != nil {
+		return err
+	}
+	if keepProfiles {
+		return nil
+	}
+	profilesByOwner, err := u.listProfiles()
+	if err != nil {
+		return err
+	}
+	for _, profile := range profilesByOwner[email] {
+		u.logger.V(0).Infof("kubeflow: delete profile %s of %s\n", profile, email)
+		if _, err := u.kubecli.Output(u.kubeConfigFile, "delete", "profile", profile, "--ignore-not-found"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns dex static users with their profiles
+func (u *UserManager) List() ([]ListedUser, error) {
+	config, err := u.DexConfig()
+	if err != nil {
+		return nil, err
+	}
+	staticPasswords, err := ListDexStaticPasswords(config)
+	if err != nil {
+		return nil, err
+	}
+	profilesByOwner, err := u.listProfiles()
+	if err != nil {
+		return nil, err
+	}
+	var users []ListedUser
+	for _, p := range staticPasswords {
+		profiles := profilesByOwner[p.Email]
+		sort.Strings(profiles)
+		users = append(users, ListedUser{Email: p.Email, Username: p.Username, Profiles: profiles})
+	}
+	return users, nil
+}
+
+// ParseUserPassword parses `email:password`, the password may contain colons
+func ParseUserPassword(s string) (string, string, error) {
+	tokens := strings.SplitN(s, ":", 2)
+	if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+		return "", "", fmt.Errorf("kubeflow: expect email:password, got %q", s)
+	}
+	if err := validateEmail(tokens[0]); err != nil {
+		return "", "", err
+	}
+	return tokens[0], tokens[1], nil
+}
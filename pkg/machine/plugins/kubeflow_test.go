@@ -0,0 +1,106 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins/source"
+)
+
+func TestNewKubeflowConfig(t *testing.T) {
+	c, err := newKubeflowConfig(NewPlugin(TypePluginKubeflow, "v1.9.0", map[string]string{
+		KubeflowParamAuthServicePVCSize:   "5",
+		KubeflowParamPipelineMinioPVCSize: "50Gi",
+	}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, c.GetAuthServicePVCSizeInG())
+	assert.EqualValues(t, 0, c.GetKatibMySQLPVCSizeInG())
+	assert.EqualValues(t, 50, c.GetPipelineMinioPVCSizeInG())
+	assert.EqualValues(t, kubeflowDefaultPassword, c.GetDefaultPassword())
+
+	for _, invalid := range []string{"0", "-1", "1Ti", "ten"} {
+		_, err := newKubeflowConfig(NewPlugin(TypePluginKubeflow, "v1.9.0", map[string]string{KubeflowParamKatibMySQLPVCSize: invalid}))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestKubeflowManifestFromSource(t *testing.T) {
+	manifest := []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: dex-passwords\n  namespace: auth\nstringData:\n  hash: \"[[ .DefaultSaltedPassword ]]\"\n")
+	sum := sha256.Sum256(manifest)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(manifest)
+	}))
+	defer server.Close()
+
+	installer := &kubeflowInstaller{logger: log.NoopLogger{}}
+	rootDir := t.TempDir()
+	m := fakeMachine{hostDir: filepath.Join(rootDir, "kf")}
+	p := NewPlugin(TypePluginKubeflow, "v1.10.0", map[string]string{
+		source.ParamLocation: server.URL + "/kubeflow.yaml",
+		source.ParamSHA256:   hex.EncodeToString(sum[:]),
+	})
+	p, err := withDefaultVersion(installer, p)
+	assert.NoError(t, err, "versions not embedded are accepted with a source")
+	manifestFile, err := installer.generateManifest(m, p)
+	assert.NoError(t, err)
+	rendered, err := os.ReadFile(manifestFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(rendered), "hash: \"$2a$")
+	assert.DirExists(t, filepath.Join(rootDir, source.CacheDirName, hex.EncodeToString(sum[:])))
+
+	_, err = withDefaultVersion(installer, NewPlugin(TypePluginKubeflow, "", map[string]string{source.ParamLocation: server.URL}))
+	assert.ErrorContains(t, err, "version of kubeflow is required")
+	_, err = withDefaultVersion(fakeInstaller{}, NewPlugin("fake", "v1", map[string]string{source.ParamLocation: server.URL}))
+	assert.ErrorContains(t, err, "doesn't support")
+}
+
+func TestKubeflowAppliedManifest(t *testing.T) {
+	installer := &kubeflowInstaller{logger: log.NoopLogger{}}
+	m := fakeMachine{hostDir: t.TempDir()}
+	// the source is gone after installation, removal still works with the applied manifest
+	p := NewPlugin(TypePluginKubeflow, "v1.10.0", map[string]string{source.ParamLocation: "/not/exist"})
+	_, err := installer.appliedManifestFile(m, p)
+	assert.Error(t, err)
+
+	manifestFile := filepath.Join(m.HostDir(), "rendered.yaml")
+	assert.NoError(t, os.WriteFile(manifestFile, []byte("kind: Namespace\n"), 0644))
+	assert.NoError(t, installer.saveAppliedManifest(m, manifestFile))
+	appliedFile, err := installer.appliedManifestFile(m, p)
+	assert.NoError(t, err)
+	assert.EqualValues(t, filepath.Join(m.HostDir(), kubeflowAppliedManifestFile), appliedFile)
+	applied, err := os.ReadFile(appliedFile)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "kind: Namespace\n", string(applied))
+}
+
+func TestKubeflowOwnedNamespaces(t *testing.T) {
+	installer := &kubeflowInstaller{logger: log.NoopLogger{}}
+	m := fakeMachine{hostDir: t.TempDir()}
+	p := NewPlugin(TypePluginKubeflow, "v1.10.0", map[string]string{source.ParamLocation: "/not/exist"})
+	assert.EqualValues(t, []string{kubeflowNamespace}, installer.OwnedNamespaces(m, p), "kubeflow's namespace if nothing is applied")
+
+	manifestFile := filepath.Join(m.HostDir(), "rendered.yaml")
+	assert.NoError(t, os.WriteFile(manifestFile, []byte(`kind: Namespace
+metadata:
+  name: cert-manager
+---
+kind: Namespace
+metadata:
+  name: kubeflow
+---
+kind: Deployment
+metadata:
+  name: cert-manager
+  namespace: cert-manager
+`), 0644))
+	assert.NoError(t, installer.saveAppliedManifest(m, manifestFile))
+	assert.EqualValues(t, []string{"cert-manager", "kubeflow"}, installer.OwnedNamespaces(m, p))
+}
@@ -0,0 +1,94 @@
+package plugins
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"text/template"
+
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+)
+
+// ManifestDataFunc converts the populated value into the data of a manifest template,
+// an error is returned if the value is not the plugin's config.
+type ManifestDataFunc func(v interface{}) (interface{}, error)
+
+// ManifestTemplate renders one of a plugin's embedded manifests (manifests/<name>) with [[ ]] delimiters
+type ManifestTemplate struct {
+	filename string
+	manifest string
+	toData   ManifestDataFunc
+	funcs    template.FuncMap
+	data     interface{}
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &ManifestTemplate{}
+)
+
+// NewManifestTemplate reads manifests/<name> from manifestFs, the rendered file is named <plugin>-<name>
+func NewManifestTemplate(manifestFs fs.FS, t TypePlugin, name string, toData ManifestDataFunc, funcs template.FuncMap) (*ManifestTemplate, error) {
+	blob, err := fs.ReadFile(manifestFs, "manifests/"+name)
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestTemplate{
+		filename: t.String() + "-" + name,
+		manifest: string(blob),
+		toData:   toData,
+		funcs:    funcs,
+	}, nil
+}
+
+func (m *ManifestTemplate) Filename() string {
+	return m.filename
+}
+
+func (m *ManifestTemplate) Execute(w io.Writer) error {
+	tmpl, err := template.New(m.filename).Delims("[[", "]]").Funcs(m.funcs).Parse(m.manifest)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, m.data)
+}
+
+func (m *ManifestTemplate) Populate(v interface{}) error {
+	data, err := m.toData(v)
+	if err != nil {
+		return err
+	}
+	m.data = data
+	return nil
+}
+
+// RenderManifest renders the template with v, it writes nothing to disk and is used by tests as well
+func RenderManifest(tmpl pkgtemplate.TemplateExecutor, v interface{}) ([]byte, error) {
+	if err := tmpl.Populate(v); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateManifests renders templates with v under hostDir, paths are returned in the order of templates
+func GenerateManifests(hostDir string, v interface{}, tmpls ...pkgtemplate.TemplateExecutor) ([]string, error) {
+	memFs := templatefs.NewMemoryFilesFs()
+	for _, tmpl := range tmpls {
+		if err := memFs.Generate(v, tmpl); err != nil {
+			return nil, err
+		}
+	}
+	if err := templatefs.NewFolder(hostDir).DumpFiles(true, memFs.FS()); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, tmpl := range tmpls {
+		files = append(files, filepath.Join(hostDir, tmpl.Filename()))
+	}
+	return files, nil
+}
@@ -0,0 +1,46 @@
+package plugins
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestTemplate(t *testing.T) {
+	manifestFs := fstest.MapFS{
+		"manifests/a.yaml": {Data: []byte("name: [[ .Name | upper ]]\n")},
+	}
+	type config struct{ Name string }
+	toData := func(v interface{}) (interface{}, error) {
+		c, isConfig := v.(config)
+		if !isConfig {
+			return nil, errors.New("not a fake config")
+		}
+		return c, nil
+	}
+	tmpl, err := NewManifestTemplate(manifestFs, "fake", "a.yaml", toData, template.FuncMap{"upper": strings.ToUpper})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "fake-a.yaml", tmpl.Filename())
+
+	blob, err := RenderManifest(tmpl, config{Name: "foo"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "name: FOO\n", string(blob))
+	_, err = RenderManifest(tmpl, "foo")
+	assert.ErrorContains(t, err, "not a fake config")
+
+	hostDir := t.TempDir()
+	files, err := GenerateManifests(hostDir, config{Name: "bar"}, tmpl)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	blob, err = os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.EqualValues(t, "name: BAR\n", string(blob))
+
+	_, err = NewManifestTemplate(manifestFs, "fake", "missing.yaml", toData, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package monitoring
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"math/big"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+	"github.com/footprintai/multikf/pkg/machine/plugins/helm"
+)
+
+const (
+	TypePluginMonitoring plugins.TypePlugin = "monitoring"
+
+	// ParamRetention is how long prometheus keeps samples (default: 7d)
+	ParamRetention = "retention"
+	// ParamStorage is the pvc size of prometheus, empty for no persistence (default: 10Gi)
+	ParamStorage = "storage"
+	// ParamGrafanaStorage is the pvc size of grafana, empty for no persistence (default: 1Gi)
+	ParamGrafanaStorage = "grafana_storage"
+	// ParamStorageClass is the storageclass of pvcs (default: the cluster's default storageclass)
+	ParamStorageClass = "storage_class"
+	// ParamGrafanaPassword is the password of grafana's admin user (default: the installed one or a random one)
+	ParamGrafanaPassword = "grafana_password"
+)
+
+const (
+	// Namespace is where the monitoring stack is installed
+	Namespace = "monitoring"
+	// GrafanaService is the service of grafana, which listens on port 80
+	GrafanaService = release + "-grafana"
+
+	// grafanaPasswordKey is the key of admin password in GrafanaService's secret
+	grafanaPasswordKey = "admin-password"
+
+	release = "monitoring"
+	chart   = "kube-prometheus-stack"
+	repo    = "https://prometheus-community.github.io/helm-charts"
+)
+
+func init() {
+	plugins.RegisterPlugin(TypePluginMonitoring, newMonitoringInstaller)
+}
+
+func newMonitoringInstaller(logger log.Logger) plugins.Installer {
+	return &monitoringInstaller{logger: logger}
+}
+
+type monitoringInstaller struct {
+	logger log.Logger
+}
+
+var (
+	_ plugins.Installer       = &monitoringInstaller{}
+	_ plugins.NamespacesOwner = &monitoringInstaller{}
+	_ plugins.Dependent       = &monitoringInstaller{}
+)
+
+func (i *monitoringInstaller) Description() string {
+	return "prometheus, grafana and node-exporter with kubeflow dashboards, plugin version stands for the kube-prometheus-stack chart version"
+}
+
+func (i *monitoringInstaller) Versions() []plugins.TypePluginVersion {
+	return []plugins.TypePluginVersion{"67.9.0", "66.7.1"}
+}
+
+// SecretParams excludes grafana's password from the machine state
+func (i *monitoringInstaller) SecretParams() []string {
+	return []string{ParamGrafanaPassword}
+}
+
+func configFromPlugin(p plugins.Plugin) (Config, error) {
+	c := Config{
+		Namespace:          Namespace,
+		Retention:          plugins.GetParam(p, ParamRetention, "7d"),
+		StorageSize:        plugins.GetParam(p, ParamStorage, "10Gi"),
+		GrafanaStorageSize: plugins.GetParam(p, ParamGrafanaStorage, "1Gi"),
+		StorageClass:       plugins.GetParam(p, ParamStorageClass, ""),
+		GrafanaPassword:    plugins.GetParam(p, ParamGrafanaPassword, ""),
+	}
+	if c.GrafanaPassword == "" && !hasParam(p, ParamGrafanaPassword) {
+		password, err := randomPassword()
+		if err != nil {
+			return c, err
+		}
+		c.GrafanaPassword = password
+	}
+	return c, c.Validate()
+}
+
+func hasParam(p plugins.Plugin, key string) bool {
+	getter, ok := p.(plugins.ParamsGetter)
+	if !ok {
+		return false
+	}
+	_, found := getter.GetParams()[key]
+	return found
+}
+
+// randomPassword returns 16 random alphanumeric characters
+func randomPassword() (string, error) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, 16)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// installedGrafanaPassword returns the admin password of an installed grafana, empty if grafana is not installed,
+// it is kept on reinstall as grafana persists the password on its first start.
+func installedGrafanaPassword(m machine.MachineCURD) string {
+	out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "secret", GrafanaService, "-n", Namespace, "-o", "jsonpath={.data."+grafanaPasswordKey+"}")
+	if err != nil {
+		return ""
+	}
+	password, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return ""
+	}
+	return string(password)
+}
+
+// generateManifests renders the helm values and dashboards under the machine's HostDir
+func (i *monitoringInstaller) generateManifests(m machine.MachineCURD, c Config) (valuesFile string, dashboardsFile string, err error) {
+	valuesTmpl, err := newMonitoringTemplateExecutor(manifestValues)
+	if err != nil {
+		return "", "", err
+	}
+	dashboardsTmpl, err := newMonitoringTemplateExecutor(manifestDashboards)
+	if err != nil {
+		return "", "", err
+	}
+	files, err := plugins.GenerateManifests(m.HostDir(), c, valuesTmpl, dashboardsTmpl)
+	if err != nil {
+		return "", "", err
+	}
+	return files[0], files[1], nil
+}
+
+func (i *monitoringInstaller) helmPlugin(p plugins.Plugin, valuesFile string) plugins.Plugin {
+	return plugins.NewPlugin(helm.TypePluginHelm, p.PluginVersion(), map[string]string{
+		helm.ParamRelease:   release,
+		helm.ParamChart:     chart,
+		helm.ParamRepo:      repo,
+		helm.ParamNamespace: Namespace,
+		helm.ParamValues:    plugins.ListParam(valuesFile),
+	})
+}
+
+func (i *monitoringInstaller) Install(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	if !hasParam(p, ParamGrafanaPassword) {
+		if installed := installedGrafanaPassword(m); installed != "" {
+			c.GrafanaPassword = installed
+		} else {
+			i.logger.V(0).Infof("monitoring: grafana's admin password is generated, run `kubectl get secret %s -n %s -o jsonpath='{.data.%s}' | base64 -d` to read it\n", GrafanaService, Namespace, grafanaPasswordKey)
+		}
+	}
+	valuesFile, dashboardsFile, err := i.generateManifests(m, c)
+	if err != nil {
+		return err
+	}
+	if err := m.GetKubeCli().Apply(m.GetKubeConfig(), dashboardsFile); err != nil {
+		return err
+	}
+	helmInstaller, err := plugins.NewInstaller(helm.TypePluginHelm, i.logger)
+	if err != nil {
+		return err
+	}
+	i.logger.V(0).Infof("monitoring: install %s %s with retention %s\n", chart, p.PluginVersion(), c.Retention)
+	return helmInstaller.Install(m, i.helmPlugin(p, valuesFile))
+}
+
+func (i *monitoringInstaller) Uninstall(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	valuesFile, dashboardsFile, err := i.generateManifests(m, c)
+	if err != nil {
+		return err
+	}
+	helmInstaller, err := plugins.NewInstaller(helm.TypePluginHelm, i.logger)
+	if err != nil {
+		return err
+	}
+	if err := helmInstaller.Uninstall(m, i.helmPlugin(p, valuesFile)); err != nil {
+		return err
+	}
+	// pvcs are deleted along with the namespace
+	return m.GetKubeCli().Delete(m.GetKubeConfig(), dashboardsFile)
+}
+
+// Dependencies returns helm which installs kube-prometheus-stack's chart
+func (i *monitoringInstaller) Dependencies(p plugins.Plugin) []plugins.TypePlugin {
+	return []plugins.TypePlugin{helm.TypePluginHelm}
+}
+
+// OwnedNamespaces returns the monitoring namespace which is deleted along with the dashboards manifest
+func (i *monitoringInstaller) OwnedNamespaces(m machine.MachineCURD, p plugins.Plugin) []string {
+	return []string{Namespace}
+}
+
+func (i *monitoringInstaller) Status(m machine.MachineCURD) (plugins.Status, error) {
+	return plugins.DeploymentsStatus(m, Namespace)
+}
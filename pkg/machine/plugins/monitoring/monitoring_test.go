@@ -0,0 +1,109 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+func TestConfigFromPlugin(t *testing.T) {
+	c, err := configFromPlugin(plugins.NewPlugin(TypePluginMonitoring, "67.9.0", nil))
+	assert.NoError(t, err)
+	assert.Len(t, c.GrafanaPassword, 16)
+	assert.EqualValues(t, Config{
+		Namespace:          Namespace,
+		Retention:          "7d",
+		StorageSize:        "10Gi",
+		GrafanaStorageSize: "1Gi",
+		GrafanaPassword:    c.GrafanaPassword,
+	}, c)
+
+	// a password is generated for each installation
+	another, err := configFromPlugin(plugins.NewPlugin(TypePluginMonitoring, "67.9.0", nil))
+	assert.NoError(t, err)
+	assert.NotEqual(t, c.GrafanaPassword, another.GrafanaPassword)
+
+	for _, invalid := range []map[string]string{
+		{ParamRetention: "7 days"},
+		{ParamStorage: "ten"},
+		{ParamGrafanaStorage: "1Gx"},
+		{ParamGrafanaPassword: ""},
+	} {
+		_, err := configFromPlugin(plugins.NewPlugin(TypePluginMonitoring, "67.9.0", invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+type values struct {
+	Prometheus struct {
+		PrometheusSpec struct {
+			Retention   string `json:"retention"`
+			StorageSpec *struct {
+				VolumeClaimTemplate struct {
+					Spec struct {
+						StorageClassName string `json:"storageClassName"`
+						Resources        struct {
+							Requests map[string]string `json:"requests"`
+						} `json:"resources"`
+					} `json:"spec"`
+				} `json:"volumeClaimTemplate"`
+			} `json:"storageSpec"`
+		} `json:"prometheusSpec"`
+	} `json:"prometheus"`
+	Grafana struct {
+		AdminPassword string `json:"adminPassword"`
+		Persistence   struct {
+			Enabled bool   `json:"enabled"`
+			Size    string `json:"size"`
+		} `json:"persistence"`
+	} `json:"grafana"`
+}
+
+func renderValues(t *testing.T, c Config) values {
+	blob, err := Render(c, manifestValues)
+	assert.NoError(t, err)
+	v := values{}
+	assert.NoError(t, yaml.Unmarshal(blob, &v))
+	return v
+}
+
+func TestRenderValues(t *testing.T) {
+	v := renderValues(t, Config{Retention: "30d", StorageSize: "50Gi", GrafanaStorageSize: "2Gi", StorageClass: "longhorn", GrafanaPassword: `p@ss: "word"`})
+	assert.EqualValues(t, "30d", v.Prometheus.PrometheusSpec.Retention)
+	assert.NotNil(t, v.Prometheus.PrometheusSpec.StorageSpec)
+	assert.EqualValues(t, "longhorn", v.Prometheus.PrometheusSpec.StorageSpec.VolumeClaimTemplate.Spec.StorageClassName)
+	assert.EqualValues(t, "50Gi", v.Prometheus.PrometheusSpec.StorageSpec.VolumeClaimTemplate.Spec.Resources.Requests["storage"])
+	assert.EqualValues(t, `p@ss: "word"`, v.Grafana.AdminPassword)
+	assert.True(t, v.Grafana.Persistence.Enabled)
+	assert.EqualValues(t, "2Gi", v.Grafana.Persistence.Size)
+
+	// no persistence
+	v = renderValues(t, Config{Retention: "1d", GrafanaPassword: "admin"})
+	assert.Nil(t, v.Prometheus.PrometheusSpec.StorageSpec)
+	assert.False(t, v.Grafana.Persistence.Enabled)
+}
+
+func TestRenderDashboards(t *testing.T) {
+	blob, err := Render(Config{Namespace: Namespace}, manifestDashboards)
+	assert.NoError(t, err)
+	docs := strings.Split(string(blob), "\n---\n")
+	assert.Len(t, docs, 2)
+	configMap := struct {
+		Metadata struct {
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Data map[string]string `json:"data"`
+	}{}
+	assert.NoError(t, yaml.Unmarshal([]byte(docs[1]), &configMap))
+	assert.EqualValues(t, Namespace, configMap.Metadata.Namespace)
+	assert.EqualValues(t, "1", configMap.Metadata.Labels[dashboardLabel])
+	dashboard := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(configMap.Data["kubeflow-namespaces.json"]), &dashboard))
+	assert.EqualValues(t, "Kubeflow / Namespaces", dashboard["title"])
+}
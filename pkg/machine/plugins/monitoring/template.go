@@ -0,0 +1,122 @@
+package monitoring
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+const (
+	manifestValues     = "values.yaml"
+	manifestDashboards = "dashboards.yaml"
+
+	// dashboardLabel is watched by grafana's sidecar to load dashboards from configmaps
+	dashboardLabel = "grafana_dashboard"
+)
+
+var retentionRegexp = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|y)$`)
+
+// Config is rendered into the helm values and dashboards of the monitoring plugin
+type Config struct {
+	Namespace string
+	// Retention is how long prometheus keeps samples, e.g. 7d
+	Retention string
+	// StorageSize is the pvc size of prometheus, empty for no persistence
+	StorageSize string
+	// GrafanaStorageSize is the pvc size of grafana, empty for no persistence
+	GrafanaStorageSize string
+	// StorageClass of pvcs, empty for the default storageclass
+	StorageClass    string
+	GrafanaPassword string
+}
+
+func (c Config) Validate() error {
+	if !retentionRegexp.MatchString(c.Retention) {
+		return fmt.Errorf("monitoring: invalid retention %q, e.g. 12h or 7d", c.Retention)
+	}
+	for _, size := range []string{c.StorageSize, c.GrafanaStorageSize} {
+		if size == "" {
+			continue
+		}
+		if _, err := resource.ParseQuantity(size); err != nil {
+			return fmt.Errorf("monitoring: invalid storage size %q, err:%+v", size, err)
+		}
+	}
+	if c.GrafanaPassword == "" {
+		return fmt.Errorf("monitoring: empty grafana password")
+	}
+	return nil
+}
+
+// loadDashboards returns embedded dashboards keyed by their file names
+func loadDashboards() (map[string]string, error) {
+	dashboards := map[string]string{}
+	err := fs.WalkDir(manifestFs, "manifests", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		blob, err := manifestFs.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		dashboards[filepath.Base(path)] = strings.TrimSpace(string(blob))
+		return nil
+	})
+	return dashboards, err
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// templateData is the data of the monitoring plugin's manifests
+type templateData struct {
+	Config
+	DashboardLabel string
+	Dashboards     map[string]string
+}
+
+func toTemplateData(v interface{}) (interface{}, error) {
+	c, isConfig := v.(Config)
+	if !isConfig {
+		return nil, fmt.Errorf("not a monitoring Config")
+	}
+	dashboards, err := loadDashboards()
+	if err != nil {
+		return nil, err
+	}
+	return templateData{
+		Config:         c,
+		DashboardLabel: dashboardLabel,
+		Dashboards:     dashboards,
+	}, nil
+}
+
+func newMonitoringTemplateExecutor(name string) (pkgtemplate.TemplateExecutor, error) {
+	if name == manifestValues {
+		return &ValuesFileTemplate{}, nil
+	}
+	return plugins.NewManifestTemplate(manifestFs, TypePluginMonitoring, name, toTemplateData, template.FuncMap{"indent": indent})
+}
+
+// Render renders the named manifest with the config
+func Render(c Config, name string) ([]byte, error) {
+	tmpl, err := newMonitoringTemplateExecutor(name)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.RenderManifest(tmpl, c)
+}
@@ -0,0 +1,86 @@
+package monitoring
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+)
+
+// ValuesFileTemplate writes kube-prometheus-stack's values, the embedded values.yaml holds static values
+// and values from Config are set on top of it, all of them are marshaled by yaml so no escaping is needed.
+type ValuesFileTemplate struct {
+	values map[string]interface{}
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &ValuesFileTemplate{}
+)
+
+func (t *ValuesFileTemplate) Filename() string {
+	return TypePluginMonitoring.String() + "-" + manifestValues
+}
+
+func (t *ValuesFileTemplate) Execute(w io.Writer) error {
+	blob, err := yaml.Marshal(t.values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(blob)
+	return err
+}
+
+func (t *ValuesFileTemplate) Populate(v interface{}) error {
+	c, isConfig := v.(Config)
+	if !isConfig {
+		return fmt.Errorf("not a monitoring Config")
+	}
+	blob, err := manifestFs.ReadFile("manifests/" + manifestValues)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(blob, &values); err != nil {
+		return err
+	}
+	setValue(values, []string{"prometheus", "prometheusSpec", "retention"}, c.Retention)
+	if c.StorageSize != "" {
+		pvcSpec := map[string]interface{}{
+			"accessModes": []string{"ReadWriteOnce"},
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{"storage": c.StorageSize},
+			},
+		}
+		if c.StorageClass != "" {
+			pvcSpec["storageClassName"] = c.StorageClass
+		}
+		setValue(values, []string{"prometheus", "prometheusSpec", "storageSpec", "volumeClaimTemplate", "spec"}, pvcSpec)
+	}
+	setValue(values, []string{"grafana", "adminPassword"}, c.GrafanaPassword)
+	persistence := map[string]interface{}{"enabled": c.GrafanaStorageSize != ""}
+	if c.GrafanaStorageSize != "" {
+		persistence["size"] = c.GrafanaStorageSize
+		if c.StorageClass != "" {
+			persistence["storageClassName"] = c.StorageClass
+		}
+	}
+	setValue(values, []string{"grafana", "persistence"}, persistence)
+	setValue(values, []string{"grafana", "sidecar", "dashboards", "label"}, dashboardLabel)
+	t.values = values
+	return nil
+}
+
+// setValue sets v at path, intermediate maps are created if missing
+func setValue(values map[string]interface{}, path []string, v interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, isMap := values[key].(map[string]interface{})
+		if !isMap {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = v
+}
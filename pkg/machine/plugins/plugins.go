@@ -1,18 +1,22 @@
 package plugins
 
 import (
-	"errors"
-	"path/filepath"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/log"
 
-	kfmanifests "github.com/footprintai/multikf/kfmanifests"
 	"github.com/footprintai/multikf/pkg/machine"
-	kubeflowplugin "github.com/footprintai/multikf/pkg/machine/plugins/kubeflow"
-	"github.com/footprintai/multikf/pkg/template"
-	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+	"github.com/footprintai/multikf/pkg/machine/plugins/source"
 )
 
 type TypePlugin string
 
+func (t TypePlugin) String() string {
+	return string(t)
+}
+
 const (
 	TypePluginKubeflow TypePlugin = "kubeflow"
 )
@@ -23,31 +27,22 @@ func (t TypePluginVersion) String() string {
 	return string(t)
 }
 
-type templateMakerFunc func() template.TemplateExecutor
-
-var (
-	noVersion             = NewTypePluginVersion("v0.0.0")
-	typePluginVersionKF14 = NewTypePluginVersion("v1.4")
-)
-
 func NewTypePluginVersion(s string) TypePluginVersion {
 	return TypePluginVersion(s)
 }
 
-func kubeflowPluginVersionTemplate(s TypePluginVersion) (TypePluginVersion, template.TemplateExecutor) {
-	version := s.String()
-	manifests, err := kfmanifests.GetVersion(version)
-	if err != nil {
-		return noVersion, nil
-	}
-	return s, kubeflowplugin.NewKubeflowTemplateExecutor(kfmanifests.VersionBaseFileName(version), manifests)
-}
-
+// Plugin is a request to install/remove a plugin with a specific version,
+// an empty version stands for the default (latest) version of the plugin.
 type Plugin interface {
 	PluginType() TypePlugin
 	PluginVersion() TypePluginVersion
 }
 
+// ParamsGetter is implemented by plugins carrying plugin specific parameters, e.g. password=xxx
+type ParamsGetter interface {
+	GetParams() map[string]string
+}
+
 type TypeHostFilePath string
 
 func (t TypeHostFilePath) String() string {
@@ -58,67 +53,211 @@ func NewTypeHostFilePath(s string) TypeHostFilePath {
 	return TypeHostFilePath(s)
 }
 
-func generatePluginsManifestsMapping(m machine.MachineCURD, dumpToFile bool, plugins ...Plugin) (map[Plugin]template.TemplateExecutor, error) {
-	pluginAndTmpls := map[Plugin]template.TemplateExecutor{}
-	for _, plugin := range plugins {
-		switch plugin.PluginType() {
-		case TypePluginKubeflow:
-			// handle kubeflow plugins
-			_, tmpl := kubeflowPluginVersionTemplate(plugin.PluginVersion())
-			if tmpl == nil {
-				return nil, errors.New("plugins: no version found")
-			}
-			pluginAndTmpls[plugin] = tmpl
-		default:
-			return nil, errors.New("plugins: no available plugins")
+// NewPlugin creates a plugin request with parameters
+func NewPlugin(t TypePlugin, version TypePluginVersion, params map[string]string) Plugin {
+	if params == nil {
+		params = map[string]string{}
+	}
+	return &plugin{pluginType: t, version: version, params: params}
+}
+
+type plugin struct {
+	pluginType TypePlugin
+	version    TypePluginVersion
+	params     map[string]string
+}
+
+var (
+	_ Plugin       = &plugin{}
+	_ ParamsGetter = &plugin{}
+)
+
+func (p *plugin) PluginType() TypePlugin {
+	return p.pluginType
+}
+
+func (p *plugin) PluginVersion() TypePluginVersion {
+	return p.version
+}
+
+func (p *plugin) GetParams() map[string]string {
+	return p.params
+}
+
+// GetParam returns the plugin's parameter if it carries one, otherwise defaultValue is returned
+func GetParam(p Plugin, key string, defaultValue string) string {
+	if getter, ok := p.(ParamsGetter); ok {
+		if v, found := getter.GetParams()[key]; found {
+			return v
 		}
 	}
-	if dumpToFile {
-		memFs := templatefs.NewMemoryFilesFs()
-		for plugin, tmpl := range pluginAndTmpls {
-			if err := memFs.Generate(plugin, tmpl); err != nil {
-				return nil, err
-			}
+	return defaultValue
+}
+
+// ListParam encodes items as a single parameter value, items could contain any character including comma
+func ListParam(items ...string) string {
+	blob, _ := json.Marshal(items)
+	return string(blob)
+}
+
+// GetListParam returns items encoded by ListParam, a plain value (e.g. from --set key=a,b)
+// is treated as a comma delimited list.
+func GetListParam(p Plugin, key string) ([]string, error) {
+	v := strings.TrimSpace(GetParam(p, key, ""))
+	if strings.HasPrefix(v, "[") {
+		var items []string
+		if err := json.Unmarshal([]byte(v), &items); err != nil {
+			return nil, fmt.Errorf("plugins: invalid list parameter %s, err:%+v", key, err)
 		}
-		// TODO: check whether we want to overwrite exsiting or not
-		if err := templatefs.NewFolder(m.HostDir()).DumpFiles(true, memFs.FS()); err != nil {
-			return nil, err
+		return items, nil
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
-	return pluginAndTmpls, nil
+	return items, nil
 }
 
-func AddPlugins(m machine.MachineCURD, plugins ...Plugin) error {
-	var err error
-	pluginAndTmpls, err := generatePluginsManifestsMapping(m, true, plugins...)
-	if err != nil {
-		return nil
+// SourceSupporter is implemented by installers which could load manifests from a source (see source.ParamLocation)
+// instead of manifests embedded in multikf, any version is accepted with a source.
+type SourceSupporter interface {
+	SupportsSource() bool
+}
+
+// withDefaultVersion fills the default version of the installer if the plugin has no version specified,
+// and rejects versions not supported by the installer.
+func withDefaultVersion(installer Installer, p Plugin) (Plugin, error) {
+	if GetParam(p, source.ParamLocation, "") != "" {
+		if supporter, ok := installer.(SourceSupporter); !ok || !supporter.SupportsSource() {
+			return nil, fmt.Errorf("plugins: %s doesn't support loading manifests from a source", p.PluginType())
+		}
+		if p.PluginVersion() == "" {
+			return nil, fmt.Errorf("plugins: version of %s is required with a source", p.PluginType())
+		}
+		return p, nil
 	}
-	for plugin, tmpl := range pluginAndTmpls {
-		if plugin.PluginType() == TypePluginKubeflow {
-			err = m.GetKubeCli().InstallKubeflow(m.GetKubeConfig(), filepath.Join(m.HostDir(), tmpl.Filename()))
-			if err != nil {
-				return err
-			}
+	versions := installer.Versions()
+	if p.PluginVersion() == "" {
+		if len(versions) == 0 {
+			return p, nil
+		}
+		var params map[string]string
+		if getter, ok := p.(ParamsGetter); ok {
+			params = getter.GetParams()
+		}
+		return NewPlugin(p.PluginType(), versions[0], params), nil
+	}
+	if len(versions) == 0 {
+		return p, nil
+	}
+	for _, version := range versions {
+		if version == p.PluginVersion() {
+			return p, nil
 		}
 	}
+	return nil, fmt.Errorf("plugins: version %s of %s is not supported, available versions: %s", p.PluginVersion(), p.PluginType(), versions)
+}
+
+// AddPlugins installs plugins in dependency order, conflicts and missing dependencies are reported before anything is installed
+func AddPlugins(logger log.Logger, m machine.MachineCURD, plugins ...Plugin) error {
+	var nodes []pluginNode
+	for _, p := range plugins {
+		installer, err := NewInstaller(p.PluginType(), logger)
+		if err != nil {
+			return err
+		}
+		if p, err = withDefaultVersion(installer, p); err != nil {
+			return err
+		}
+		nodes = append(nodes, pluginNode{p: p, installer: installer})
+	}
+	state, err := machine.LoadMachineState(m.HostDir())
 	if err != nil {
 		return err
 	}
+	installed := typesOf(installedNodes(logger, state))
+	if err := checkConflicts(nodes, installed); err != nil {
+		return err
+	}
+	if nodes, err = sortByDependencies(nodes, installed); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		p, installer := n.p, n.installer
+		logger.V(0).Infof("plugins: install %s (%s) on %s\n", p.PluginType(), p.PluginVersion(), m.Name())
+		installErr := installer.Install(m, p)
+		if err := recordPlugin(m, installer, p, installErr); err != nil {
+			logger.Warnf("plugins: record %s on %s failed, err:%+v\n", p.PluginType(), m.Name(), err)
+		}
+		if installErr != nil {
+			return installErr
+		}
+	}
 	return nil
 }
 
-func RemovePlugins(m machine.MachineCURD, plugins ...Plugin) error {
-	var err error
-	pluginAndTmpls, err := generatePluginsManifestsMapping(m, false, plugins...)
+// RemovePlugins uninstalls plugins in reverse dependency order and waits until namespaces they own are deleted,
+// plugins still required by remaining plugins are rejected before anything is uninstalled.
+func RemovePlugins(logger log.Logger, m machine.MachineCURD, plugins ...Plugin) error {
+	var nodes []pluginNode
+	for _, p := range plugins {
+		installer, err := NewInstaller(p.PluginType(), logger)
+		if err != nil {
+			return err
+		}
+		if p, err = withRecorded(m, installer, p); err != nil {
+			return err
+		}
+		if p, err = withDefaultVersion(installer, p); err != nil {
+			return err
+		}
+		nodes = append(nodes, pluginNode{p: p, installer: installer})
+	}
+	state, err := machine.LoadMachineState(m.HostDir())
 	if err != nil {
 		return err
 	}
-	for plugin, tmpl := range pluginAndTmpls {
-		if plugin.PluginType() == TypePluginKubeflow {
-			err = m.GetKubeCli().RemoveKubeflow(m.GetKubeConfig(), filepath.Join(m.HostDir(), tmpl.Filename()))
+	removing := map[string]bool{}
+	for _, n := range nodes {
+		removing[n.p.PluginType().String()+"/"+instanceName(n.installer, n.p)] = true
+	}
+	var remaining []pluginNode
+	for _, n := range installedNodes(logger, state) {
+		if !removing[n.p.PluginType().String()+"/"+instanceName(n.installer, n.p)] {
+			remaining = append(remaining, n)
+		}
+	}
+	if err := checkRequiredBy(nodes, remaining); err != nil {
+		return err
+	}
+	// dependencies outside of the removal are satisfied already
+	satisfied := map[TypePlugin]bool{}
+	for _, n := range nodes {
+		for _, dependency := range n.dependencies() {
+			satisfied[dependency] = true
 		}
 	}
-	return err
-
+	if nodes, err = sortByDependencies(nodes, satisfied); err != nil {
+		return err
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		p, installer := nodes[i].p, nodes[i].installer
+		namespaces := nodes[i].ownedNamespaces(m)
+		logger.V(0).Infof("plugins: uninstall %s (%s) from %s\n", p.PluginType(), p.PluginVersion(), m.Name())
+		if err := installer.Uninstall(m, p); err != nil {
+			return err
+		}
+		if err := unrecordPlugin(m, installer, p); err != nil {
+			logger.Warnf("plugins: unrecord %s on %s failed, err:%+v\n", p.PluginType(), m.Name(), err)
+		}
+		if len(namespaces) > 0 {
+			logger.V(0).Infof("plugins: wait for namespaces %s to be deleted\n", strings.Join(namespaces, ","))
+			if err := m.GetKubeCli().WaitNamespacesDeleted(m.GetKubeConfig(), namespaces, namespaceDeletionTimeout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
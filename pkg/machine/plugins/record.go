@@ -0,0 +1,164 @@
+package plugins
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+const (
+	// RecordStatusInstalled is recorded when the installation succeeded
+	RecordStatusInstalled = "installed"
+	// RecordStatusFailed is recorded when the installation failed
+	RecordStatusFailed = "failed"
+)
+
+// SecretParamsGetter is implemented by installers whose parameters carry secrets,
+// those parameters are never persisted in the machine state.
+type SecretParamsGetter interface {
+	SecretParams() []string
+}
+
+// InstanceNamer is implemented by installers which could be installed multiple times on a machine, e.g. helm releases
+type InstanceNamer interface {
+	InstanceName(p Plugin) string
+}
+
+// PathParamsGetter is implemented by installers whose parameters are local paths,
+// those parameters are persisted as absolute paths as later upgrades and removals may run in another working directory.
+type PathParamsGetter interface {
+	PathParams() []string
+}
+
+func instanceName(installer Installer, p Plugin) string {
+	if namer, ok := installer.(InstanceNamer); ok {
+		return namer.InstanceName(p)
+	}
+	return ""
+}
+
+// recordableParams returns a copy of the plugin's parameters without secrets
+func recordableParams(installer Installer, p Plugin) map[string]string {
+	getter, ok := p.(ParamsGetter)
+	if !ok || len(getter.GetParams()) == 0 {
+		return nil
+	}
+	secrets := map[string]bool{}
+	if secretGetter, ok := installer.(SecretParamsGetter); ok {
+		for _, key := range secretGetter.SecretParams() {
+			secrets[key] = true
+		}
+	}
+	paths := map[string]bool{}
+	if pathGetter, ok := installer.(PathParamsGetter); ok {
+		for _, key := range pathGetter.PathParams() {
+			paths[key] = true
+		}
+	}
+	params := map[string]string{}
+	for k, v := range getter.GetParams() {
+		if secrets[k] {
+			continue
+		}
+		if paths[k] {
+			v = absolutePath(v)
+		}
+		params[k] = v
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// absolutePath resolves a local path against the working directory, urls are kept as they are
+func absolutePath(path string) string {
+	if path == "" || strings.Contains(path, "://") {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// recordPlugin persists the installation result of the plugin into the machine state
+func recordPlugin(m machine.MachineCURD, installer Installer, p Plugin, installErr error) error {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return err
+	}
+	status := RecordStatusInstalled
+	if installErr != nil {
+		status = RecordStatusFailed
+	}
+	state.PutPlugin(machine.InstalledPlugin{
+		Name:        p.PluginType().String(),
+		Instance:    instanceName(installer, p),
+		Version:     p.PluginVersion().String(),
+		Params:      recordableParams(installer, p),
+		InstalledAt: time.Now(),
+		Status:      status,
+	})
+	return state.Save(m.HostDir())
+}
+
+// unrecordPlugin removes the plugin from the machine state
+func unrecordPlugin(m machine.MachineCURD, installer Installer, p Plugin) error {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return err
+	}
+	state.RemovePlugin(p.PluginType().String(), instanceName(installer, p))
+	return state.Save(m.HostDir())
+}
+
+// withRecorded fills the version and parameters recorded in the machine state if the plugin doesn't specify them,
+// so a plugin could be removed with the same settings it was installed with.
+func withRecorded(m machine.MachineCURD, installer Installer, p Plugin) (Plugin, error) {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return nil, err
+	}
+	recorded := state.FindPlugin(p.PluginType().String(), instanceName(installer, p))
+	if recorded == nil {
+		return p, nil
+	}
+	version := p.PluginVersion()
+	if version == "" {
+		version = NewTypePluginVersion(recorded.Version)
+	}
+	params := map[string]string{}
+	for k, v := range recorded.Params {
+		params[k] = v
+	}
+	if getter, ok := p.(ParamsGetter); ok {
+		for k, v := range getter.GetParams() {
+			params[k] = v
+		}
+	}
+	return NewPlugin(p.PluginType(), version, params), nil
+}
+
+// InstalledPlugins returns plugins recorded in the machine state
+func InstalledPlugins(m machine.MachineCURD) ([]machine.InstalledPlugin, error) {
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return nil, err
+	}
+	return state.Plugins, nil
+}
+
+// FormatParams formats params as sorted k=v pairs joined by comma
+func FormatParams(params map[string]string) string {
+	pairs := make([]string, 0, len(params))
+	for k, v := range params {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,179 @@
+package plugins
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins/source"
+)
+
+type fakeMachine struct {
+	machine.MachineCURD
+	hostDir string
+}
+
+func (f fakeMachine) Name() string    { return "fake" }
+func (f fakeMachine) HostDir() string { return f.hostDir }
+
+type fakeSecretInstaller struct {
+	fakeInstaller
+	installErr      error
+	uninstalls      []TypePluginVersion
+	uninstallParams []map[string]string
+}
+
+func (f *fakeSecretInstaller) SecretParams() []string { return []string{"password"} }
+func (f *fakeSecretInstaller) InstanceName(p Plugin) string {
+	return GetParam(p, "release", "")
+}
+func (f *fakeSecretInstaller) Install(m machine.MachineCURD, p Plugin) error { return f.installErr }
+func (f *fakeSecretInstaller) Uninstall(m machine.MachineCURD, p Plugin) error {
+	f.uninstalls = append(f.uninstalls, p.PluginVersion())
+	f.uninstallParams = append(f.uninstallParams, p.(ParamsGetter).GetParams())
+	return nil
+}
+
+func TestRecordPlugins(t *testing.T) {
+	const typePluginFake TypePlugin = "fake-secret"
+	installer := &fakeSecretInstaller{}
+	assert.NoError(t, RegisterPlugin(typePluginFake, func(log.Logger) Installer { return installer }))
+	defer delete(pluginRegister, typePluginFake)
+
+	m := fakeMachine{hostDir: t.TempDir()}
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m,
+		NewPlugin(typePluginFake, "v1", map[string]string{"release": "a", "password": "s3cret", "k": "v"}),
+		NewPlugin(typePluginFake, "", map[string]string{"release": "b"}),
+	))
+	installed, err := InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.Len(t, installed, 2)
+	assert.EqualValues(t, "fake-secret/a@v1", installed[0].String())
+	assert.EqualValues(t, map[string]string{"release": "a", "k": "v"}, installed[0].Params)
+	assert.EqualValues(t, RecordStatusInstalled, installed[0].Status)
+	assert.False(t, installed[0].InstalledAt.IsZero())
+	assert.EqualValues(t, "v2", installed[1].Version)
+	assert.EqualValues(t, "k=v,release=a", FormatParams(installed[0].Params))
+
+	// failed installation is recorded as well
+	installer.installErr = errors.New("boom")
+	assert.Error(t, AddPlugins(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", map[string]string{"release": "a"})))
+	installed, err = InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.Len(t, installed, 2)
+	assert.EqualValues(t, RecordStatusFailed, installed[0].Status)
+	assert.EqualValues(t, "v2", installed[0].Version)
+
+	// remove defaults to the recorded version and parameters
+	installer.installErr = nil
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v1", map[string]string{"release": "a", "k": "v"})))
+	assert.ErrorContains(t, RemovePlugins(log.NoopLogger{}, m, NewPlugin(typePluginFake, "", map[string]string{"release": "a", source.ParamLocation: "/kf"})), "doesn't support loading manifests from a source")
+	assert.NoError(t, RemovePlugins(log.NoopLogger{}, m, NewPlugin(typePluginFake, "", map[string]string{"release": "a"})))
+	assert.EqualValues(t, []TypePluginVersion{"v1"}, installer.uninstalls)
+	assert.EqualValues(t, []map[string]string{{"release": "a", "k": "v"}}, installer.uninstallParams)
+	installed, err = InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.Len(t, installed, 1)
+	assert.EqualValues(t, "b", installed[0].Instance)
+}
+
+type fakePathInstaller struct {
+	fakeInstaller
+}
+
+func (f *fakePathInstaller) PathParams() []string { return []string{"overlay", "source"} }
+
+func TestRecordableParamsAbsolutePaths(t *testing.T) {
+	abs, err := filepath.Abs("myoverlay")
+	assert.NoError(t, err)
+	p := NewPlugin("fake", "v1", map[string]string{"overlay": "./myoverlay", "source": "https://example.com/kf.tar.gz", "k": "./v"})
+	assert.EqualValues(t, map[string]string{"overlay": abs, "source": "https://example.com/kf.tar.gz", "k": "./v"}, recordableParams(&fakePathInstaller{}, p))
+}
+
+func TestWithRecorded(t *testing.T) {
+	installer := &fakeSecretInstaller{}
+	m := fakeMachine{hostDir: t.TempDir()}
+
+	// nothing recorded, the plugin is returned as is
+	p := NewPlugin("fake-secret", "", map[string]string{"release": "a"})
+	got, err := withRecorded(m, installer, p)
+	assert.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	state := &machine.MachineState{}
+	state.PutPlugin(machine.InstalledPlugin{Name: "fake-secret", Instance: "a", Version: "v1", Params: map[string]string{"release": "a", "k": "v", "x": "1"}})
+	assert.NoError(t, state.Save(m.HostDir()))
+
+	// recorded version and params are filled, specified ones win
+	got, err = withRecorded(m, installer, NewPlugin("fake-secret", "", map[string]string{"release": "a", "x": "2"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v1", got.PluginVersion())
+	assert.EqualValues(t, map[string]string{"release": "a", "k": "v", "x": "2"}, got.(ParamsGetter).GetParams())
+
+	got, err = withRecorded(m, installer, NewPlugin("fake-secret", "v2", map[string]string{"release": "a"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v2", got.PluginVersion())
+	assert.EqualValues(t, map[string]string{"release": "a", "k": "v", "x": "1"}, got.(ParamsGetter).GetParams())
+
+	// another instance has no record
+	got, err = withRecorded(m, installer, NewPlugin("fake-secret", "", map[string]string{"release": "b"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "", got.PluginVersion())
+	assert.EqualValues(t, map[string]string{"release": "b"}, got.(ParamsGetter).GetParams())
+}
+
+type fakeUpgrader struct {
+	fakeSecretInstaller
+	from, to   []Plugin
+	upgradeErr error
+}
+
+func (f *fakeUpgrader) Upgrade(m machine.MachineCURD, from Plugin, to Plugin, opts UpgradeOptions) error {
+	f.from, f.to = append(f.from, from), append(f.to, to)
+	return f.upgradeErr
+}
+
+func TestUpgradePlugin(t *testing.T) {
+	const typePluginFake TypePlugin = "fake-upgrader"
+	installer := &fakeUpgrader{}
+	assert.NoError(t, RegisterPlugin(typePluginFake, func(log.Logger) Installer { return installer }))
+	defer delete(pluginRegister, typePluginFake)
+
+	m := fakeMachine{hostDir: t.TempDir()}
+	assert.ErrorContains(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", nil), UpgradeOptions{}), "not installed")
+
+	assert.NoError(t, AddPlugins(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v1", map[string]string{"k": "v"})))
+	assert.ErrorContains(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v1", nil), UpgradeOptions{}), "already v1")
+	assert.ErrorContains(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v3", nil), UpgradeOptions{}), "version v3 of fake-upgrader is not supported")
+	assert.ErrorContains(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", map[string]string{source.ParamLocation: "/kf"}), UpgradeOptions{}), "doesn't support loading manifests from a source")
+
+	// dry run leaves the record as is
+	assert.NoError(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", nil), UpgradeOptions{DryRun: true}))
+	installed, err := InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v1", installed[0].Version)
+
+	// failed upgrade keeps the installed version
+	installer.upgradeErr = errors.New("boom")
+	assert.Error(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", nil), UpgradeOptions{}))
+	installed, err = InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v1", installed[0].Version)
+	assert.EqualValues(t, RecordStatusFailed, installed[0].Status)
+	assert.EqualValues(t, map[string]string{"k": "v"}, installed[0].Params)
+
+	installer.upgradeErr = nil
+	assert.NoError(t, UpgradePlugin(log.NoopLogger{}, m, NewPlugin(typePluginFake, "v2", nil), UpgradeOptions{}))
+	assert.EqualValues(t, "v1", installer.from[2].PluginVersion())
+	assert.EqualValues(t, "v2", installer.to[2].PluginVersion())
+	assert.EqualValues(t, map[string]string{"k": "v"}, installer.to[2].(ParamsGetter).GetParams())
+	installed, err = InstalledPlugins(m)
+	assert.NoError(t, err)
+	assert.Len(t, installed, 1)
+	assert.EqualValues(t, "v2", installed[0].Version)
+	assert.EqualValues(t, RecordStatusInstalled, installed[0].Status)
+}
@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+// Status is the status of a plugin on a machine
+type Status string
+
+func (s Status) String() string {
+	return string(s)
+}
+
+const (
+	StatusNotInstalled Status = "not-installed"
+	StatusNotReady     Status = "not-ready"
+	StatusReady        Status = "ready"
+)
+
+// Installer manages the lifecycle of a plugin type on machines
+type Installer interface {
+	// Description is a short sentence shown in `plugin list`
+	Description() string
+	// Versions lists supported versions, the first one is the default
+	Versions() []TypePluginVersion
+	Install(m machine.MachineCURD, p Plugin) error
+	Uninstall(m machine.MachineCURD, p Plugin) error
+	Status(m machine.MachineCURD) (Status, error)
+}
+
+type FactoryFunc func(logger log.Logger) Installer
+
+var pluginRegister = map[TypePlugin]FactoryFunc{}
+
+// RegisterPlugin registers a plugin type, it is expected to be called in init() of the plugin's package
+func RegisterPlugin(t TypePlugin, fac FactoryFunc) error {
+	if _, found := pluginRegister[t]; found {
+		return fmt.Errorf("duplicated register plugin:%s\n", t)
+	}
+	pluginRegister[t] = fac
+	return nil
+}
+
+func ParseTypePlugin(s string) (TypePlugin, error) {
+	for t := range pluginRegister {
+		if s == t.String() {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("plugins: unknown plugin %q, available plugins: %s", s, ListPlugins())
+}
+
+func NewInstaller(t TypePlugin, logger log.Logger) (Installer, error) {
+	fac, found := pluginRegister[t]
+	if !found {
+		return nil, errors.New("plugins: no available plugins")
+	}
+	return fac(logger), nil
+}
+
+// ListPlugins returns registered plugin types in alphabetical order
+func ListPlugins() []TypePlugin {
+	var types []TypePlugin
+	for t := range pluginRegister {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func ForEachPlugin(iter func(t TypePlugin)) {
+	for _, t := range ListPlugins() {
+		iter(t)
+	}
+}
@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+type fakeInstaller struct{}
+
+func (f fakeInstaller) Description() string { return "fake" }
+func (f fakeInstaller) Versions() []TypePluginVersion {
+	return []TypePluginVersion{"v2", "v1"}
+}
+func (f fakeInstaller) Install(m machine.MachineCURD, p Plugin) error   { return nil }
+func (f fakeInstaller) Uninstall(m machine.MachineCURD, p Plugin) error { return nil }
+func (f fakeInstaller) Status(m machine.MachineCURD) (Status, error)    { return StatusReady, nil }
+
+func TestRegistry(t *testing.T) {
+	const typePluginFake TypePlugin = "fake"
+	assert.NoError(t, RegisterPlugin(typePluginFake, func(log.Logger) Installer { return fakeInstaller{} }))
+	defer delete(pluginRegister, typePluginFake)
+	assert.Error(t, RegisterPlugin(typePluginFake, func(log.Logger) Installer { return fakeInstaller{} }))
+
+	parsed, err := ParseTypePlugin("fake")
+	assert.NoError(t, err)
+	assert.EqualValues(t, typePluginFake, parsed)
+	_, err = ParseTypePlugin("unknown")
+	assert.Error(t, err)
+	assert.EqualValues(t, []TypePlugin{typePluginFake, TypePluginKubeflow}, ListPlugins())
+
+	installer, err := NewInstaller(typePluginFake, log.NoopLogger{})
+	assert.NoError(t, err)
+
+	// default version is filled and params are kept
+	p, err := withDefaultVersion(installer, NewPlugin(typePluginFake, "", map[string]string{"k": "v"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v2", p.PluginVersion())
+	assert.EqualValues(t, "v", GetParam(p, "k", ""))
+	assert.EqualValues(t, "default", GetParam(p, "missing", "default"))
+
+	p, err = withDefaultVersion(installer, NewPlugin(typePluginFake, "v1", nil))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "v1", p.PluginVersion())
+
+	_, err = withDefaultVersion(installer, NewPlugin(typePluginFake, "v3", nil))
+	assert.Error(t, err)
+}
+
+func TestParseDeploymentsStatus(t *testing.T) {
+	status, err := parseDeploymentsStatus([]byte(`{"items":[]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusNotInstalled, status)
+
+	status, err = parseDeploymentsStatus([]byte(`{"items":[
+{"spec":{"replicas":1},"status":{"availableReplicas":1}},
+{"spec":{},"status":{"availableReplicas":1}}
+]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusReady, status)
+
+	status, err = parseDeploymentsStatus([]byte(`{"items":[
+{"spec":{"replicas":2},"status":{"availableReplicas":1}}
+]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusNotReady, status)
+}
+
+func TestParseDaemonSetsStatus(t *testing.T) {
+	status, err := parseDaemonSetsStatus([]byte(`{"items":[]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusNotInstalled, status)
+
+	status, err = parseDaemonSetsStatus([]byte(`{"items":[{"status":{"desiredNumberScheduled":2,"numberAvailable":2}}]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusReady, status)
+
+	status, err = parseDaemonSetsStatus([]byte(`{"items":[{"status":{"desiredNumberScheduled":2,"numberAvailable":1}}]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, StatusNotReady, status)
+}
@@ -0,0 +1,351 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+const (
+	// ParamLocation is a local directory, a tarball (.tar, .tar.gz, .tgz) or an http(s) url of a tarball or a single manifest,
+	// tarballs are detected from their content so urls without an extension are accepted as well
+	ParamLocation = "source"
+	// ParamSHA256 is the sha256 of the source, which is required, see DirSHA256 for directories
+	ParamSHA256 = "source_sha256"
+
+	// CacheDirName is the folder under multikf's root dir where fetched sources are kept
+	CacheDirName = "sources"
+)
+
+var (
+	// fetchTimeout bounds fetching a url including reading its body, so a stalled server fails the fetch
+	fetchTimeout = 10 * time.Minute
+)
+
+// Source is where manifests are loaded from instead of manifests embedded in multikf
+type Source struct {
+	Location string
+	SHA256   string
+}
+
+func (s Source) Validate() error {
+	if s.Location == "" {
+		return errors.New("source: no location specified")
+	}
+	if s.SHA256 == "" {
+		return fmt.Errorf("source: %s is required for %s", ParamSHA256, s.Location)
+	}
+	if decoded, err := hex.DecodeString(s.SHA256); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("source: invalid sha256 %q, expect 64 hex characters", s.SHA256)
+	}
+	return nil
+}
+
+func (s Source) isURL() bool {
+	return strings.HasPrefix(s.Location, "http://") || strings.HasPrefix(s.Location, "https://")
+}
+
+// Fetch verifies the source against its sha256 and returns a directory containing its files.
+// Sources are copied into cacheDir/<sha256> once verified, later fetches of the same checksum use the cache.
+func Fetch(logger log.Logger, cacheDir string, s Source) (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+	s.SHA256 = strings.ToLower(s.SHA256)
+	cachedDir := filepath.Join(cacheDir, s.SHA256)
+	if info, err := os.Stat(cachedDir); err == nil && info.IsDir() {
+		logger.V(1).Infof("source: use cached %s for %s\n", cachedDir, s.Location)
+		return cachedDir, nil
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	// files are unpacked into a temporary folder first, so an interrupted fetch never leaves a partial cache
+	tmpDir, err := os.MkdirTemp(cacheDir, "."+s.SHA256+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if s.isURL() {
+		logger.V(0).Infof("source: fetch %s\n", s.Location)
+		if err := fetchURL(s, tmpDir); err != nil {
+			return "", err
+		}
+	} else {
+		info, err := os.Stat(s.Location)
+		if err != nil {
+			return "", fmt.Errorf("source: %s not found, err:%+v", s.Location, err)
+		}
+		if info.IsDir() {
+			err = copyDir(s, tmpDir)
+		} else {
+			err = unpackFile(s, s.Location, filepath.Base(s.Location), tmpDir)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := moveIntoCache(tmpDir, cachedDir); err != nil {
+		return "", err
+	}
+	return cachedDir, nil
+}
+
+// moveIntoCache renames the verified tmpDir to cachedDir, a concurrent fetch of the same checksum may have moved
+// its own copy first, which has the same content as the checksum is verified, so that one is kept.
+func moveIntoCache(tmpDir string, cachedDir string) error {
+	err := os.Rename(tmpDir, cachedDir)
+	if err == nil {
+		return nil
+	}
+	if info, statErr := os.Stat(cachedDir); statErr == nil && info.IsDir() {
+		return nil
+	}
+	return err
+}
+
+func fetchURL(s Source, dst string) error {
+	u, err := url.Parse(s.Location)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(s.Location)
+	if err != nil {
+		return fmt.Errorf("source: fetch %s failed, err:%+v", s.Location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("source: fetch %s failed, status:%s", s.Location, resp.Status)
+	}
+	downloaded, err := os.CreateTemp(filepath.Dir(dst), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(downloaded.Name())
+	_, err = io.Copy(downloaded, resp.Body)
+	if closeErr := downloaded.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return unpackFile(s, downloaded.Name(), path.Base(u.Path), dst)
+}
+
+// unpackFile verifies the file and extracts it into dst if it is a tarball, otherwise the file is copied as a yaml named after name.
+// The type is detected from the content, as urls (e.g. of release assets) don't always end with an extension.
+func unpackFile(s Source, file string, name string, dst string) error {
+	sum, err := FileSHA256(file)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, s.SHA256) {
+		return fmt.Errorf("source: sha256 mismatch of %s, expect %s, got %s", s.Location, s.SHA256, sum)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	header = header[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return untar(gz, dst)
+	case len(header) == tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:], tarMagic):
+		return untar(f, dst)
+	}
+	return writeFile(filepath.Join(dst, manifestName(name)), f, 0644)
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	// tarMagic is at offset 257 of ustar (posix and gnu) headers
+	tarMagic = []byte("ustar")
+)
+
+const tarMagicOffset = 257
+
+// manifestName returns the file name of a single manifest, which is read by ReadManifests only with a yaml extension
+func manifestName(name string) string {
+	switch ext := filepath.Ext(name); {
+	case name == "" || name == "." || name == "/":
+		return "manifest.yaml"
+	case ext == ".yaml" || ext == ".yml":
+		return name
+	}
+	return name + ".yaml"
+}
+
+func untar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("source: invalid path %q in tarball", header.Name)
+		}
+		target := filepath.Join(dst, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, 0644); err != nil {
+				return err
+			}
+		default:
+			// links and devices are not expected in manifests
+		}
+	}
+}
+
+func copyDir(s Source, dst string) error {
+	sum, err := DirSHA256(s.Location)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, s.SHA256) {
+		return fmt.Errorf("source: sha256 mismatch of %s, expect %s, got %s", s.Location, s.SHA256, sum)
+	}
+	return filepath.WalkDir(s.Location, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(s.Location, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(filepath.Join(dst, rel), f, 0644)
+	})
+}
+
+func writeFile(file string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// FileSHA256 returns the hex encoded sha256 of the file
+func FileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DirSHA256 returns the sha256 of the directory's checksum listing, which is the same as
+// `cd <dir> && find . -type f | LC_ALL=C sort | xargs sha256sum | sha256sum`
+func DirSHA256(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, "./"+filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	listing := &strings.Builder{}
+	for _, file := range files {
+		sum, err := FileSHA256(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(listing, "%s  %s\n", sum, file)
+	}
+	h := sha256.Sum256([]byte(listing.String()))
+	return hex.EncodeToString(h[:]), nil
+}
+
+// ReadManifests joins yaml files under dir in lexical order of their paths into a multi-document manifest
+func ReadManifests(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(p); ext == ".yaml" || ext == ".yml" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("source: no yaml found under %s", dir)
+	}
+	sort.Strings(files)
+	var docs []string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, strings.TrimSpace(string(content)))
+	}
+	return strings.Join(docs, "\n---\n") + "\n", nil
+}
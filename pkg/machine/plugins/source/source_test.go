@@ -0,0 +1,164 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kind/pkg/log"
+)
+
+const (
+	namespaceYaml  = "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: kubeflow\n"
+	deploymentYaml = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: dex\n  annotations:\n    password: \"[[ .DefaultSaltedPassword ]]\"\n"
+)
+
+func sha256Of(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func tarball(t *testing.T, files map[string]string, names ...string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(files[name]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestFetchURL(t *testing.T) {
+	archive := tarball(t, map[string]string{"kf/01-namespace.yaml": namespaceYaml, "kf/02-dex.yaml": deploymentYaml}, "kf/02-dex.yaml", "kf/01-namespace.yaml")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/kubeflow.tar.gz":
+			w.Write(archive)
+		case "/kubeflow.yaml", "/releases/latest":
+			w.Write([]byte(namespaceYaml))
+		case "/releases/download":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	_, err := Fetch(log.NoopLogger{}, cacheDir, Source{Location: server.URL + "/kubeflow.tar.gz"})
+	assert.ErrorContains(t, err, ParamSHA256+" is required")
+	_, err = Fetch(log.NoopLogger{}, cacheDir, Source{Location: server.URL + "/kubeflow.tar.gz", SHA256: sha256Of([]byte("tampered"))})
+	assert.ErrorContains(t, err, "sha256 mismatch")
+	_, err = Fetch(log.NoopLogger{}, cacheDir, Source{Location: server.URL + "/missing.yaml", SHA256: sha256Of(nil)})
+	assert.ErrorContains(t, err, "404")
+	entries, err := os.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "failed fetches leave nothing in the cache")
+
+	s := Source{Location: server.URL + "/kubeflow.tar.gz", SHA256: sha256Of(archive)}
+	dir, err := Fetch(log.NoopLogger{}, cacheDir, s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, filepath.Join(cacheDir, s.SHA256), dir)
+	manifest, err := ReadManifests(dir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, namespaceYaml+"---\n"+deploymentYaml, manifest)
+
+	// cached by checksum
+	requests = 0
+	_, err = Fetch(log.NoopLogger{}, cacheDir, s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, requests)
+
+	dir, err = Fetch(log.NoopLogger{}, cacheDir, Source{Location: server.URL + "/kubeflow.yaml", SHA256: sha256Of([]byte(namespaceYaml))})
+	assert.NoError(t, err)
+	manifest, err = ReadManifests(dir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, namespaceYaml, manifest)
+
+	// urls without an extension are detected from their content
+	for _, location := range []string{"/releases/download?asset=kubeflow", "/releases/latest"} {
+		content := archive
+		if location == "/releases/latest" {
+			content = []byte(namespaceYaml)
+		}
+		dir, err = Fetch(log.NoopLogger{}, t.TempDir(), Source{Location: server.URL + location, SHA256: sha256Of(content)})
+		assert.NoError(t, err, location)
+		manifest, err = ReadManifests(dir)
+		assert.NoError(t, err, location)
+		assert.Contains(t, manifest, namespaceYaml, location)
+	}
+}
+
+func TestMoveIntoCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	cachedDir := filepath.Join(cacheDir, sha256Of([]byte(namespaceYaml)))
+	for i := 0; i < 2; i++ {
+		tmpDir, err := os.MkdirTemp(cacheDir, ".fetch-")
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(tmpDir, "kubeflow.yaml"), []byte(namespaceYaml), 0644))
+		// the second one loses the race against the first one
+		assert.NoError(t, moveIntoCache(tmpDir, cachedDir))
+	}
+	content, err := os.ReadFile(filepath.Join(cachedDir, "kubeflow.yaml"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, namespaceYaml, content)
+
+	assert.Error(t, moveIntoCache(filepath.Join(cacheDir, "missing"), filepath.Join(cacheDir, "other")))
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// stalls until the client gives up
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	defer func(timeout time.Duration) { fetchTimeout = timeout }(fetchTimeout)
+	fetchTimeout = 100 * time.Millisecond
+
+	_, err := Fetch(log.NoopLogger{}, t.TempDir(), Source{Location: server.URL + "/kubeflow.yaml", SHA256: sha256Of(nil)})
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestFetchLocal(t *testing.T) {
+	srcDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "base"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "base", "namespace.yaml"), []byte(namespaceYaml), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "dex.yaml"), []byte(deploymentYaml), 0644))
+	// sha256sum listing of `find . -type f | LC_ALL=C sort | xargs sha256sum`
+	listing := sha256Of([]byte(namespaceYaml)) + "  ./base/namespace.yaml\n" + sha256Of([]byte(deploymentYaml)) + "  ./dex.yaml\n"
+	dirSum, err := DirSHA256(srcDir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, sha256Of([]byte(listing)), dirSum)
+
+	cacheDir := t.TempDir()
+	_, err = Fetch(log.NoopLogger{}, cacheDir, Source{Location: srcDir, SHA256: sha256Of(nil)})
+	assert.ErrorContains(t, err, "sha256 mismatch")
+	dir, err := Fetch(log.NoopLogger{}, cacheDir, Source{Location: srcDir, SHA256: dirSum})
+	assert.NoError(t, err)
+	manifest, err := ReadManifests(dir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, namespaceYaml+"---\n"+deploymentYaml, manifest)
+
+	archive := tarball(t, map[string]string{"../escape.yaml": namespaceYaml}, "../escape.yaml")
+	archiveFile := filepath.Join(t.TempDir(), "evil.tgz")
+	assert.NoError(t, os.WriteFile(archiveFile, archive, 0644))
+	_, err = Fetch(log.NoopLogger{}, cacheDir, Source{Location: archiveFile, SHA256: sha256Of(archive)})
+	assert.ErrorContains(t, err, "invalid path")
+
+	assert.Error(t, Source{Location: srcDir, SHA256: "abc"}.Validate())
+}
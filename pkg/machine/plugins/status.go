@@ -0,0 +1,85 @@
+package plugins
+
+import (
+	"encoding/json"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+type deploymentList struct {
+	Items []struct {
+		Spec struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+		Status struct {
+			AvailableReplicas int `json:"availableReplicas"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parseDeploymentsStatus reduces the output of `kubectl get deployments -o json` into a plugin status
+func parseDeploymentsStatus(blob []byte) (Status, error) {
+	list := &deploymentList{}
+	if err := json.Unmarshal(blob, list); err != nil {
+		return "", err
+	}
+	if len(list.Items) == 0 {
+		return StatusNotInstalled, nil
+	}
+	for _, item := range list.Items {
+		replicas := 1
+		if item.Spec.Replicas != nil {
+			replicas = *item.Spec.Replicas
+		}
+		if item.Status.AvailableReplicas < replicas {
+			return StatusNotReady, nil
+		}
+	}
+	return StatusReady, nil
+}
+
+// DeploymentsStatus reports a plugin as ready when all deployments in the namespace are available,
+// and as not installed when there is no deployment in the namespace.
+func DeploymentsStatus(m machine.MachineCURD, namespace string) (Status, error) {
+	out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "deployments", "-n", namespace, "-o", "json")
+	if err != nil {
+		return "", err
+	}
+	return parseDeploymentsStatus(out)
+}
+
+type daemonSetList struct {
+	Items []struct {
+		Status struct {
+			DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+			NumberAvailable        int `json:"numberAvailable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parseDaemonSetsStatus reduces the output of `kubectl get daemonsets -o json` into a plugin status
+func parseDaemonSetsStatus(blob []byte) (Status, error) {
+	list := &daemonSetList{}
+	if err := json.Unmarshal(blob, list); err != nil {
+		return "", err
+	}
+	if len(list.Items) == 0 {
+		return StatusNotInstalled, nil
+	}
+	for _, item := range list.Items {
+		if item.Status.DesiredNumberScheduled == 0 || item.Status.NumberAvailable < item.Status.DesiredNumberScheduled {
+			return StatusNotReady, nil
+		}
+	}
+	return StatusReady, nil
+}
+
+// DaemonSetsStatus reports a plugin as ready when all daemonsets in the namespace are available on every scheduled node,
+// and as not installed when there is no daemonset in the namespace.
+func DaemonSetsStatus(m machine.MachineCURD, namespace string) (Status, error) {
+	out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "daemonsets", "-n", namespace, "-o", "json")
+	if err != nil {
+		return "", err
+	}
+	return parseDaemonSetsStatus(out)
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+	"github.com/footprintai/multikf/pkg/machine/plugins/monitoring"
+)
+
+const (
+	TypePluginStorage plugins.TypePlugin = "storage"
+
+	// ParamProvisioner is the provisioner of the storageclass, either local-path (default) or shared-path
+	ParamProvisioner = "provisioner"
+	// ParamPath is the directory inside nodes where volumes are stored,
+	// it defaults to /var/local-path-provisioner which is where --use_localpath mounts the host dir
+	ParamPath = "path"
+	// ParamStorageClass is the name of the storageclass (default: multikf-local-path or multikf-shared-path)
+	ParamStorageClass = "storage_class"
+	// ParamDefault marks the storageclass as the default one (default: true)
+	ParamDefault = "default"
+	// ParamReclaimPolicy is either Delete (default) or Retain
+	ParamReclaimPolicy = "reclaim_policy"
+)
+
+// Provisioner provisions persistent volumes of the storageclass
+type Provisioner string
+
+const (
+	// ProvisionerLocalPath stores volumes in the node where the pod is scheduled first, volumes are bound to that node
+	ProvisionerLocalPath Provisioner = "local-path"
+	// ProvisionerSharedPath stores volumes in a directory shared by every node (the host dir of --use_localpath),
+	// volumes are ReadWriteMany and not bound to any node, so pods could be rescheduled to other nodes with their data.
+	ProvisionerSharedPath Provisioner = "shared-path"
+)
+
+func (p Provisioner) String() string {
+	return string(p)
+}
+
+func ListProvisionerString() []string {
+	return []string{ProvisionerLocalPath.String(), ProvisionerSharedPath.String()}
+}
+
+func ParseProvisioner(s string) (Provisioner, error) {
+	for _, p := range []Provisioner{ProvisionerLocalPath, ProvisionerSharedPath} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("storage: unsupported provisioner %q, available provisioners: %s", s, strings.Join(ListProvisionerString(), ","))
+}
+
+const (
+	// DefaultClassAnnotation marks a storageclass as the default one
+	DefaultClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+	// KindStorageClass is kind's built-in storageclass, it is the default one again once the plugin is uninstalled
+	KindStorageClass = "standard"
+
+	localPathNamespace        = "multikf-local-path"
+	localPathProvisionerName  = "multikf.footprint-ai.com/local-path"
+	localPathStorageClass     = "multikf-local-path"
+	sharedPathProvisionerName = "multikf.footprint-ai.com/shared-path"
+	sharedPathStorageClass    = "multikf-shared-path"
+	localPathDefaultPath      = "/var/local-path-provisioner"
+	localPathRolloutResource  = "deployment/local-path-provisioner"
+)
+
+var (
+	storageRolloutTimeout = 5 * time.Minute
+)
+
+func init() {
+	plugins.RegisterPlugin(TypePluginStorage, newStorageInstaller)
+}
+
+func newStorageInstaller(logger log.Logger) plugins.Installer {
+	return &storageInstaller{logger: logger}
+}
+
+type storageInstaller struct {
+	logger log.Logger
+}
+
+var (
+	_ plugins.Installer       = &storageInstaller{}
+	_ plugins.NamespacesOwner = &storageInstaller{}
+	_ plugins.Precursor       = &storageInstaller{}
+)
+
+func (s *storageInstaller) Description() string {
+	return "local-path or shared-path provisioner as the default storageclass, plugin version stands for the local-path-provisioner version"
+}
+
+func (s *storageInstaller) Versions() []plugins.TypePluginVersion {
+	return []plugins.TypePluginVersion{"v0.0.30", "v0.0.28"}
+}
+
+func configFromPlugin(p plugins.Plugin) (Config, error) {
+	provisioner, err := ParseProvisioner(plugins.GetParam(p, ParamProvisioner, ProvisionerLocalPath.String()))
+	if err != nil {
+		return Config{}, err
+	}
+	defaultStorageClass := localPathStorageClass
+	if provisioner == ProvisionerSharedPath {
+		defaultStorageClass = sharedPathStorageClass
+	}
+	c := Config{
+		Provisioner:   provisioner,
+		Version:       p.PluginVersion().String(),
+		Path:          plugins.GetParam(p, ParamPath, localPathDefaultPath),
+		StorageClass:  plugins.GetParam(p, ParamStorageClass, defaultStorageClass),
+		ReclaimPolicy: plugins.GetParam(p, ParamReclaimPolicy, "Delete"),
+	}
+	return c, c.Validate()
+}
+
+// generateManifest renders the local-path manifest under the machine's HostDir
+func (s *storageInstaller) generateManifest(m machine.MachineCURD, c Config) (string, error) {
+	tmpl, err := newStorageTemplateExecutor(manifestLocalPath)
+	if err != nil {
+		return "", err
+	}
+	files, err := plugins.GenerateManifests(m.HostDir(), c, tmpl)
+	if err != nil {
+		return "", err
+	}
+	return files[0], nil
+}
+
+func (s *storageInstaller) Install(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	manifestFile, err := s.generateManifest(m, c)
+	if err != nil {
+		return err
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	s.logger.V(0).Infof("storage: install %s provisioner %s under %s\n", c.Provisioner, c.Version, c.Path)
+	if err := kubecli.Apply(kubeConfig, manifestFile); err != nil {
+		return err
+	}
+	if err := kubecli.RolloutStatus(kubeConfig, localPathNamespace, localPathRolloutResource, storageRolloutTimeout); err != nil {
+		return err
+	}
+	if plugins.GetParam(p, ParamDefault, "true") == "false" {
+		return nil
+	}
+	return SetDefaultStorageClass(s.logger, m, c.StorageClass)
+}
+
+func (s *storageInstaller) Uninstall(m machine.MachineCURD, p plugins.Plugin) error {
+	c, err := configFromPlugin(p)
+	if err != nil {
+		return err
+	}
+	manifestFile, err := s.generateManifest(m, c)
+	if err != nil {
+		return err
+	}
+	if err := m.GetKubeCli().Delete(m.GetKubeConfig(), manifestFile); err != nil {
+		return err
+	}
+	if plugins.GetParam(p, ParamDefault, "true") == "false" {
+		return nil
+	}
+	return s.restoreDefaultStorageClass(m)
+}
+
+// restoreDefaultStorageClass marks kind's standard storageclass as the default one again,
+// which is skipped if it has been removed from the cluster.
+func (s *storageInstaller) restoreDefaultStorageClass(m machine.MachineCURD) error {
+	names, err := listStorageClasses(m)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == KindStorageClass {
+			return SetDefaultStorageClass(s.logger, m, KindStorageClass)
+		}
+	}
+	s.logger.Warnf("storage: storageclass %s not found, no default storageclass on %s\n", KindStorageClass, m.Name())
+	return nil
+}
+
+// Precedes returns plugins creating pvcs, so the storageclass is the default one before their pvcs are bound
+// and is removed after their pvcs are deleted.
+func (s *storageInstaller) Precedes(p plugins.Plugin) []plugins.TypePlugin {
+	return []plugins.TypePlugin{plugins.TypePluginKubeflow, monitoring.TypePluginMonitoring}
+}
+
+// OwnedNamespaces returns local-path's namespace which is deleted along with the manifest
+func (s *storageInstaller) OwnedNamespaces(m machine.MachineCURD, p plugins.Plugin) []string {
+	return []string{localPathNamespace}
+}
+
+func (s *storageInstaller) Status(m machine.MachineCURD) (plugins.Status, error) {
+	return plugins.DeploymentsStatus(m, localPathNamespace)
+}
+
+// SetDefaultStorageClass marks the storageclass as the default one and unmarks all others
+func SetDefaultStorageClass(logger log.Logger, m machine.MachineCURD, name string) error {
+	names, err := listStorageClasses(m)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, existing := range names {
+		if existing == name {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("storage: storageclass %s not found, available storageclasses: %s", name, strings.Join(names, ","))
+	}
+	kubecli, kubeConfig := m.GetKubeCli(), m.GetKubeConfig()
+	for _, existing := range names {
+		isDefault := strconv.FormatBool(existing == name)
+		if _, err := kubecli.Output(kubeConfig, "annotate", "storageclass", existing, DefaultClassAnnotation+"="+isDefault, "--overwrite"); err != nil {
+			return err
+		}
+	}
+	logger.V(0).Infof("storage: storageclass %s is now the default one\n", name)
+	return nil
+}
+
+func listStorageClasses(m machine.MachineCURD) ([]string, error) {
+	out, err := m.GetKubeCli().Output(m.GetKubeConfig(), "get", "storageclass", "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
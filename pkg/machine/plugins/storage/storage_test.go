@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+func TestConfigFromPlugin(t *testing.T) {
+	c, err := configFromPlugin(plugins.NewPlugin(TypePluginStorage, "v0.0.30", nil))
+	assert.NoError(t, err)
+	assert.EqualValues(t, Config{
+		Provisioner:   ProvisionerLocalPath,
+		Version:       "v0.0.30",
+		Path:          "/var/local-path-provisioner",
+		StorageClass:  "multikf-local-path",
+		ReclaimPolicy: "Delete",
+	}, c)
+
+	c, err = configFromPlugin(plugins.NewPlugin(TypePluginStorage, "v0.0.30", map[string]string{ParamProvisioner: "shared-path"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, ProvisionerSharedPath, c.Provisioner)
+	assert.EqualValues(t, "multikf-shared-path", c.StorageClass)
+
+	for _, invalid := range []map[string]string{
+		{ParamProvisioner: "nfs"},
+		{ParamProvisioner: "longhorn"},
+		{ParamPath: "relative/dir"},
+		{ParamStorageClass: "Not_Valid"},
+		{ParamReclaimPolicy: "Recycle"},
+	} {
+		_, err := configFromPlugin(plugins.NewPlugin(TypePluginStorage, "v0.0.30", invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRenderLocalPath(t *testing.T) {
+	blob, err := Render(Config{
+		Provisioner:   ProvisionerLocalPath,
+		Version:       "v0.0.30",
+		Path:          "/data/volumes",
+		StorageClass:  "fast",
+		ReclaimPolicy: "Retain",
+	}, manifestLocalPath)
+	assert.NoError(t, err)
+	var (
+		storageClass map[string]interface{}
+		configMap    struct {
+			Data map[string]string `json:"data"`
+		}
+	)
+	for _, doc := range strings.Split(string(blob), "\n---\n") {
+		obj := map[string]interface{}{}
+		assert.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+		switch obj["kind"] {
+		case "StorageClass":
+			storageClass = obj
+		case "ConfigMap":
+			assert.NoError(t, yaml.Unmarshal([]byte(doc), &configMap))
+		}
+	}
+	assert.EqualValues(t, "fast", storageClass["metadata"].(map[string]interface{})["name"])
+	assert.EqualValues(t, localPathProvisionerName, storageClass["provisioner"])
+	assert.EqualValues(t, "Retain", storageClass["reclaimPolicy"])
+	assert.EqualValues(t, "WaitForFirstConsumer", storageClass["volumeBindingMode"])
+
+	pathConfig := struct {
+		NodePathMap []struct {
+			Paths []string `json:"paths"`
+		} `json:"nodePathMap"`
+	}{}
+	assert.NoError(t, json.Unmarshal([]byte(configMap.Data["config.json"]), &pathConfig))
+	assert.EqualValues(t, []string{"/data/volumes"}, pathConfig.NodePathMap[0].Paths)
+}
+
+func TestRenderSharedPath(t *testing.T) {
+	blob, err := Render(Config{
+		Provisioner:   ProvisionerSharedPath,
+		Version:       "v0.0.30",
+		Path:          "/var/local-path-provisioner",
+		StorageClass:  "multikf-shared-path",
+		ReclaimPolicy: "Delete",
+	}, manifestLocalPath)
+	assert.NoError(t, err)
+	var (
+		storageClass map[string]interface{}
+		configMap    struct {
+			Data map[string]string `json:"data"`
+		}
+	)
+	for _, doc := range strings.Split(string(blob), "\n---\n") {
+		obj := map[string]interface{}{}
+		assert.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+		switch obj["kind"] {
+		case "StorageClass":
+			storageClass = obj
+		case "ConfigMap":
+			assert.NoError(t, yaml.Unmarshal([]byte(doc), &configMap))
+		}
+	}
+	assert.EqualValues(t, sharedPathProvisionerName, storageClass["provisioner"])
+	assert.EqualValues(t, "Immediate", storageClass["volumeBindingMode"])
+
+	pathConfig := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(configMap.Data["config.json"]), &pathConfig))
+	assert.EqualValues(t, map[string]interface{}{"sharedFileSystemPath": "/var/local-path-provisioner"}, pathConfig)
+}
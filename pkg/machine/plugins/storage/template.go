@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"embed"
+	"fmt"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/footprintai/multikf/pkg/machine/plugins"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+const (
+	manifestLocalPath = "local-path.yaml"
+)
+
+// Config is rendered into manifests of the storage plugin
+type Config struct {
+	Provisioner Provisioner
+	// Version is the version of local-path-provisioner
+	Version string
+	// Path is the directory inside nodes where volumes are stored
+	Path string
+	// StorageClass is the name of the storageclass
+	StorageClass string
+	// ReclaimPolicy is either Delete or Retain
+	ReclaimPolicy string
+}
+
+func (c Config) Validate() error {
+	if _, err := ParseProvisioner(c.Provisioner.String()); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(c.Path) {
+		return fmt.Errorf("storage: path %q should be an absolute path inside nodes", c.Path)
+	}
+	if errs := validation.IsDNS1123Subdomain(c.StorageClass); len(errs) > 0 {
+		return fmt.Errorf("storage: invalid storageclass %q: %s", c.StorageClass, errs)
+	}
+	switch corev1.PersistentVolumeReclaimPolicy(c.ReclaimPolicy) {
+	case corev1.PersistentVolumeReclaimDelete, corev1.PersistentVolumeReclaimRetain:
+	default:
+		return fmt.Errorf("storage: invalid reclaim policy %q, expect Delete or Retain", c.ReclaimPolicy)
+	}
+	return nil
+}
+
+// templateData is the data of the storage plugin's manifests
+type templateData struct {
+	Config
+	Namespace       string
+	ProvisionerName string
+	// Shared stores volumes under a directory shared by every node instead of the node's own one
+	Shared bool
+}
+
+func toTemplateData(v interface{}) (interface{}, error) {
+	c, isConfig := v.(Config)
+	if !isConfig {
+		return nil, fmt.Errorf("not a storage Config")
+	}
+	data := templateData{
+		Config:          c,
+		Namespace:       localPathNamespace,
+		ProvisionerName: localPathProvisionerName,
+	}
+	if c.Provisioner == ProvisionerSharedPath {
+		data.ProvisionerName = sharedPathProvisionerName
+		data.Shared = true
+	}
+	return data, nil
+}
+
+func newStorageTemplateExecutor(name string) (*plugins.ManifestTemplate, error) {
+	return plugins.NewManifestTemplate(manifestFs, TypePluginStorage, name, toTemplateData, nil)
+}
+
+// Render renders the named manifest with the config
+func Render(c Config, name string) ([]byte, error) {
+	tmpl, err := newStorageTemplateExecutor(name)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.RenderManifest(tmpl, c)
+}
@@ -0,0 +1,75 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/kind/pkg/log"
+
+	"github.com/footprintai/multikf/pkg/machine"
+)
+
+// UpgradeOptions controls an in-place upgrade
+type UpgradeOptions struct {
+	// DryRun prints the plan without touching the cluster
+	DryRun bool
+	// ExportDB exports the plugin's databases under the machine's HostDir before upgrading
+	ExportDB bool
+	// PruneCascading prunes removed namespaces and crds, which deletes every object inside them
+	PruneCascading bool
+	// Out receives the upgrade plan
+	Out io.Writer
+}
+
+// Upgrader is implemented by installers which could be upgraded in place, from is the recorded plugin
+type Upgrader interface {
+	Upgrade(m machine.MachineCURD, from Plugin, to Plugin, opts UpgradeOptions) error
+}
+
+// UpgradePlugin upgrades the installed plugin to p's version, parameters not specified in p are taken from the machine state
+func UpgradePlugin(logger log.Logger, m machine.MachineCURD, p Plugin, opts UpgradeOptions) error {
+	installer, err := NewInstaller(p.PluginType(), logger)
+	if err != nil {
+		return err
+	}
+	upgrader, ok := installer.(Upgrader)
+	if !ok {
+		return fmt.Errorf("plugins: %s doesn't support in-place upgrade", p.PluginType())
+	}
+	state, err := machine.LoadMachineState(m.HostDir())
+	if err != nil {
+		return err
+	}
+	recorded := state.FindPlugin(p.PluginType().String(), instanceName(installer, p))
+	if recorded == nil {
+		return fmt.Errorf("plugins: %s is not installed on %s", p.PluginType(), m.Name())
+	}
+	from := NewPlugin(p.PluginType(), NewTypePluginVersion(recorded.Version), recorded.Params)
+	if p.PluginVersion() == "" {
+		return fmt.Errorf("plugins: no version specified to upgrade %s to", p.PluginType())
+	}
+	to, err := withRecorded(m, installer, p)
+	if err != nil {
+		return err
+	}
+	if to, err = withDefaultVersion(installer, to); err != nil {
+		return err
+	}
+	if to.PluginVersion() == from.PluginVersion() {
+		return fmt.Errorf("plugins: %s on %s is already %s", p.PluginType(), m.Name(), from.PluginVersion())
+	}
+	logger.V(0).Infof("plugins: upgrade %s from %s to %s on %s\n", p.PluginType(), from.PluginVersion(), to.PluginVersion(), m.Name())
+	upgradeErr := upgrader.Upgrade(m, from, to, opts)
+	if opts.DryRun {
+		return upgradeErr
+	}
+	// a failed upgrade keeps the installed version, so the upgrade could be retried from it
+	installedPlugin := to
+	if upgradeErr != nil {
+		installedPlugin = from
+	}
+	if err := recordPlugin(m, installer, installedPlugin, upgradeErr); err != nil {
+		logger.Warnf("plugins: record %s on %s failed, err:%+v\n", p.PluginType(), m.Name(), err)
+	}
+	return upgradeErr
+}
@@ -0,0 +1,177 @@
+package machine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const machineStateFileName = "machine-state.json"
+
+// MachineState records resources allocated to a machine, it is persisted under the machine's HostDir
+type MachineState struct {
+	// LoadBalancerAddressRange is the metallb address range (e.g. 172.18.255.239-172.18.255.254) assigned to the machine
+	LoadBalancerAddressRange string `json:"loadBalancerAddressRange,omitempty"`
+	// AdvertiseHost is the ip address/hostname remote clients use to reach kubeapi
+	AdvertiseHost string `json:"advertiseHost,omitempty"`
+	// MergedKubeConfigPaths are kubeconfig files which the machine's context was merged into by `export --merge`,
+	// the most recent one goes last
+	MergedKubeConfigPaths []string `json:"mergedKubeConfigPaths,omitempty"`
+	// HelmReleases are releases installed by the helm plugin
+	HelmReleases []HelmRelease `json:"helmReleases,omitempty"`
+	// Plugins are plugins installed by `add` or `plugin add`
+	Plugins []InstalledPlugin `json:"plugins,omitempty"`
+}
+
+// InstalledPlugin records a plugin installed on the machine
+type InstalledPlugin struct {
+	Name string `json:"name"`
+	// Instance distinguishes multiple installations of the same plugin, e.g. helm releases
+	Instance string `json:"instance,omitempty"`
+	Version  string `json:"version,omitempty"`
+	// Params are parameters used for installation, secrets are excluded
+	Params      map[string]string `json:"params,omitempty"`
+	InstalledAt time.Time         `json:"installedAt"`
+	Status      string            `json:"status"`
+}
+
+// String returns name[/instance]@version, e.g. kubeflow@v1.9.0
+func (p InstalledPlugin) String() string {
+	s := p.Name
+	if p.Instance != "" {
+		s += "/" + p.Instance
+	}
+	if p.Version != "" {
+		s += "@" + p.Version
+	}
+	return s
+}
+
+// FindPlugin returns the recorded plugin, nil if not found
+func (s *MachineState) FindPlugin(name string, instance string) *InstalledPlugin {
+	for i := range s.Plugins {
+		if s.Plugins[i].Name == name && s.Plugins[i].Instance == instance {
+			return &s.Plugins[i]
+		}
+	}
+	return nil
+}
+
+// PutPlugin adds or replaces the recorded plugin with the same name and instance
+func (s *MachineState) PutPlugin(p InstalledPlugin) {
+	if found := s.FindPlugin(p.Name, p.Instance); found != nil {
+		*found = p
+		return
+	}
+	s.Plugins = append(s.Plugins, p)
+}
+
+// RemovePlugin removes the recorded plugin
+func (s *MachineState) RemovePlugin(name string, instance string) {
+	var kept []InstalledPlugin
+	for _, p := range s.Plugins {
+		if p.Name != name || p.Instance != instance {
+			kept = append(kept, p)
+		}
+	}
+	s.Plugins = kept
+}
+
+// HasMergedKubeConfigPath returns true if the machine's context was merged into path
+func (s *MachineState) HasMergedKubeConfigPath(path string) bool {
+	for _, p := range s.MergedKubeConfigPaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMergedKubeConfigPath records path as the most recent merge target
+func (s *MachineState) AddMergedKubeConfigPath(path string) {
+	var kept []string
+	for _, p := range s.MergedKubeConfigPaths {
+		if p != path {
+			kept = append(kept, p)
+		}
+	}
+	s.MergedKubeConfigPaths = append(kept, path)
+}
+
+// LastMergedKubeConfigPath returns the most recent merge target, empty if never merged
+func (s *MachineState) LastMergedKubeConfigPath() string {
+	if len(s.MergedKubeConfigPaths) == 0 {
+		return ""
+	}
+	return s.MergedKubeConfigPaths[len(s.MergedKubeConfigPaths)-1]
+}
+
+// HelmRelease records how a helm release was installed, so it could be upgraded or removed later
+type HelmRelease struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Chart        string    `json:"chart"`
+	Repo         string    `json:"repo,omitempty"`
+	ChartVersion string    `json:"chartVersion,omitempty"`
+	ValuesFiles  []string  `json:"valuesFiles,omitempty"`
+	Sets         string    `json:"sets,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// FindHelmRelease returns the recorded release with name, nil if not found
+func (s *MachineState) FindHelmRelease(name string) *HelmRelease {
+	for i := range s.HelmReleases {
+		if s.HelmReleases[i].Name == name {
+			return &s.HelmReleases[i]
+		}
+	}
+	return nil
+}
+
+// PutHelmRelease adds or replaces the recorded release with the same name
+func (s *MachineState) PutHelmRelease(r HelmRelease) {
+	if found := s.FindHelmRelease(r.Name); found != nil {
+		*found = r
+		return
+	}
+	s.HelmReleases = append(s.HelmReleases, r)
+}
+
+// RemoveHelmRelease removes the recorded release with name
+func (s *MachineState) RemoveHelmRelease(name string) {
+	var kept []HelmRelease
+	for _, r := range s.HelmReleases {
+		if r.Name != name {
+			kept = append(kept, r)
+		}
+	}
+	s.HelmReleases = kept
+}
+
+// LoadMachineState reads state from hostDir, an empty state is returned if no state was saved before
+func LoadMachineState(hostDir string) (*MachineState, error) {
+	blob, err := os.ReadFile(filepath.Join(hostDir, machineStateFileName))
+	if os.IsNotExist(err) {
+		return &MachineState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &MachineState{}
+	if err := json.Unmarshal(blob, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *MachineState) Save(hostDir string) error {
+	blob, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hostDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(hostDir, machineStateFileName), blob, 0644)
+}
@@ -0,0 +1,20 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergedKubeConfigPaths(t *testing.T) {
+	s := &MachineState{}
+	assert.EqualValues(t, "", s.LastMergedKubeConfigPath())
+
+	s.AddMergedKubeConfigPath("/a/config")
+	s.AddMergedKubeConfigPath("/b/config")
+	s.AddMergedKubeConfigPath("/a/config")
+	assert.EqualValues(t, []string{"/b/config", "/a/config"}, s.MergedKubeConfigPaths)
+	assert.EqualValues(t, "/a/config", s.LastMergedKubeConfigPath())
+	assert.True(t, s.HasMergedKubeConfigPath("/b/config"))
+	assert.False(t, s.HasMergedKubeConfigPath("/c/config"))
+}
@@ -0,0 +1,52 @@
+package users
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseCSV reads users from csv lines in format `user,namespace[,role]`, an optional header line
+// starting with `user` is skipped, and defaultRole is used when the role column is omitted.
+func ParseCSV(r io.Reader, defaultRole Role) ([]User, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	seen := map[string]bool{}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "user") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("users: line %d: expect user,namespace[,role], got %q", i+1, strings.Join(record, ","))
+		}
+		u := User{
+			Name:      strings.TrimSpace(record[0]),
+			Namespace: strings.TrimSpace(record[1]),
+			Role:      defaultRole,
+		}
+		if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+			role, err := ParseRole(strings.TrimSpace(record[2]))
+			if err != nil {
+				return nil, fmt.Errorf("users: line %d: %w", i+1, err)
+			}
+			u.Role = role
+		}
+		if err := u.Validate(); err != nil {
+			return nil, fmt.Errorf("users: line %d: %w", i+1, err)
+		}
+		if seen[u.Name] {
+			return nil, fmt.Errorf("users: line %d: duplicated user %s", i+1, u.Name)
+		}
+		seen[u.Name] = true
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,249 @@
+package users
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/kind/pkg/log"
+
+	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	"github.com/footprintai/multikf/pkg/machine/kubeconfig"
+	pkgtemplate "github.com/footprintai/multikf/pkg/template"
+	templatefs "github.com/footprintai/multikf/pkg/template/fs"
+)
+
+//go:embed manifests/*
+var manifestFs embed.FS
+
+const (
+	// UserLabel is put on every resource created for a user, its value is the user name
+	UserLabel = "multikf.footprint-ai.com/user"
+	// RoleAnnotation records the bound role on the user's serviceaccount
+	RoleAnnotation = "multikf.footprint-ai.com/role"
+)
+
+var (
+	tokenPollInterval = 2 * time.Second
+	tokenPollTimeout  = 1 * time.Minute
+)
+
+// Role is one of kubernetes' user-facing clusterroles, it is bound within the user's namespace only
+type Role string
+
+const (
+	RoleView  Role = "view"
+	RoleEdit  Role = "edit"
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
+func ListRoleString() []string {
+	return []string{RoleView.String(), RoleEdit.String(), RoleAdmin.String()}
+}
+
+func ParseRole(s string) (Role, error) {
+	for _, r := range []Role{RoleView, RoleEdit, RoleAdmin} {
+		if strings.EqualFold(s, r.String()) {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("users: unsupported role %q, available roles: %s", s, strings.Join(ListRoleString(), ","))
+}
+
+// User is a namespace scoped identity backed by a serviceaccount
+type User struct {
+	Name      string
+	Namespace string
+	Role      Role
+}
+
+func (u User) Validate() error {
+	if errs := validation.IsDNS1123Label(u.Name); len(errs) > 0 {
+		return fmt.Errorf("users: invalid user name %q: %s", u.Name, strings.Join(errs, ","))
+	}
+	if errs := validation.IsDNS1123Label(u.Namespace); len(errs) > 0 {
+		return fmt.Errorf("users: invalid namespace %q: %s", u.Namespace, strings.Join(errs, ","))
+	}
+	if _, err := ParseRole(u.Role.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u User) serviceAccountName() string {
+	return "multikf-user-" + u.Name
+}
+
+func (u User) tokenSecretName() string {
+	return u.serviceAccountName() + "-token"
+}
+
+func NewUserTemplateExecutor() (*UserFileTemplate, error) {
+	tmplBytes, err := manifestFs.ReadFile("manifests/user-template.yaml")
+	if err != nil {
+		return nil, err
+	}
+	return &UserFileTemplate{
+		UserLabel:        UserLabel,
+		RoleAnnotation:   RoleAnnotation,
+		userFileTemplate: string(tmplBytes),
+	}, nil
+}
+
+// UserFileTemplate renders namespace, serviceaccount, token secret and rolebinding for a user
+type UserFileTemplate struct {
+	Name               string
+	Namespace          string
+	Role               string
+	ServiceAccountName string
+	TokenSecretName    string
+	UserLabel          string
+	RoleAnnotation     string
+	userFileTemplate   string
+}
+
+var (
+	_ pkgtemplate.TemplateExecutor = &UserFileTemplate{}
+)
+
+func (u *UserFileTemplate) Filename() string {
+	return fmt.Sprintf("user-%s.yaml", u.Name)
+}
+
+func (u *UserFileTemplate) Execute(w io.Writer) error {
+	tmpl, err := template.New("user").Delims("[[", "]]").Parse(u.userFileTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, u)
+}
+
+func (u *UserFileTemplate) Populate(v interface{}) error {
+	user, isUser := v.(User)
+	if !isUser {
+		return fmt.Errorf("not a User")
+	}
+	u.Name = user.Name
+	u.Namespace = user.Namespace
+	u.Role = user.Role.String()
+	u.ServiceAccountName = user.serviceAccountName()
+	u.TokenSecretName = user.tokenSecretName()
+	return nil
+}
+
+// Add creates the user's identity and rolebinding, and returns a standalone kubeconfig for the user
+func Add(logger log.Logger, kubecli *machinekubectlcmd.CLI, kubeConfigFile string, hostDir string, u User) ([]byte, error) {
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	tmpl, err := NewUserTemplateExecutor()
+	if err != nil {
+		return nil, err
+	}
+	memFs := templatefs.NewMemoryFilesFs()
+	if err := memFs.Generate(u, tmpl); err != nil {
+		return nil, err
+	}
+	if err := templatefs.NewFolder(hostDir).DumpFiles(true, memFs.FS()); err != nil {
+		return nil, err
+	}
+	logger.V(0).Infof("users: add user %s with role %s in namespace %s\n", u.Name, u.Role, u.Namespace)
+	if err := kubecli.Apply(kubeConfigFile, filepath.Join(hostDir, tmpl.Filename())); err != nil {
+		return nil, err
+	}
+	token, err := waitForToken(kubecli, kubeConfigFile, u)
+	if err != nil {
+		return nil, err
+	}
+	adminKubeConfig, err := os.ReadFile(kubeConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	return kubeconfig.NewTokenKubeConfig(adminKubeConfig, u.Name, u.Namespace, token)
+}
+
+// waitForToken waits until the token controller populates the secret
+func waitForToken(kubecli *machinekubectlcmd.CLI, kubeConfigFile string, u User) (string, error) {
+	deadline := time.Now().Add(tokenPollTimeout)
+	for {
+		out, err := kubecli.Output(kubeConfigFile, "get", "secret", u.tokenSecretName(), "-n", u.Namespace, "-o", "jsonpath={.data.token}")
+		if err == nil && len(strings.TrimSpace(string(out))) > 0 {
+			token, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+			if err != nil {
+				return "", err
+			}
+			return string(token), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("users: token for %s is not ready after %s", u.Name, tokenPollTimeout)
+		}
+		time.Sleep(tokenPollInterval)
+	}
+}
+
+type objectList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Labels      map[string]string `json:"labels"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// parseServiceAccountList extracts users from the output of `kubectl get sa -o json`
+func parseServiceAccountList(blob []byte) ([]User, error) {
+	list := &objectList{}
+	if err := json.Unmarshal(blob, list); err != nil {
+		return nil, err
+	}
+	var users []User
+	for _, item := range list.Items {
+		name, found := item.Metadata.Labels[UserLabel]
+		if !found {
+			continue
+		}
+		users = append(users, User{
+			Name:      name,
+			Namespace: item.Metadata.Namespace,
+			Role:      Role(item.Metadata.Annotations[RoleAnnotation]),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name == users[j].Name {
+			return users[i].Namespace < users[j].Namespace
+		}
+		return users[i].Name < users[j].Name
+	})
+	return users, nil
+}
+
+// List returns users created by Add
+func List(kubecli *machinekubectlcmd.CLI, kubeConfigFile string) ([]User, error) {
+	out, err := kubecli.Output(kubeConfigFile, "get", "serviceaccounts", "--all-namespaces", "-l", UserLabel, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseServiceAccountList(out)
+}
+
+// Revoke deletes the user's serviceaccount, token and rolebinding, issued kubeconfigs stop working immediately.
+// The namespace is kept as it may contain the user's workloads.
+func Revoke(logger log.Logger, kubecli *machinekubectlcmd.CLI, kubeConfigFile string, name string) error {
+	logger.V(0).Infof("users: revoke user %s\n", name)
+	return kubecli.DeleteBySelector(kubeConfigFile, "rolebindings,secrets,serviceaccounts", fmt.Sprintf("%s=%s", UserLabel, name))
+}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserTemplate(t *testing.T) {
+	tmpl, err := NewUserTemplateExecutor()
+	assert.NoError(t, err)
+	assert.NoError(t, tmpl.Populate(User{Name: "alice", Namespace: "team-a", Role: RoleEdit}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, tmpl.Execute(buf))
+	assert.EqualValues(t, "user-alice.yaml", tmpl.Filename())
+	rendered := buf.String()
+	assert.Contains(t, rendered, "multikf.footprint-ai.com/user: alice")
+	assert.Contains(t, rendered, "kubernetes.io/service-account.name: multikf-user-alice")
+	assert.Contains(t, rendered, "  kind: ClusterRole\n  name: edit\n")
+	assert.EqualValues(t, 4, strings.Count(rendered, "namespace: team-a"))
+}
+
+func TestParseCSV(t *testing.T) {
+	users, err := ParseCSV(strings.NewReader(`user,namespace,role
+alice, team-a
+# comment
+bob,team-b,view
+`), RoleEdit)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []User{
+		{Name: "alice", Namespace: "team-a", Role: RoleEdit},
+		{Name: "bob", Namespace: "team-b", Role: RoleView},
+	}, users)
+
+	invalids := []string{
+		"alice",
+		"alice,team-a,root",
+		"Alice,team-a",
+		"alice,team_a",
+		"alice,team-a\nalice,team-b",
+	}
+	for _, invalid := range invalids {
+		_, err := ParseCSV(strings.NewReader(invalid), RoleEdit)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseServiceAccountList(t *testing.T) {
+	users, err := parseServiceAccountList([]byte(`{"items":[
+{"metadata":{"name":"multikf-user-bob","namespace":"team-b","labels":{"multikf.footprint-ai.com/user":"bob"},"annotations":{"multikf.footprint-ai.com/role":"view"}}},
+{"metadata":{"name":"default","namespace":"team-b"}},
+{"metadata":{"name":"multikf-user-alice","namespace":"team-a","labels":{"multikf.footprint-ai.com/user":"alice"},"annotations":{"multikf.footprint-ai.com/role":"edit"}}}
+]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []User{
+		{Name: "alice", Namespace: "team-a", Role: RoleEdit},
+		{Name: "bob", Namespace: "team-b", Role: RoleView},
+	}, users)
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/footprintai/multikf/pkg/k8s"
+	"github.com/footprintai/multikf/pkg/machine"
 	vagranttemplates "github.com/footprintai/multikf/pkg/machine/vagrant/template"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -33,23 +34,7 @@ func TestVagrantFile(t *testing.T) {
 	fmt.Printf("tmpdir:%s\n", tmpdir)
 	mockFs := afero.NewBasePathFs(afero.NewOsFs(), tmpdir)
 	vdir := NewVagrantFolder(tmpdir)
-	assert.NoError(t, vdir.GenerateVagrantFiles(vagranttemplates.NewVagrantTemplateConfig(
-		"unittest",
-		2,
-		1026,
-		1234,
-		5678,
-		"1.2.3.4",
-		0,
-		nil,
-		false,
-		"",
-		0,
-		nil,
-		"",
-		k8s.DefaultVersion(),
-	),
-	))
+	assert.NoError(t, vdir.GenerateVagrantFiles(vagranttemplates.NewVagrantTemplateConfig("unittest", vagrantConfiger{}, 1234, 5678, "")))
 
 	expectedFiles := []string{
 		"Vagrantfile",
@@ -64,3 +49,28 @@ func TestVagrantFile(t *testing.T) {
 		}
 	}
 }
+
+type vagrantConfiger struct{}
+
+var (
+	_ machine.MachineConfiger = vagrantConfiger{}
+)
+
+func (v vagrantConfiger) Info() string                             { return "" }
+func (v vagrantConfiger) GetCPUs() int                             { return 2 }
+func (v vagrantConfiger) GetMemory() int                           { return 1026 }
+func (v vagrantConfiger) GetGPUs() int                             { return 0 }
+func (v vagrantConfiger) GetKubeAPIIP() string                     { return "1.2.3.4" }
+func (v vagrantConfiger) GetExportPorts() []machine.ExportPortPair { return nil }
+func (v vagrantConfiger) GetForceOverwriteConfig() bool            { return false }
+func (v vagrantConfiger) AuditEnabled() bool                       { return false }
+func (v vagrantConfiger) GetAuditPolicy() string                   { return "" }
+func (v vagrantConfiger) GetControlPlanes() int                    { return 1 }
+func (v vagrantConfiger) GetWorkers() int                          { return 0 }
+func (v vagrantConfiger) GetWorkerGroups() []machine.WorkerGroup   { return nil }
+func (v vagrantConfiger) GetNodeLabels() []machine.NodeLabel       { return nil }
+func (v vagrantConfiger) GetLocalPath() string                     { return "" }
+func (v vagrantConfiger) GetNodeVersion() k8s.KindK8sVersion       { return k8s.DefaultVersion() }
+func (v vagrantConfiger) GetCNI() k8s.CNI                          { return k8s.CNIDefault }
+func (v vagrantConfiger) GetAPIServerCertSANs() []string           { return nil }
+func (v vagrantConfiger) GetKindPatches() []string                 { return nil }
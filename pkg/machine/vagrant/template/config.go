@@ -1,7 +1,6 @@
 package template
 
 import (
-	"github.com/footprintai/multikf/pkg/k8s"
 	"github.com/footprintai/multikf/pkg/machine"
 	pkgtemplateconfig "github.com/footprintai/multikf/pkg/template/config"
 )
@@ -10,24 +9,9 @@ type VagrantTemplateConfig struct {
 	*pkgtemplateconfig.DefaultTemplateConfig
 }
 
-func NewVagrantTemplateConfig(name string, cpus int, memory int, sshport int, kubeApiPort int, kubeApiIP string, gpus int, exportPorts []machine.ExportPortPair, auditEnabled bool, auditFileAbsolutePath string, workerCount int, nodeLabels []machine.NodeLabel, localPath string, nodeVersion k8s.KindK8sVersion) *VagrantTemplateConfig {
+func NewVagrantTemplateConfig(name string, options machine.MachineConfiger, sshport int, kubeApiPort int, auditFileAbsolutePath string) *VagrantTemplateConfig {
 	return &VagrantTemplateConfig{
-		DefaultTemplateConfig: pkgtemplateconfig.NewDefaultTemplateConfig(
-			name,
-			cpus,
-			memory,
-			sshport,
-			kubeApiPort,
-			kubeApiIP,
-			gpus,
-			exportPorts,
-			auditEnabled,
-			auditFileAbsolutePath,
-			workerCount,
-			nodeLabels,
-			localPath,
-			nodeVersion,
-		),
+		DefaultTemplateConfig: pkgtemplateconfig.NewDefaultTemplateConfig(name, options, sshport, kubeApiPort, auditFileAbsolutePath),
 	}
 }
 
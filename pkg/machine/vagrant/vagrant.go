@@ -13,6 +13,7 @@ import (
 	machinecmd "github.com/footprintai/multikf/pkg/machine/cmd"
 	machinekindcmd "github.com/footprintai/multikf/pkg/machine/cmd/kind"
 	machinekubectlcmd "github.com/footprintai/multikf/pkg/machine/cmd/kubectl"
+	"github.com/footprintai/multikf/pkg/machine/cni"
 	"github.com/footprintai/multikf/pkg/machine/fsutil"
 	"github.com/footprintai/multikf/pkg/machine/vagrant/template"
 	"sigs.k8s.io/kind/pkg/log"
@@ -121,7 +122,10 @@ func (v *VagrantMachine) Up() error {
 		return err
 	}
 	kubeConfigPath := filepath.Join(v.vagrantMachineDir, "kubeconfig.yaml")
-	return v.ExportKubeConfig(kubeConfigPath, true)
+	if err := v.ExportKubeConfig(kubeConfigPath, true); err != nil {
+		return err
+	}
+	return cni.Install(v.logger, v.kubectlcli, kubeConfigPath, v.vagrantMachineDir, v.options.GetCNI())
 }
 
 func (v *VagrantMachine) NewVagrantCli() (*vagrantclient.VagrantCli, error) {
@@ -207,22 +211,8 @@ func (v *VagrantMachine) prepareFiles() error {
 		return err
 	}
 	v.logger.V(0).Infof("vagrantmachine(%s): get port (%d,%d) for ssh and kubeapi\n", v.name, sshport, kubeport)
-	tmplConfig := template.NewVagrantTemplateConfig(
-		v.name,
-		v.options.GetCPUs(),
-		v.options.GetMemory(),
-		sshport,
-		kubeport,
-		v.options.GetKubeAPIIP(),
-		v.options.GetGPUs(),
-		v.options.GetExportPorts(),
-		v.options.AuditEnabled(),
-		"/tmp/audit-policy.yaml", /*for vagrant, we will copy the file under /tmp and run local installation*/
-		v.options.GetWorkers(),
-		v.options.GetNodeLabels(),
-		v.options.GetLocalPath(),
-		v.options.GetNodeVersion(),
-	)
+	// for vagrant, the audit policy is copied under /tmp and installed locally
+	tmplConfig := template.NewVagrantTemplateConfig(v.name, v.options, sshport, kubeport, "/tmp/audit-policy.yaml")
 
 	vfolder := NewVagrantFolder(v.vagrantMachineDir)
 	if err := vfolder.GenerateVagrantFiles(tmplConfig); err != nil {
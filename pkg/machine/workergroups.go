@@ -0,0 +1,220 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+
+	kindNodeRepository = "kindest/node"
+)
+
+// WorkerGroup is a group of identical worker nodes
+type WorkerGroup struct {
+	Count     int               `json:"count"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Taints    []Taint           `json:"taints,omitempty"`
+	GPU       bool              `json:"gpu,omitempty"`
+	Mounts    []Mount           `json:"mounts,omitempty"`
+	Image     string            `json:"image,omitempty"`     // overrides the node image of the cluster, e.g. kindest/node:v1.29.8
+	LocalPath bool              `json:"localPath,omitempty"` // mounts --use_localpath of the cluster, groups don't mount it unless they opt in
+}
+
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+type Mount struct {
+	HostPath      string `json:"hostPath"`
+	ContainerPath string `json:"containerPath"`
+	ReadOnly      bool   `json:"readOnly,omitempty"`
+}
+
+func (g WorkerGroup) Validate() error {
+	if g.Count < 1 {
+		return fmt.Errorf("workergroup: count should be at least 1, got %d", g.Count)
+	}
+	for key := range g.Labels {
+		if key == "" {
+			return fmt.Errorf("workergroup: label with an empty key")
+		}
+	}
+	for _, taint := range g.Taints {
+		if taint.Key == "" {
+			return fmt.Errorf("workergroup: taint with an empty key")
+		}
+		switch taint.Effect {
+		case TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("workergroup: unknown taint effect:%s, possible value: %s, %s and %s", taint.Effect, TaintEffectNoSchedule, TaintEffectPreferNoSchedule, TaintEffectNoExecute)
+		}
+	}
+	for _, mount := range g.Mounts {
+		if !path.IsAbs(mount.HostPath) || !path.IsAbs(mount.ContainerPath) {
+			return fmt.Errorf("workergroup: mount %s:%s should have absolute paths", mount.HostPath, mount.ContainerPath)
+		}
+	}
+	return nil
+}
+
+// ValidateImage rejects a kindest/node image newer than the control plane's version (e.g. v1.28.13),
+// as kubelets should never be newer than kube-apiserver. Images of other repositories are not checked.
+func (g WorkerGroup) ValidateImage(controlPlaneVersion string) error {
+	if g.Image == "" {
+		return nil
+	}
+	repository, tag := splitImage(g.Image)
+	if repository != kindNodeRepository && !strings.HasSuffix(repository, "/"+kindNodeRepository) {
+		return nil
+	}
+	imageVersion, err := version.ParseGeneric(tag)
+	if err != nil {
+		return fmt.Errorf("workergroup: invalid version of image %s, err:%+v", g.Image, err)
+	}
+	clusterVersion, err := version.ParseGeneric(controlPlaneVersion)
+	if err != nil {
+		return fmt.Errorf("workergroup: invalid control plane version %s, err:%+v", controlPlaneVersion, err)
+	}
+	if clusterVersion.LessThan(imageVersion) {
+		return fmt.Errorf("workergroup: image %s is newer than the control plane %s, workers should be the same or an older version", g.Image, controlPlaneVersion)
+	}
+	return nil
+}
+
+// splitImage returns the repository and tag of an image, e.g. kindest/node and v1.29.8 of kindest/node:v1.29.8@sha256:...
+func splitImage(image string) (string, string) {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		image = image[:idx]
+	}
+	idx := strings.LastIndex(image, ":")
+	if idx < 0 || strings.Contains(image[idx+1:], "/") {
+		return image, ""
+	}
+	return image[:idx], image[idx+1:]
+}
+
+// ParseWorkerGroup parses a worker group delimited by semicolon, each field is in the format of name=value
+//
+//	count=2;labels=key=value,...;taints=key[=value]:Effect,...;gpu=true;mounts=hostPath:containerPath[:ro],...;image=kindest/node:v1.29.8;localpath=true
+//
+// count is 1 if not specified.
+func ParseWorkerGroup(s string) (WorkerGroup, error) {
+	group := WorkerGroup{Count: 1}
+	for _, field := range strings.Split(s, ";") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		idx := strings.Index(field, "=")
+		if idx < 0 {
+			return group, fmt.Errorf("workergroup: parse failed, expect: name=value but got:%s", field)
+		}
+		name, value := field[:idx], field[idx+1:]
+		var err error
+		switch name {
+		case "count":
+			group.Count, err = strconv.Atoi(value)
+			if err != nil {
+				return group, fmt.Errorf("workergroup: invalid count:%s", value)
+			}
+		case "labels":
+			group.Labels, err = parseNodeLabels(value)
+		case "taints":
+			group.Taints, err = parseTaints(value)
+		case "gpu":
+			group.GPU, err = strconv.ParseBool(value)
+			if err != nil {
+				return group, fmt.Errorf("workergroup: invalid gpu:%s, expect true or false", value)
+			}
+		case "mounts":
+			group.Mounts, err = parseMounts(value)
+		case "image":
+			group.Image = value
+		case "localpath":
+			group.LocalPath, err = strconv.ParseBool(value)
+			if err != nil {
+				return group, fmt.Errorf("workergroup: invalid localpath:%s, expect true or false", value)
+			}
+		default:
+			return group, fmt.Errorf("workergroup: unknown field:%s, possible value: count, labels, taints, gpu, mounts, image and localpath", name)
+		}
+		if err != nil {
+			return group, err
+		}
+	}
+	return group, group.Validate()
+}
+
+// LoadWorkerGroups loads worker groups from a yaml list of WorkerGroup, count is 1 if not specified
+func LoadWorkerGroups(content []byte) ([]WorkerGroup, error) {
+	var groups []WorkerGroup
+	if err := yaml.UnmarshalStrict(content, &groups); err != nil {
+		return nil, fmt.Errorf("workergroup: invalid worker groups, err:%+v", err)
+	}
+	for i := range groups {
+		if groups[i].Count == 0 {
+			groups[i].Count = 1
+		}
+		if err := groups[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+func parseNodeLabels(s string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, token := range strings.Split(s, ",") {
+		subtokens := strings.Split(token, "=")
+		if len(subtokens) != 2 {
+			return nil, fmt.Errorf("workergroup: parse label failed, expect: key=value but got:%s", token)
+		}
+		labels[subtokens[0]] = subtokens[1]
+	}
+	return labels, nil
+}
+
+// parseTaints parses taints in the format of key[=value]:Effect delimited by comma
+func parseTaints(s string) ([]Taint, error) {
+	var taints []Taint
+	for _, token := range strings.Split(s, ",") {
+		idx := strings.LastIndex(token, ":")
+		if idx < 0 {
+			return nil, fmt.Errorf("workergroup: parse taint failed, expect: key[=value]:Effect but got:%s", token)
+		}
+		taint := Taint{Key: token[:idx], Effect: token[idx+1:]}
+		if kv := strings.SplitN(taint.Key, "=", 2); len(kv) == 2 {
+			taint.Key, taint.Value = kv[0], kv[1]
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
+// parseMounts parses mounts in the format of hostPath:containerPath[:ro] delimited by comma
+func parseMounts(s string) ([]Mount, error) {
+	var mounts []Mount
+	for _, token := range strings.Split(s, ",") {
+		subtokens := strings.Split(token, ":")
+		if len(subtokens) < 2 || len(subtokens) > 3 || (len(subtokens) == 3 && subtokens[2] != "ro") {
+			return nil, fmt.Errorf("workergroup: parse mount failed, expect: hostPath:containerPath[:ro] but got:%s", token)
+		}
+		mounts = append(mounts, Mount{
+			HostPath:      subtokens[0],
+			ContainerPath: subtokens[1],
+			ReadOnly:      len(subtokens) == 3,
+		})
+	}
+	return mounts, nil
+}
@@ -0,0 +1,104 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkerGroup(t *testing.T) {
+	group, err := ParseWorkerGroup("")
+	assert.NoError(t, err)
+	assert.EqualValues(t, WorkerGroup{Count: 1}, group)
+
+	group, err = ParseWorkerGroup("count=2;labels=accelerator=nvidia,tier=gpu;taints=nvidia.com/gpu=present:NoSchedule,spot:PreferNoSchedule;gpu=true;mounts=/data:/data:ro,/models:/mnt/models;image=kindest/node:v1.29.8@sha256:d46b7aa29567e93b27f7531d258c372e829d7224b25e3fc6ffdefed12476d3aa;localpath=true")
+	assert.NoError(t, err)
+	assert.EqualValues(t, WorkerGroup{
+		Count:  2,
+		Labels: map[string]string{"accelerator": "nvidia", "tier": "gpu"},
+		Taints: []Taint{
+			{Key: "nvidia.com/gpu", Value: "present", Effect: TaintEffectNoSchedule},
+			{Key: "spot", Effect: TaintEffectPreferNoSchedule},
+		},
+		GPU: true,
+		Mounts: []Mount{
+			{HostPath: "/data", ContainerPath: "/data", ReadOnly: true},
+			{HostPath: "/models", ContainerPath: "/mnt/models"},
+		},
+		Image:     "kindest/node:v1.29.8@sha256:d46b7aa29567e93b27f7531d258c372e829d7224b25e3fc6ffdefed12476d3aa",
+		LocalPath: true,
+	}, group)
+
+	invalids := []string{
+		"count=0",
+		"count=two",
+		"size=2",
+		"labels",
+		"labels=a",
+		"taints=spot",
+		"taints=spot:Never",
+		"gpu=yes",
+		"localpath=yes",
+		"mounts=/data",
+		"mounts=/data:/data:rw",
+		"mounts=data:/data",
+	}
+	for _, invalid := range invalids {
+		_, err := ParseWorkerGroup(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadWorkerGroups(t *testing.T) {
+	groups, err := LoadWorkerGroups([]byte(`
+- count: 2
+  labels:
+    tier: cpu
+- gpu: true
+  localPath: true
+  taints:
+  - key: nvidia.com/gpu
+    effect: NoSchedule
+  mounts:
+  - hostPath: /data
+    containerPath: /data
+    readOnly: true
+`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []WorkerGroup{
+		{Count: 2, Labels: map[string]string{"tier": "cpu"}},
+		{
+			Count:     1,
+			GPU:       true,
+			Taints:    []Taint{{Key: "nvidia.com/gpu", Effect: TaintEffectNoSchedule}},
+			Mounts:    []Mount{{HostPath: "/data", ContainerPath: "/data", ReadOnly: true}},
+			LocalPath: true,
+		},
+	}, groups)
+
+	_, err = LoadWorkerGroups([]byte("- count: 1\n  gpus: true\n"))
+	assert.Error(t, err)
+	_, err = LoadWorkerGroups([]byte("- taints:\n  - key: spot\n"))
+	assert.Error(t, err)
+}
+
+func TestWorkerGroupValidateImage(t *testing.T) {
+	for image, valid := range map[string]bool{
+		"":                      true,
+		"kindest/node:v1.28.13": true,
+		"kindest/node:v1.27.16@sha256:3fd82731af34efe19cd54ea5c25e882985bafa2c9baefe14f8deab1737d9fabe": true,
+		"docker.io/kindest/node:v1.26.15":         true,
+		"registry.local:5000/custom/node:v1.31.0": true,
+		"kindest/node:v1.28.14":                   false,
+		"kindest/node:v1.29.8@sha256:d46b7aa29567e93b27f7531d258c372e829d7224b25e3fc6ffdefed12476d3aa": false,
+		"docker.io/kindest/node:v1.31.0": false,
+		"kindest/node:latest":            false,
+	} {
+		err := WorkerGroup{Count: 1, Image: image}.ValidateImage("v1.28.13")
+		if valid {
+			assert.NoError(t, err, image)
+		} else {
+			assert.Error(t, err, image)
+		}
+	}
+}
@@ -1,46 +1,47 @@
 package template
 
 import (
-	"html/template"
 	"io"
+
+	"github.com/footprintai/multikf/pkg/machine/audit"
 )
 
 func NewAuditPolicyTemplate() *AuditPolicyFileTemplate {
-	return &AuditPolicyFileTemplate{
-		auditPolicyFileTemplate: auditPolicyDefaultFileTemplate,
-	}
+	return &AuditPolicyFileTemplate{}
 }
 
 func (k *AuditPolicyFileTemplate) Filename() string {
 	return "audit-policy.yaml"
 }
 
+// Execute writes the audit policy, which is the default preset if the config doesn't specify one
 func (k *AuditPolicyFileTemplate) Execute(w io.Writer) error {
-	tmpl, err := template.New("auditpolicy").Parse(k.auditPolicyFileTemplate)
-	if err != nil {
-		return err
+	policy := k.auditPolicy
+	if policy == "" {
+		var err error
+		if policy, err = audit.ResolvePolicy(audit.DefaultPreset); err != nil {
+			return err
+		}
 	}
-	if err := tmpl.Execute(w, k); err != nil {
+	if err := audit.ValidatePolicy([]byte(policy)); err != nil {
 		return err
 	}
-	return nil
+	_, err := io.WriteString(w, policy)
+	return err
 }
 
 func (k *AuditPolicyFileTemplate) Populate(v interface{}) error {
+	k.auditPolicy = ""
+	if getter, ok := v.(AuditPolicyGetter); ok {
+		k.auditPolicy = getter.GetAuditPolicy()
+	}
 	return nil
 }
 
 type AuditPolicyFileTemplate struct {
-	auditPolicyFileTemplate string
+	auditPolicy string
 }
 
 var (
 	_ TemplateExecutor = &AuditPolicyFileTemplate{}
 )
-
-var auditPolicyDefaultFileTemplate string = `
-apiVersion: audit.k8s.io/v1
-kind: Policy
-rules:
-- level: Metadata
-`
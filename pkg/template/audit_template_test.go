@@ -0,0 +1,37 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ AuditPolicyGetter = auditPolicyConfig{}
+)
+
+type auditPolicyConfig struct {
+	policy string
+}
+
+func (s auditPolicyConfig) GetAuditPolicy() string {
+	return s.policy
+}
+
+func TestAuditPolicyTemplate(t *testing.T) {
+	at := NewAuditPolicyTemplate()
+	assert.NoError(t, at.Populate(auditConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, at.Execute(buf))
+	assert.EqualValues(t, "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: Metadata\n", buf.String())
+
+	policy := "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: RequestResponse\n"
+	assert.NoError(t, at.Populate(auditPolicyConfig{policy: policy}))
+	buf.Reset()
+	assert.NoError(t, at.Execute(buf))
+	assert.EqualValues(t, policy, buf.String())
+
+	assert.NoError(t, at.Populate(auditPolicyConfig{policy: "kind: Policy\n"}))
+	assert.Error(t, at.Execute(&bytes.Buffer{}))
+}
@@ -9,42 +9,29 @@ import (
 )
 
 var (
-	_ template.KindConfiger = &DefaultTemplateConfig{}
+	_ template.KindConfiger        = &DefaultTemplateConfig{}
+	_ template.KindPatchesGetter   = &DefaultTemplateConfig{}
+	_ template.ControlPlanesGetter = &DefaultTemplateConfig{}
+	_ template.AuditPolicyGetter   = &DefaultTemplateConfig{}
 )
 
+// DefaultTemplateConfig renders templates of a machine, user settings come from the machine's options
+// while ports and paths are decided by the provisioner.
 type DefaultTemplateConfig struct {
 	name                  string
-	cpus                  int // number of cpus allocated
-	memory                int // number of bytes memory allocated
+	options               machine.MachineConfiger
 	sshPort               int
 	kubeApiPort           int
-	kubeApiIP             string
-	gpus                  int
-	exportPorts           []machine.ExportPortPair
-	auditEnabled          bool
 	auditFileAbsolutePath string
-	workerCount           int
-	nodeLabels            []machine.NodeLabel
-	localPath             string
-	nodeVersion           k8s.KindK8sVersion
 }
 
-func NewDefaultTemplateConfig(name string, cpus int, memory int, sshport int, kubeApiPort int, kubeApiIP string, gpus int, exportPorts []machine.ExportPortPair, auditEnabled bool, auditFileAbsolutePath string, workerCount int, nodeLabels []machine.NodeLabel, localPath string, nodeVersion k8s.KindK8sVersion) *DefaultTemplateConfig {
+func NewDefaultTemplateConfig(name string, options machine.MachineConfiger, sshport int, kubeApiPort int, auditFileAbsolutePath string) *DefaultTemplateConfig {
 	return &DefaultTemplateConfig{
 		name:                  name,
-		cpus:                  cpus,
-		memory:                memory,
+		options:               options,
 		sshPort:               sshport,
 		kubeApiPort:           kubeApiPort,
-		kubeApiIP:             kubeApiIP,
-		gpus:                  gpus,
-		exportPorts:           exportPorts,
-		auditEnabled:          auditEnabled,
 		auditFileAbsolutePath: auditFileAbsolutePath,
-		workerCount:           workerCount,
-		nodeLabels:            nodeLabels,
-		localPath:             localPath,
-		nodeVersion:           nodeVersion,
 	}
 }
 
@@ -53,15 +40,15 @@ func (t *DefaultTemplateConfig) GetName() string {
 }
 
 func (t *DefaultTemplateConfig) GetNodeVersion() k8s.KindK8sVersion {
-	return t.nodeVersion
+	return t.options.GetNodeVersion()
 }
 
 func (t *DefaultTemplateConfig) GetMemory() int {
-	return t.memory
+	return t.options.GetMemory()
 }
 
 func (t *DefaultTemplateConfig) GetCPUs() int {
-	return t.cpus
+	return t.options.GetCPUs()
 }
 
 func (t *DefaultTemplateConfig) GetKubeAPIPort() int {
@@ -69,11 +56,11 @@ func (t *DefaultTemplateConfig) GetKubeAPIPort() int {
 }
 
 func (t *DefaultTemplateConfig) GetKubeAPIIP() string {
-	return t.kubeApiIP
+	return t.options.GetKubeAPIIP()
 }
 
 func (t *DefaultTemplateConfig) GetGPUs() int {
-	return t.gpus
+	return t.options.GetGPUs()
 }
 
 func (t *DefaultTemplateConfig) GetSSHPort() int {
@@ -81,20 +68,32 @@ func (t *DefaultTemplateConfig) GetSSHPort() int {
 }
 
 func (t *DefaultTemplateConfig) GetExportPorts() []machine.ExportPortPair {
-	return t.exportPorts
+	return t.options.GetExportPorts()
 }
 
 func (t *DefaultTemplateConfig) AuditEnabled() bool {
-	return t.auditEnabled
+	return t.options.AuditEnabled()
 }
 
 func (t *DefaultTemplateConfig) AuditFileAbsolutePath() string {
 	return t.auditFileAbsolutePath
 }
 
+// GetAuditPolicy returns the audit policy, the default one is used if it is empty
+func (t *DefaultTemplateConfig) GetAuditPolicy() string {
+	return t.options.GetAuditPolicy()
+}
+
+func (t *DefaultTemplateConfig) GetControlPlanes() int {
+	return t.options.GetControlPlanes()
+}
+
+// GetWorkers returns identical workers inheriting cluster-wide settings followed by workers of each worker group,
+// which mount the local path only if the group opts in
 func (t *DefaultTemplateConfig) GetWorkers() []template.Worker {
-	ids := make([]template.Worker, t.workerCount, t.workerCount)
-	for i := 0; i < t.workerCount; i++ {
+	workerCount := t.options.GetWorkers()
+	ids := make([]template.Worker, workerCount, workerCount)
+	for i := 0; i < workerCount; i++ {
 		ids[i] = template.Worker{
 			Id:          fmt.Sprintf("%d", i),
 			UseGPU:      t.GetGPUs() > 0,
@@ -102,13 +101,47 @@ func (t *DefaultTemplateConfig) GetWorkers() []template.Worker {
 			NodeVersion: t.GetNodeVersion().String(),
 		}
 	}
+	for _, group := range t.options.GetWorkerGroups() {
+		nodeVersion := t.GetNodeVersion().String()
+		if group.Image != "" {
+			nodeVersion = group.Image
+		}
+		var localPath string
+		if group.LocalPath {
+			localPath = t.LocalPath()
+		}
+		for i := 0; i < group.Count; i++ {
+			ids = append(ids, template.Worker{
+				Id:          fmt.Sprintf("%d", len(ids)),
+				UseGPU:      group.GPU,
+				LocalPath:   localPath,
+				NodeVersion: nodeVersion,
+				Labels:      group.Labels,
+				Taints:      group.Taints,
+				ExtraMounts: group.Mounts,
+			})
+		}
+	}
 	return ids
 }
 
 func (t *DefaultTemplateConfig) GetNodeLabels() []machine.NodeLabel {
-	return t.nodeLabels
+	return t.options.GetNodeLabels()
 }
 
 func (t *DefaultTemplateConfig) LocalPath() string {
-	return t.localPath
+	return t.options.GetLocalPath()
+}
+
+func (t *DefaultTemplateConfig) GetCNI() k8s.CNI {
+	return t.options.GetCNI()
+}
+
+// GetKindPatches returns user yaml merged into the rendered kind config
+func (t *DefaultTemplateConfig) GetKindPatches() []string {
+	return t.options.GetKindPatches()
+}
+
+func (t *DefaultTemplateConfig) GetAPIServerCertSANs() []string {
+	return t.options.GetAPIServerCertSANs()
 }
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/footprintai/multikf/pkg/k8s"
+	"github.com/footprintai/multikf/pkg/machine"
+	"github.com/footprintai/multikf/pkg/template"
+)
+
+// workersConfiger has settings used by GetWorkers only
+type workersConfiger struct {
+	machine.MachineConfiger
+	gpus         int
+	workers      int
+	workerGroups []machine.WorkerGroup
+	localPath    string
+}
+
+func (w workersConfiger) GetGPUs() int                           { return w.gpus }
+func (w workersConfiger) GetWorkers() int                        { return w.workers }
+func (w workersConfiger) GetWorkerGroups() []machine.WorkerGroup { return w.workerGroups }
+func (w workersConfiger) GetLocalPath() string                   { return w.localPath }
+func (w workersConfiger) GetNodeVersion() k8s.KindK8sVersion     { return k8s.DefaultVersion() }
+
+func TestGetWorkers(t *testing.T) {
+	nodeVersion := k8s.DefaultVersion()
+	workerGroups := []machine.WorkerGroup{
+		{Count: 2, Labels: map[string]string{"tier": "cpu"}},
+		{
+			Count:     1,
+			GPU:       true,
+			LocalPath: true,
+			Taints:    []machine.Taint{{Key: "nvidia.com/gpu", Effect: machine.TaintEffectNoSchedule}},
+			Mounts:    []machine.Mount{{HostPath: "/models", ContainerPath: "/mnt/models", ReadOnly: true}},
+			Image:     "kindest/node:v1.27.16",
+		},
+	}
+	c := NewDefaultTemplateConfig("unittest", workersConfiger{gpus: 1, workers: 1, workerGroups: workerGroups, localPath: "/data"}, -1, 8443, "")
+
+	assert.EqualValues(t, []template.Worker{
+		{Id: "0", UseGPU: true, LocalPath: "/data", NodeVersion: nodeVersion.String()},
+		{Id: "1", NodeVersion: nodeVersion.String(), Labels: map[string]string{"tier": "cpu"}},
+		{Id: "2", NodeVersion: nodeVersion.String(), Labels: map[string]string{"tier": "cpu"}},
+		{
+			Id:          "3",
+			UseGPU:      true,
+			LocalPath:   "/data",
+			NodeVersion: "kindest/node:v1.27.16",
+			Taints:      workerGroups[1].Taints,
+			ExtraMounts: workerGroups[1].Mounts,
+		},
+	}, c.GetWorkers(), "identical workers come first, groups mount the local path only if opted in and inherit the cluster's image unless overridden")
+
+	c = NewDefaultTemplateConfig("unittest", workersConfiger{}, -1, 8443, "")
+	assert.Empty(t, c.GetWorkers())
+}
@@ -0,0 +1,251 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kindConfigAPIVersion = "kind.x-k8s.io/v1alpha4"
+
+	// replaceDirective lists keys of a patch map whose values replace existing ones instead of being merged,
+	// e.g. `$replace: [extraPortMappings]` replaces the node's port mappings rather than appending to them.
+	replaceDirective = "$replace"
+)
+
+// MergeKindConfig deep merges patches into the kind config in order: maps are merged recursively, scalars are replaced
+// and lists are always appended, since list items have no keys to match them. Keys listed by `$replace` in a patch map
+// are replaced as a whole instead, which is the way to drop or reorder list items. The `nodes` list is merged
+// strategically, the n-th patch node of a role merges into the n-th node of the same role, e.g. the first `role: worker`
+// patches the first worker, unmatched patch nodes are appended.
+func MergeKindConfig(config []byte, patches ...[]byte) ([]byte, error) {
+	merged := map[string]interface{}{}
+	if err := yaml.Unmarshal(config, &merged); err != nil {
+		return nil, fmt.Errorf("kindpatch: invalid kind config, err:%+v", err)
+	}
+	for i, patch := range patches {
+		patchMap := map[string]interface{}{}
+		if err := yaml.Unmarshal(patch, &patchMap); err != nil {
+			return nil, fmt.Errorf("kindpatch: invalid patch #%d, err:%+v", i+1, err)
+		}
+		replaced, err := replacedKeys(patchMap)
+		if err != nil {
+			return nil, fmt.Errorf("kindpatch: patch #%d, err:%+v", i+1, err)
+		}
+		if patchNodes, found := patchMap["nodes"]; found && !replaced["nodes"] {
+			nodes, err := mergeNodes(merged["nodes"], patchNodes)
+			if err != nil {
+				return nil, fmt.Errorf("kindpatch: patch #%d, err:%+v", i+1, err)
+			}
+			merged["nodes"] = nodes
+			delete(patchMap, "nodes")
+		}
+		if merged, err = mergeMaps(merged, patchMap); err != nil {
+			return nil, fmt.Errorf("kindpatch: patch #%d, err:%+v", i+1, err)
+		}
+	}
+	return yaml.Marshal(merged)
+}
+
+// replacedKeys returns keys listed by the map's `$replace` directive
+func replacedKeys(m map[string]interface{}) (map[string]bool, error) {
+	directive, found := m[replaceDirective]
+	if !found {
+		return nil, nil
+	}
+	keys, ok := directive.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s should be a list of keys", replaceDirective)
+	}
+	replaced := map[string]bool{}
+	for _, key := range keys {
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s should be a list of keys, got %v", replaceDirective, key)
+		}
+		if _, found := m[name]; !found {
+			return nil, fmt.Errorf("%s lists %s which is not in the patch", replaceDirective, name)
+		}
+		replaced[name] = true
+	}
+	return replaced, nil
+}
+
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) (map[string]interface{}, error) {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	replaced, err := replacedKeys(src)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range src {
+		if k == replaceDirective {
+			continue
+		}
+		if replaced[k] {
+			dst[k] = v
+			continue
+		}
+		if dst[k], err = mergeValues(dst[k], v); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+func mergeValues(dst interface{}, src interface{}) (interface{}, error) {
+	switch srcValue := src.(type) {
+	case map[string]interface{}:
+		if dstMap, ok := dst.(map[string]interface{}); ok {
+			return mergeMaps(dstMap, srcValue)
+		}
+		// maps without a counterpart are kept as is, directives inside are still applied
+		return mergeMaps(nil, srcValue)
+	case []interface{}:
+		if dstList, ok := dst.([]interface{}); ok {
+			return append(dstList, srcValue...), nil
+		}
+	}
+	return src, nil
+}
+
+func mergeNodes(dst interface{}, src interface{}) ([]interface{}, error) {
+	if src == nil {
+		if dst == nil {
+			return nil, nil
+		}
+		nodes, ok := dst.([]interface{})
+		if !ok {
+			return nil, errors.New("nodes should be a list")
+		}
+		return nodes, nil
+	}
+	dstNodes, _ := dst.([]interface{})
+	srcNodes, ok := src.([]interface{})
+	if !ok {
+		return nil, errors.New("nodes should be a list")
+	}
+	// matched counts patch nodes of a role seen so far
+	matched := map[string]int{}
+	for i, srcNode := range srcNodes {
+		srcNodeMap, ok := srcNode.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("node #%d should be a map", i+1)
+		}
+		role, _ := srcNodeMap["role"].(string)
+		if role == "" {
+			return nil, fmt.Errorf("node #%d has no role, which is required to match nodes", i+1)
+		}
+		target := nthNodeOfRole(dstNodes, role, matched[role])
+		matched[role]++
+		if target < 0 {
+			target = len(dstNodes)
+			dstNodes = append(dstNodes, nil)
+		}
+		dstNode, _ := dstNodes[target].(map[string]interface{})
+		merged, err := mergeMaps(dstNode, srcNodeMap)
+		if err != nil {
+			return nil, fmt.Errorf("node #%d, err:%+v", i+1, err)
+		}
+		dstNodes[target] = merged
+	}
+	return dstNodes, nil
+}
+
+func nthNodeOfRole(nodes []interface{}, role string, n int) int {
+	for i, node := range nodes {
+		nodeMap, ok := node.(map[string]interface{})
+		if !ok || nodeMap["role"] != role {
+			continue
+		}
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return -1
+}
+
+// ValidateKindConfig validates the kind config against kind's v1alpha4 schema, unknown fields are rejected.
+// `gpus` of nodes is accepted as it is supported by the kind build multikf uses.
+func ValidateKindConfig(config []byte) error {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(config, &raw); err != nil {
+		return fmt.Errorf("kindconfig: invalid yaml, err:%+v", err)
+	}
+	if nodes, ok := raw["nodes"].([]interface{}); ok {
+		for i, node := range nodes {
+			nodeMap, ok := node.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if gpus, found := nodeMap["gpus"]; found {
+				if _, isBool := gpus.(bool); !isBool {
+					return fmt.Errorf("kindconfig: nodes[%d].gpus should be a boolean, got %v", i, gpus)
+				}
+				delete(nodeMap, "gpus")
+			}
+		}
+	}
+	stripped, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	cluster := kindv1alpha4.Cluster{}
+	if err := yaml.UnmarshalStrict(stripped, &cluster); err != nil {
+		return fmt.Errorf("kindconfig: not a valid %s config, err:%+v", kindConfigAPIVersion, err)
+	}
+	if cluster.Kind != "Cluster" || cluster.APIVersion != kindConfigAPIVersion {
+		return fmt.Errorf("kindconfig: expect kind Cluster of %s, got kind %q of %q", kindConfigAPIVersion, cluster.Kind, cluster.APIVersion)
+	}
+	controlPlanes := 0
+	for i, node := range cluster.Nodes {
+		switch node.Role {
+		case kindv1alpha4.ControlPlaneRole:
+			controlPlanes++
+		case kindv1alpha4.WorkerRole:
+		default:
+			return fmt.Errorf("kindconfig: nodes[%d] has an invalid role %q, expect %s or %s", i, node.Role, kindv1alpha4.ControlPlaneRole, kindv1alpha4.WorkerRole)
+		}
+		for j, mapping := range node.ExtraPortMappings {
+			switch mapping.Protocol {
+			case "", kindv1alpha4.PortMappingProtocolTCP, kindv1alpha4.PortMappingProtocolUDP, kindv1alpha4.PortMappingProtocolSCTP:
+			default:
+				return fmt.Errorf("kindconfig: nodes[%d].extraPortMappings[%d] has an invalid protocol %q", i, j, mapping.Protocol)
+			}
+		}
+		for j, mount := range node.ExtraMounts {
+			if mount.HostPath == "" || mount.ContainerPath == "" {
+				return fmt.Errorf("kindconfig: nodes[%d].extraMounts[%d] requires both hostPath and containerPath", i, j)
+			}
+		}
+		if err := validateKubeadmConfigPatches(fmt.Sprintf("nodes[%d].", i), node.KubeadmConfigPatches); err != nil {
+			return err
+		}
+	}
+	if len(cluster.Nodes) > 0 && controlPlanes == 0 {
+		return errors.New("kindconfig: at least one control-plane node is required")
+	}
+	if port := cluster.Networking.APIServerPort; port < -1 || port > 65535 {
+		return fmt.Errorf("kindconfig: invalid networking.apiServerPort %d", port)
+	}
+	return validateKubeadmConfigPatches("", cluster.KubeadmConfigPatches)
+}
+
+func validateKubeadmConfigPatches(prefix string, patches []string) error {
+	for i, patch := range patches {
+		patchMap := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(patch), &patchMap); err != nil {
+			return fmt.Errorf("kindconfig: %skubeadmConfigPatches[%d] is not valid yaml, err:%+v", prefix, i, err)
+		}
+		if kind, _ := patchMap["kind"].(string); strings.TrimSpace(kind) == "" {
+			return fmt.Errorf("kindconfig: %skubeadmConfigPatches[%d] has no kind, e.g. ClusterConfiguration", prefix, i)
+		}
+	}
+	return nil
+}
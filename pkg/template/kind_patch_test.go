@@ -0,0 +1,174 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+const baseKindConfig = `
+kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+name: unittest
+nodes:
+- role: control-plane
+  image: kindest/node:v1.28.13
+  gpus: false
+  labels:
+    tier: control
+- role: worker
+  image: kindest/node:v1.28.13
+- role: worker
+  image: kindest/node:v1.28.13
+networking:
+  apiServerPort: 8443
+`
+
+func TestMergeKindConfig(t *testing.T) {
+	merged, err := MergeKindConfig([]byte(baseKindConfig), []byte(`
+featureGates:
+  InPlacePodVerticalScaling: true
+containerdConfigPatches:
+- |-
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."localhost:5000"]
+    endpoint = ["http://kind-registry:5000"]
+nodes:
+- role: worker
+- role: worker
+  labels:
+    gpu: "true"
+  extraMounts:
+  - hostPath: /data
+    containerPath: /data
+- role: worker
+  image: kindest/node:v1.27.16
+`), []byte(`
+networking:
+  podSubnet: 10.244.0.0/16
+nodes:
+- role: control-plane
+  labels:
+    ingress-ready: "true"
+  kubeadmConfigPatches:
+  - |
+    kind: InitConfiguration
+    nodeRegistration:
+      kubeletExtraArgs:
+        max-pods: "200"
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateKindConfig(merged))
+
+	cluster := struct {
+		Name                    string
+		FeatureGates            map[string]bool `json:"featureGates"`
+		ContainerdConfigPatches []string        `json:"containerdConfigPatches"`
+		Nodes                   []struct {
+			Role                 string
+			Image                string
+			Labels               map[string]string
+			ExtraMounts          []map[string]string `json:"extraMounts"`
+			KubeadmConfigPatches []string            `json:"kubeadmConfigPatches"`
+		}
+		Networking map[string]interface{}
+	}{}
+	assert.NoError(t, yaml.Unmarshal(merged, &cluster))
+	assert.EqualValues(t, "unittest", cluster.Name)
+	assert.EqualValues(t, map[string]bool{"InPlacePodVerticalScaling": true}, cluster.FeatureGates)
+	assert.Len(t, cluster.ContainerdConfigPatches, 1)
+	assert.Len(t, cluster.Nodes, 4, "the third worker is appended")
+	assert.EqualValues(t, map[string]string{"tier": "control", "ingress-ready": "true"}, cluster.Nodes[0].Labels)
+	assert.Len(t, cluster.Nodes[0].KubeadmConfigPatches, 1)
+	assert.Nil(t, cluster.Nodes[1].Labels)
+	assert.EqualValues(t, map[string]string{"gpu": "true"}, cluster.Nodes[2].Labels)
+	assert.EqualValues(t, []map[string]string{{"hostPath": "/data", "containerPath": "/data"}}, cluster.Nodes[2].ExtraMounts)
+	assert.EqualValues(t, "kindest/node:v1.28.13", cluster.Nodes[2].Image)
+	assert.EqualValues(t, "worker", cluster.Nodes[3].Role)
+	assert.EqualValues(t, "kindest/node:v1.27.16", cluster.Nodes[3].Image)
+	assert.EqualValues(t, map[string]interface{}{"apiServerPort": float64(8443), "podSubnet": "10.244.0.0/16"}, cluster.Networking)
+
+	// lists are appended unless replaced by $replace
+	merged, err = MergeKindConfig([]byte(baseKindConfig), []byte(`
+nodes:
+- role: control-plane
+  extraPortMappings:
+  - containerPort: 80
+    hostPort: 8080
+`), []byte(`
+nodes:
+- role: control-plane
+  $replace: [extraPortMappings, labels]
+  extraPortMappings:
+  - containerPort: 443
+    hostPort: 8443
+  labels:
+    ingress-ready: "true"
+`))
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateKindConfig(merged))
+	assert.NotContains(t, string(merged), "$replace")
+	replaced := struct {
+		Nodes []struct {
+			Labels            map[string]string
+			ExtraPortMappings []map[string]int `json:"extraPortMappings"`
+		}
+	}{}
+	assert.NoError(t, yaml.Unmarshal(merged, &replaced))
+	assert.EqualValues(t, []map[string]int{{"containerPort": 443, "hostPort": 8443}}, replaced.Nodes[0].ExtraPortMappings)
+	assert.EqualValues(t, map[string]string{"ingress-ready": "true"}, replaced.Nodes[0].Labels)
+
+	merged, err = MergeKindConfig([]byte(baseKindConfig), []byte("$replace: [nodes]\nnodes:\n- role: control-plane\n"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "nodes:\n- role: control-plane\n", string(merged[bytes.Index(merged, []byte("nodes:")):]))
+
+	_, err = MergeKindConfig([]byte(baseKindConfig), []byte("$replace: nodes\n"))
+	assert.ErrorContains(t, err, "$replace should be a list of keys")
+	_, err = MergeKindConfig([]byte(baseKindConfig), []byte("$replace: [networking]\nname: foo\n"))
+	assert.ErrorContains(t, err, "$replace lists networking which is not in the patch")
+	_, err = MergeKindConfig([]byte(baseKindConfig), []byte("nodes:\n- image: foo\n"))
+	assert.ErrorContains(t, err, "node #1 has no role")
+	_, err = MergeKindConfig([]byte(baseKindConfig), []byte("- not a map\n"))
+	assert.ErrorContains(t, err, "invalid patch #1")
+}
+
+func TestValidateKindConfig(t *testing.T) {
+	assert.NoError(t, ValidateKindConfig([]byte(baseKindConfig)))
+	for patch, expected := range map[string]string{
+		"nodes:\n- role: worker\n  extraMount: []\n":                       `unknown field "extraMount"`,
+		"nodes:\n- role: master\n":                                         `invalid role "master"`,
+		"nodes:\n- role: worker\n  gpus: all\n":                            "gpus should be a boolean",
+		"nodes:\n- role: worker\n  extraPortMappings:\n  - protocol: ICMP": `invalid protocol "ICMP"`,
+		"nodes:\n- role: worker\n  extraMounts:\n  - hostPath: /data":      "requires both hostPath and containerPath",
+		"kubeadmConfigPatches:\n- \"apiServer: {}\"\n":                     "kubeadmConfigPatches[0] has no kind",
+		"apiVersion: kind.x-k8s.io/v1alpha3\n":                             "expect kind Cluster of kind.x-k8s.io/v1alpha4",
+		"networking:\n  apiServerPort: 70000\n":                            "invalid networking.apiServerPort",
+	} {
+		merged, err := MergeKindConfig([]byte(baseKindConfig), []byte(patch))
+		assert.NoError(t, err)
+		assert.ErrorContains(t, ValidateKindConfig(merged), expected, patch)
+	}
+}
+
+type kindPatchesConfig struct {
+	auditConfig
+}
+
+func (s kindPatchesConfig) GetKindPatches() []string {
+	return []string{"featureGates:\n  InPlacePodVerticalScaling: true\n"}
+}
+
+func TestKindTemplateWithPatches(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(kindPatchesConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assert.Contains(t, buf.String(), "featureGates:\n  InPlacePodVerticalScaling: true\n")
+	assert.Contains(t, buf.String(), "node-labels: ingress-ready=true")
+
+	assert.NoError(t, kt.Populate(auditConfig{}))
+	buf.Reset()
+	assert.NoError(t, kt.Execute(buf))
+	assert.NotContains(t, buf.String(), "featureGates")
+}
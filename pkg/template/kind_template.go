@@ -2,15 +2,25 @@ package template
 
 import (
 	"fmt"
-	"html/template"
 	"io"
+	"strings"
+
+	kindv1alpha4 "sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/yaml"
 
 	"github.com/footprintai/multikf/pkg/machine"
 )
 
+const (
+	auditPolicyDir        = "/etc/kubernetes/policies"
+	auditPolicyFile       = auditPolicyDir + "/audit-policy.yaml"
+	auditLogDir           = "/var/log/kubernetes"
+	localPathProvisionDir = "/var/local-path-provisioner"
+)
+
 func NewKindTemplate() *KindFileTemplate {
 	return &KindFileTemplate{
-		kindFileTemplate: kindDefaultFileTemplate,
+		ControlPlanes: 1,
 	}
 }
 
@@ -18,15 +28,30 @@ func (k *KindFileTemplate) Filename() string {
 	return "kind-config.yaml"
 }
 
+// Execute renders the kind config, merges user patches into it and validates the result before it is written
 func (k *KindFileTemplate) Execute(w io.Writer) error {
-	tmpl, err := template.New("kindconfig").Parse(k.kindFileTemplate)
+	cluster, err := k.kindCluster()
 	if err != nil {
 		return err
 	}
-	if err := tmpl.Execute(w, k); err != nil {
+	config, err := yaml.Marshal(cluster)
+	if err != nil {
 		return err
 	}
-	return nil
+	if len(k.kindPatches) > 0 {
+		var patches [][]byte
+		for _, patch := range k.kindPatches {
+			patches = append(patches, []byte(patch))
+		}
+		if config, err = MergeKindConfig(config, patches...); err != nil {
+			return err
+		}
+	}
+	if err := ValidateKindConfig(config); err != nil {
+		return err
+	}
+	_, err = w.Write(config)
+	return err
 }
 
 type KindConfiger interface {
@@ -40,6 +65,8 @@ type KindConfiger interface {
 	WorkersGetter
 	NodeLabelsGetter
 	LocalPathGetter
+	CNIGetter
+	APIServerCertSANsGetter
 }
 
 func (k *KindFileTemplate) Populate(v interface{}) error {
@@ -52,11 +79,27 @@ func (k *KindFileTemplate) Populate(v interface{}) error {
 	k.KubeAPIPort = c.GetKubeAPIPort()
 	k.KubeAPIIP = c.GetKubeAPIIP()
 	k.UseGPU = c.GetGPUs() > 0
-	k.ExportPorts = c.GetExportPorts()
+	k.ExportPorts = nil
+	for _, p := range c.GetExportPorts() {
+		if p.Protocol == "" {
+			p.Protocol = machine.ProtocolTCP
+		}
+		k.ExportPorts = append(k.ExportPorts, p)
+	}
 	k.AuditEnabled = c.AuditEnabled()
 	k.AuditFileAbsolutePath = c.AuditFileAbsolutePath()
 	k.LocalPath = c.LocalPath()
 	k.Workers = c.GetWorkers()
+	k.DisableDefaultCNI = !c.GetCNI().IsDefault()
+	k.APIServerCertSANs = c.GetAPIServerCertSANs()
+	k.ControlPlanes = 1
+	if getter, ok := v.(ControlPlanesGetter); ok && getter.GetControlPlanes() > 1 {
+		k.ControlPlanes = getter.GetControlPlanes()
+	}
+	k.kindPatches = nil
+	if getter, ok := v.(KindPatchesGetter); ok {
+		k.kindPatches = getter.GetKindPatches()
+	}
 
 	nodeLabels := c.GetNodeLabels()
 	k.NodeLabels = make([]string, len(nodeLabels), len(nodeLabels))
@@ -72,7 +115,6 @@ type KindFileTemplate struct {
 	KubeAPIIP             string
 	KubeAPIPort           int
 	UseGPU                bool
-	kindFileTemplate      string
 	ExportPorts           []machine.ExportPortPair
 	AuditEnabled          bool
 	AuditFileAbsolutePath string
@@ -80,82 +122,203 @@ type KindFileTemplate struct {
 	Workers               []Worker
 	NodeLabels            []string
 	NodeVersion           string
+	DisableDefaultCNI     bool
+	APIServerCertSANs     []string
+	ControlPlanes         int
+	kindPatches           []string
 }
 
 var (
 	_ TemplateExecutor = &KindFileTemplate{}
 )
 
-var kindDefaultFileTemplate string = `
-kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-name: {{.Name}}
-nodes:
-- role: control-plane
-  kubeadmConfigPatches:
-  {{- if .AuditEnabled}}
-  - |
-    kind: ClusterConfiguration
-    apiServer:
-      # enable auditing flags on the API server
-      extraArgs:
-        audit-log-path: /var/log/kubernetes/kube-apiserver-audit.log
-        audit-policy-file: /etc/kubernetes/policies/audit-policy.yaml
-        audit-log-maxage: "30"
-        audit-log-maxbackup: "10"
-        audit-log-maxsize: "100"
-      # mount new files / directories on the control plane
-      extraVolumes:
-        - name: audit-policies
-          hostPath: /etc/kubernetes/policies
-          mountPath: /etc/kubernetes/policies
-          readOnly: true
-          pathType: "DirectoryOrCreate"
-        - name: "audit-logs"
-          hostPath: "/var/log/kubernetes"
-          mountPath: "/var/log/kubernetes"
-          readOnly: false
-          pathType: DirectoryOrCreate
-  {{- end}}
-  - |
-    kind: InitConfiguration
-    nodeRegistration:
-      kubeletExtraArgs:
-        node-labels: "ingress-ready=true"
-        {{- range $i, $p := .NodeLabels}}
-        node-labels: "{{$p}}"
-        {{- end}}
-  image: {{.NodeVersion}}
-  gpus: {{.UseGPU}}
-  {{if .ExportPorts}}extraPortMappings:{{end}}
-  {{- range $i, $p := .ExportPorts}}
-  - containerPort: {{ $p.ContainerPort }}
-    hostPort: {{ $p.HostPort }}
-    protocol: TCP
-  {{- end}}
-  {{- if or .AuditEnabled .LocalPath}}
-  extraMounts:
-  {{- if or .AuditEnabled }}
-  - hostPath: {{.AuditFileAbsolutePath}}
-    containerPath: /etc/kubernetes/policies/audit-policy.yaml
-    readOnly: true
-  {{- end}}
-  {{- if ne .LocalPath ""}}
-  - hostPath: {{.LocalPath}}
-    containerPath: /var/local-path-provisioner
-  {{- end}}
-  {{- end}}
-{{- range .Workers }}
-- role: worker
-  image: {{ .NodeVersion}}
-  gpus: {{ .UseGPU}}
-  {{- if ne .LocalPath ""}}
-  extraMounts:
-  - hostPath: {{.LocalPath}}
-    containerPath: /var/local-path-provisioner
-  {{- end}}
-{{- end}}
-networking:
-  apiServerAddress: {{.KubeAPIIP}}
-  apiServerPort: {{.KubeAPIPort}}
-`
+// kindCluster is kind's v1alpha4 cluster with nodes supporting `gpus`
+type kindCluster struct {
+	kindv1alpha4.Cluster `json:",inline"`
+	Nodes                []kindNode `json:"nodes,omitempty"`
+}
+
+// kindNode is kind's v1alpha4 node with `gpus` of the kind build multikf uses
+type kindNode struct {
+	kindv1alpha4.Node `json:",inline"`
+	GPUs              bool `json:"gpus"`
+}
+
+// kubeadmConfigPatch is the subset of kubeadm's ClusterConfiguration, InitConfiguration and JoinConfiguration patched by multikf
+type kubeadmConfigPatch struct {
+	Kind             string                   `json:"kind"`
+	APIServer        *kubeadmAPIServer        `json:"apiServer,omitempty"`
+	NodeRegistration *kubeadmNodeRegistration `json:"nodeRegistration,omitempty"`
+}
+
+type kubeadmAPIServer struct {
+	ExtraArgs    map[string]string      `json:"extraArgs,omitempty"`
+	ExtraVolumes []kubeadmHostPathMount `json:"extraVolumes,omitempty"`
+	CertSANs     []string               `json:"certSANs,omitempty"`
+}
+
+type kubeadmHostPathMount struct {
+	Name      string `json:"name"`
+	HostPath  string `json:"hostPath"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly"`
+	PathType  string `json:"pathType"`
+}
+
+type kubeadmNodeRegistration struct {
+	KubeletExtraArgs map[string]string `json:"kubeletExtraArgs,omitempty"`
+	Taints           []machine.Taint   `json:"taints,omitempty"`
+}
+
+func (k *KindFileTemplate) kindCluster() (*kindCluster, error) {
+	cluster := &kindCluster{
+		Cluster: kindv1alpha4.Cluster{
+			TypeMeta: kindv1alpha4.TypeMeta{
+				Kind:       "Cluster",
+				APIVersion: kindConfigAPIVersion,
+			},
+			Name: k.Name,
+			Networking: kindv1alpha4.Networking{
+				APIServerAddress:  k.KubeAPIIP,
+				APIServerPort:     int32(k.KubeAPIPort),
+				DisableDefaultCNI: k.DisableDefaultCNI,
+			},
+		},
+	}
+	// kind puts a load balancer in front of control-plane nodes if there are more than one of them
+	for index := 1; index <= k.ControlPlanes; index++ {
+		controlPlane, err := k.controlPlaneNode(index)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Nodes = append(cluster.Nodes, controlPlane)
+	}
+	for _, worker := range k.Workers {
+		node, err := workerNode(worker)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Nodes = append(cluster.Nodes, node)
+	}
+	return cluster, nil
+}
+
+func workerNode(worker Worker) (kindNode, error) {
+	node := kindNode{
+		Node: kindv1alpha4.Node{
+			Role:   kindv1alpha4.WorkerRole,
+			Image:  worker.NodeVersion,
+			Labels: worker.Labels,
+		},
+		GPUs: worker.UseGPU,
+	}
+	if len(worker.Taints) > 0 {
+		// workers join the cluster, so taints are registered through JoinConfiguration
+		marshaled, err := yaml.Marshal(kubeadmConfigPatch{
+			Kind: "JoinConfiguration",
+			NodeRegistration: &kubeadmNodeRegistration{
+				Taints: worker.Taints,
+			},
+		})
+		if err != nil {
+			return node, err
+		}
+		node.KubeadmConfigPatches = append(node.KubeadmConfigPatches, string(marshaled))
+	}
+	if worker.LocalPath != "" {
+		node.ExtraMounts = append(node.ExtraMounts, kindv1alpha4.Mount{
+			HostPath:      worker.LocalPath,
+			ContainerPath: localPathProvisionDir,
+		})
+	}
+	for _, mount := range worker.ExtraMounts {
+		node.ExtraMounts = append(node.ExtraMounts, kindv1alpha4.Mount{
+			HostPath:      mount.HostPath,
+			ContainerPath: mount.ContainerPath,
+			Readonly:      mount.ReadOnly,
+		})
+	}
+	return node, nil
+}
+
+// controlPlaneNode returns the index-th control-plane node, index starts from 1.
+// Every control-plane node runs kube-apiserver, so all of them have the audit setup and mounts, while ports are only
+// exported from the first one which initializes the cluster and is labeled as ingress-ready.
+func (k *KindFileTemplate) controlPlaneNode(index int) (kindNode, error) {
+	node := kindNode{
+		Node: kindv1alpha4.Node{
+			Role:  kindv1alpha4.ControlPlaneRole,
+			Image: k.NodeVersion,
+		},
+		GPUs: k.UseGPU,
+	}
+	var patches []kubeadmConfigPatch
+	if k.AuditEnabled || len(k.APIServerCertSANs) > 0 {
+		apiServer := &kubeadmAPIServer{
+			CertSANs: k.APIServerCertSANs,
+		}
+		if k.AuditEnabled {
+			apiServer.ExtraArgs = map[string]string{
+				"audit-log-path":      auditLogDir + "/kube-apiserver-audit.log",
+				"audit-policy-file":   auditPolicyFile,
+				"audit-log-maxage":    "30",
+				"audit-log-maxbackup": "10",
+				"audit-log-maxsize":   "100",
+			}
+			apiServer.ExtraVolumes = []kubeadmHostPathMount{
+				{Name: "audit-policies", HostPath: auditPolicyDir, MountPath: auditPolicyDir, ReadOnly: true, PathType: "DirectoryOrCreate"},
+				{Name: "audit-logs", HostPath: auditLogDir, MountPath: auditLogDir, ReadOnly: false, PathType: "DirectoryOrCreate"},
+			}
+		}
+		patches = append(patches, kubeadmConfigPatch{Kind: "ClusterConfiguration", APIServer: apiServer})
+	}
+	// kubelet takes a single comma separated --node-labels, repeated keys would override each other
+	nodeLabels := k.NodeLabels
+	registration := "JoinConfiguration"
+	if index == 1 {
+		nodeLabels = append([]string{"ingress-ready=true"}, nodeLabels...)
+		registration = "InitConfiguration"
+	}
+	if len(nodeLabels) > 0 {
+		patches = append(patches, kubeadmConfigPatch{
+			Kind: registration,
+			NodeRegistration: &kubeadmNodeRegistration{
+				KubeletExtraArgs: map[string]string{
+					"node-labels": strings.Join(nodeLabels, ","),
+				},
+			},
+		})
+	}
+	for _, patch := range patches {
+		marshaled, err := yaml.Marshal(patch)
+		if err != nil {
+			return node, err
+		}
+		node.KubeadmConfigPatches = append(node.KubeadmConfigPatches, string(marshaled))
+	}
+	if index == 1 {
+		// a host port could be bound only once
+		for _, p := range k.ExportPorts {
+			node.ExtraPortMappings = append(node.ExtraPortMappings, kindv1alpha4.PortMapping{
+				ContainerPort: int32(p.ContainerPort),
+				HostPort:      int32(p.HostPort),
+				ListenAddress: p.ListenAddress,
+				Protocol:      kindv1alpha4.PortMappingProtocol(p.Protocol),
+			})
+		}
+	}
+	if k.AuditEnabled {
+		node.ExtraMounts = append(node.ExtraMounts, kindv1alpha4.Mount{
+			HostPath:      k.AuditFileAbsolutePath,
+			ContainerPath: auditPolicyFile,
+			Readonly:      true,
+		})
+	}
+	if k.LocalPath != "" {
+		node.ExtraMounts = append(node.ExtraMounts, kindv1alpha4.Mount{
+			HostPath:      k.LocalPath,
+			ContainerPath: localPathProvisionDir,
+		})
+	}
+	return node, nil
+}
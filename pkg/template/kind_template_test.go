@@ -2,6 +2,10 @@ package template
 
 import (
 	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/footprintai/multikf/pkg/k8s"
@@ -9,6 +13,21 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var update = flag.Bool("update", false, "update golden files under testdata")
+
+// assertGolden compares the rendered kind config with testdata/<name>.golden.yaml, run with -update to regenerate
+func assertGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name+".golden.yaml")
+	if *update {
+		assert.NoError(t, os.MkdirAll("testdata", 0755))
+		assert.NoError(t, os.WriteFile(golden, []byte(actual), 0644))
+	}
+	expected, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.EqualValues(t, string(expected), actual)
+}
+
 var (
 	_ KindConfiger = staticConfig{}
 )
@@ -70,6 +89,14 @@ func (s staticConfig) GetWorkers() []Worker {
 	}
 }
 
+func (s staticConfig) GetCNI() k8s.CNI {
+	return k8s.CNIDefault
+}
+
+func (s staticConfig) GetAPIServerCertSANs() []string {
+	return nil
+}
+
 func (s staticConfig) GetNodeLabels() []machine.NodeLabel {
 	return []machine.NodeLabel{
 		{
@@ -101,57 +128,9 @@ func TestKindTemplate(t *testing.T) {
 	assert.NoError(t, kt.Populate(staticConfig{}))
 	buf := &bytes.Buffer{}
 	assert.NoError(t, kt.Execute(buf))
-	assert.EqualValues(t, gold, buf.String())
-}
-
-var gold = `
-kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-name: staticconfig
-nodes:
-- role: control-plane
-  kubeadmConfigPatches:
-  - |
-    kind: InitConfiguration
-    nodeRegistration:
-      kubeletExtraArgs:
-        node-labels: "ingress-ready=true"
-        node-labels: "a=b"
-        node-labels: "c=d"
-  image: kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110
-  gpus: true
-  extraPortMappings:
-  - containerPort: 8081
-    hostPort: 80
-    protocol: TCP
-  - containerPort: 8083
-    hostPort: 443
-    protocol: TCP
-  extraMounts:
-  - hostPath: /mnt/test
-    containerPath: /var/local-path-provisioner
-- role: worker
-  image: kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110
-  gpus: true
-  extraMounts:
-  - hostPath: /mnt/test
-    containerPath: /var/local-path-provisioner
-- role: worker
-  image: kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110
-  gpus: true
-  extraMounts:
-  - hostPath: /mnt/test
-    containerPath: /var/local-path-provisioner
-- role: worker
-  image: kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110
-  gpus: true
-  extraMounts:
-  - hostPath: /mnt/test
-    containerPath: /var/local-path-provisioner
-networking:
-  apiServerAddress: 1.2.3.4
-  apiServerPort: 8443
-`
+	assertGolden(t, "staticconfig", buf.String())
+}
+
 var (
 	_ KindConfiger = auditConfig{}
 )
@@ -207,59 +186,248 @@ func (s auditConfig) LocalPath() string {
 	return ""
 }
 
+func (s auditConfig) GetCNI() k8s.CNI {
+	return k8s.CNIDefault
+}
+
+func (s auditConfig) GetAPIServerCertSANs() []string {
+	return nil
+}
+
 func TestKindTemplateWithAudit(t *testing.T) {
 	kt := NewKindTemplate()
 	assert.NoError(t, kt.Populate(auditConfig{}))
 	buf := &bytes.Buffer{}
 	assert.NoError(t, kt.Execute(buf))
-	assert.EqualValues(t, goldWithAudit, buf.String())
-}
-
-var goldWithAudit = `
-kind: Cluster
-apiVersion: kind.x-k8s.io/v1alpha4
-name: auditConfig
-nodes:
-- role: control-plane
-  kubeadmConfigPatches:
-  - |
-    kind: ClusterConfiguration
-    apiServer:
-      # enable auditing flags on the API server
-      extraArgs:
-        audit-log-path: /var/log/kubernetes/kube-apiserver-audit.log
-        audit-policy-file: /etc/kubernetes/policies/audit-policy.yaml
-        audit-log-maxage: "30"
-        audit-log-maxbackup: "10"
-        audit-log-maxsize: "100"
-      # mount new files / directories on the control plane
-      extraVolumes:
-        - name: audit-policies
-          hostPath: /etc/kubernetes/policies
-          mountPath: /etc/kubernetes/policies
-          readOnly: true
-          pathType: "DirectoryOrCreate"
-        - name: "audit-logs"
-          hostPath: "/var/log/kubernetes"
-          mountPath: "/var/log/kubernetes"
-          readOnly: false
-          pathType: DirectoryOrCreate
-  - |
-    kind: InitConfiguration
-    nodeRegistration:
-      kubeletExtraArgs:
-        node-labels: "ingress-ready=true"
-  image: kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110
-  gpus: false
-  extraPortMappings:
-  - containerPort: 8081
-    hostPort: 80
+	assertGolden(t, "audit", buf.String())
+}
+
+var (
+	_ KindConfiger = cniConfig{}
+)
+
+type cniConfig struct {
+	auditConfig
+}
+
+func (s cniConfig) GetCNI() k8s.CNI {
+	return k8s.CNICalico
+}
+
+func TestKindTemplateWithCNI(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(cniConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assert.Contains(t, buf.String(), "  apiServerPort: 8443\n  disableDefaultCNI: true\n")
+}
+
+var (
+	_ KindConfiger = exportPortsConfig{}
+)
+
+type exportPortsConfig struct {
+	auditConfig
+}
+
+func (s exportPortsConfig) GetExportPorts() []machine.ExportPortPair {
+	return []machine.ExportPortPair{
+		{
+			HostPort:      8000,
+			ContainerPort: 30000,
+			Protocol:      machine.ProtocolUDP,
+			ListenAddress: "127.0.0.1",
+		},
+		{
+			HostPort:      8443,
+			ContainerPort: 443,
+		},
+	}
+}
+
+func TestKindTemplateWithExportPorts(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(exportPortsConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assert.Contains(t, buf.String(), `  extraPortMappings:
+  - containerPort: 30000
+    hostPort: 8000
+    listenAddress: 127.0.0.1
+    protocol: UDP
+  - containerPort: 443
+    hostPort: 8443
     protocol: TCP
-  extraMounts:
-  - hostPath: foo.bar.yaml
-    containerPath: /etc/kubernetes/policies/audit-policy.yaml
-    readOnly: true
-networking:
-  apiServerAddress: 1.2.3.4
-  apiServerPort: 8443
-`
+`)
+}
+
+var (
+	_ KindConfiger = certSANsConfig{}
+)
+
+type certSANsConfig struct {
+	auditConfig
+}
+
+func (s certSANsConfig) GetAPIServerCertSANs() []string {
+	return []string{"kf.example.com", "10.1.2.3"}
+}
+
+func TestKindTemplateWithCertSANs(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(certSANsConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assert.Contains(t, buf.String(), `      certSANs:
+      - kf.example.com
+      - 10.1.2.3
+      extraArgs:
+`)
+	assert.Equal(t, 1, strings.Count(buf.String(), "kind: ClusterConfiguration"), "audit and certSANs share the ClusterConfiguration patch")
+}
+
+var (
+	_ KindConfiger = auditWorkersConfig{}
+)
+
+// auditWorkersConfig combines audit, node labels, local path mounts and workers
+type auditWorkersConfig struct {
+	auditConfig
+}
+
+func (s auditWorkersConfig) LocalPath() string {
+	return "/mnt/R&D's data"
+}
+
+func (s auditWorkersConfig) GetNodeLabels() []machine.NodeLabel {
+	return []machine.NodeLabel{
+		{
+			Key:   "team",
+			Value: "r-and-d",
+		},
+		{
+			Key:   "example.com/tier",
+			Value: "gold",
+		},
+	}
+}
+
+func (s auditWorkersConfig) GetWorkers() []Worker {
+	return []Worker{
+		{
+			Id:          "1",
+			UseGPU:      true,
+			LocalPath:   s.LocalPath(),
+			NodeVersion: "kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110",
+		},
+		{
+			Id:          "2",
+			NodeVersion: "kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110",
+		},
+	}
+}
+
+func TestKindTemplateWithAuditWorkers(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(auditWorkersConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assertGolden(t, "audit_workers", buf.String())
+	assert.NotContains(t, buf.String(), "&amp;")
+	assert.NotContains(t, buf.String(), "&#39;")
+	assert.Equal(t, 1, strings.Count(buf.String(), "node-labels:"))
+}
+
+var (
+	_ KindConfiger = labelsConfig{}
+)
+
+// labelsConfig has node labels without workers and mounts
+type labelsConfig struct {
+	auditWorkersConfig
+}
+
+func (s labelsConfig) AuditEnabled() bool {
+	return false
+}
+
+func (s labelsConfig) LocalPath() string {
+	return ""
+}
+
+func (s labelsConfig) GetWorkers() []Worker {
+	return nil
+}
+
+func TestKindTemplateWithLabels(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(labelsConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assertGolden(t, "labels", buf.String())
+}
+
+var (
+	_ KindConfiger = workerGroupsConfig{}
+)
+
+// workerGroupsConfig has workers with their own labels, taints, gpu, mounts and image
+type workerGroupsConfig struct {
+	auditConfig
+}
+
+func (s workerGroupsConfig) GetWorkers() []Worker {
+	return []Worker{
+		{
+			Id:          "0",
+			NodeVersion: "kindest/node:v1.28.13@sha256:45d319897776e11167e4698f6b14938eb4d52eb381d9e3d7a9086c16c69a8110",
+			Labels:      map[string]string{"tier": "cpu"},
+		},
+		{
+			Id:          "1",
+			UseGPU:      true,
+			NodeVersion: "kindest/node:v1.27.16",
+			Labels:      map[string]string{"accelerator": "nvidia"},
+			Taints: []machine.Taint{
+				{Key: "nvidia.com/gpu", Value: "present", Effect: machine.TaintEffectNoSchedule},
+			},
+			ExtraMounts: []machine.Mount{
+				{HostPath: "/models", ContainerPath: "/mnt/models", ReadOnly: true},
+			},
+		},
+	}
+}
+
+func TestKindTemplateWithWorkerGroups(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(workerGroupsConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assertGolden(t, "worker_groups", buf.String())
+}
+
+var (
+	_ KindConfiger        = controlPlanesConfig{}
+	_ ControlPlanesGetter = controlPlanesConfig{}
+)
+
+// controlPlanesConfig has 3 control-plane nodes with audit, node labels, local path mounts and workers
+type controlPlanesConfig struct {
+	auditWorkersConfig
+}
+
+func (s controlPlanesConfig) GetControlPlanes() int {
+	return 3
+}
+
+func TestKindTemplateWithControlPlanes(t *testing.T) {
+	kt := NewKindTemplate()
+	assert.NoError(t, kt.Populate(controlPlanesConfig{}))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, kt.Execute(buf))
+	assertGolden(t, "control_planes", buf.String())
+	assert.Equal(t, 3, strings.Count(buf.String(), "role: control-plane"))
+	assert.Equal(t, 3, strings.Count(buf.String(), "containerPath: /etc/kubernetes/policies/audit-policy.yaml"))
+	assert.Equal(t, 1, strings.Count(buf.String(), "extraPortMappings:"))
+	assert.Equal(t, 1, strings.Count(buf.String(), "ingress-ready=true"))
+}
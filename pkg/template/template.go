@@ -65,6 +65,9 @@ type Worker struct {
 	UseGPU      bool
 	LocalPath   string
 	NodeVersion string
+	Labels      map[string]string
+	Taints      []machine.Taint
+	ExtraMounts []machine.Mount
 }
 
 type NodeLabelsGetter interface {
@@ -75,6 +78,29 @@ type LocalPathGetter interface {
 	LocalPath() string
 }
 
+type CNIGetter interface {
+	GetCNI() k8s.CNI
+}
+
+type APIServerCertSANsGetter interface {
+	GetAPIServerCertSANs() []string
+}
+
+// AuditPolicyGetter is implemented by configs carrying an audit policy instead of the default one
+type AuditPolicyGetter interface {
+	GetAuditPolicy() string
+}
+
+// ControlPlanesGetter is implemented by configs with more than one control-plane node
+type ControlPlanesGetter interface {
+	GetControlPlanes() int
+}
+
+// KindPatchesGetter is implemented by configs carrying user yaml merged into the rendered kind config
+type KindPatchesGetter interface {
+	GetKindPatches() []string
+}
+
 type K8sNodeVersion struct {
 	K8sVersion string // started with v1.26.x
 	SHA256     string